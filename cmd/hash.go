@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/demodesk/neko/pkg/utils"
+)
+
+func init() {
+	command := &cobra.Command{
+		Use:   "hash [password]",
+		Short: "hash password for member config",
+		Long: `hash password for member config, e.g. member.object.users or member.multiuser passwords.
+if password is not provided as argument, it is read from standard input.`,
+		Run:  hashCmd,
+		Args: cobra.MaximumNArgs(1),
+	}
+
+	command.Flags().String("algorithm", utils.PasswordHashArgon2id, "hash algorithm, argon2id or bcrypt")
+
+	root.AddCommand(command)
+}
+
+func hashCmd(cmd *cobra.Command, args []string) {
+	algorithm, _ := cmd.Flags().GetString("algorithm")
+
+	var password string
+	if len(args) > 0 {
+		password = args[0]
+	} else {
+		// avoid password in shell history
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			log.Fatal().Err(err).Msg("unable to read password from stdin")
+		}
+		password = strings.TrimRight(line, "\r\n")
+	}
+
+	if password == "" {
+		log.Fatal().Msg("password must not be empty")
+	}
+
+	hash, err := utils.HashPassword(algorithm, password)
+	if err != nil {
+		log.Fatal().Err(err).Msg("unable to hash password")
+	}
+
+	fmt.Println(hash)
+}
@@ -106,6 +106,7 @@ func (c *serve) Start(cmd *cobra.Command) {
 	c.managers.session = session.New(
 		&c.configs.Session,
 	)
+	c.managers.session.Start()
 
 	c.managers.member = member.New(
 		c.managers.session,
@@ -127,11 +128,25 @@ func (c *serve) Start(cmd *cobra.Command) {
 	)
 	c.managers.capture.Start()
 
+	// in single port mode, ICE-TCP shares listener with HTTP server
+	var demux *http.Demux
+	if c.configs.WebRTC.SinglePort {
+		var err error
+		demux, err = http.NewDemux(c.configs.Server.Bind)
+		if err != nil {
+			c.logger.Panic().Err(err).Msg("unable to listen on server bind address")
+		}
+	}
+
 	c.managers.webRTC = webrtc.New(
+		c.managers.session,
 		c.managers.desktop,
 		c.managers.capture,
 		&c.configs.WebRTC,
 	)
+	if demux != nil {
+		c.managers.webRTC.SetTCPMuxListener(demux.ICE())
+	}
 	c.managers.webRTC.Start()
 
 	c.managers.webSocket = websocket.New(
@@ -162,6 +177,7 @@ func (c *serve) Start(cmd *cobra.Command) {
 		c.managers.session,
 		c.managers.webSocket,
 		c.managers.api,
+		c.managers.webRTC,
 	)
 
 	c.managers.http = http.New(
@@ -169,6 +185,9 @@ func (c *serve) Start(cmd *cobra.Command) {
 		c.managers.api,
 		&c.configs.Server,
 	)
+	if demux != nil {
+		c.managers.http.SetListener(demux.HTTP())
+	}
 	c.managers.http.Start()
 }
 
@@ -195,6 +214,9 @@ func (c *serve) Shutdown() {
 
 	err = c.managers.member.Disconnect()
 	c.logger.Err(err).Msg("member manager disconnect")
+
+	err = c.managers.session.Shutdown()
+	c.logger.Err(err).Msg("session manager shutdown")
 }
 
 func (c *serve) Run(cmd *cobra.Command, args []string) {
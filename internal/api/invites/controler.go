@@ -0,0 +1,68 @@
+package invites
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+
+	"github.com/demodesk/neko/pkg/types"
+	"github.com/demodesk/neko/pkg/utils"
+)
+
+type InviteCreatePayload struct {
+	// expiration in seconds
+	ExpiresIn int                 `json:"expires_in"`
+	MaxUses   int                 `json:"max_uses"`
+	Profile   types.MemberProfile `json:"profile"`
+}
+
+func (h *InvitesHandler) invitesList(w http.ResponseWriter, r *http.Request) error {
+	return utils.HttpSuccess(w, h.members.ListInvites())
+}
+
+func (h *InvitesHandler) invitesCreate(w http.ResponseWriter, r *http.Request) error {
+	data := &InviteCreatePayload{
+		// default values, view only access
+		Profile: types.MemberProfile{
+			CanLogin:   true,
+			CanConnect: true,
+			CanWatch:   true,
+		},
+	}
+
+	if err := utils.HttpJsonRequest(w, r, data); err != nil {
+		return err
+	}
+
+	if data.ExpiresIn <= 0 {
+		return utils.HttpBadRequest("expires_in must be positive")
+	}
+
+	if data.MaxUses < 0 {
+		return utils.HttpBadRequest("max_uses cannot be negative")
+	}
+
+	expiresAt := time.Now().Add(time.Duration(data.ExpiresIn) * time.Second)
+	invite, err := h.members.CreateInvite(data.Profile, expiresAt, data.MaxUses)
+	if err != nil {
+		return utils.HttpInternalServerError().WithInternalErr(err)
+	}
+
+	return utils.HttpSuccess(w, invite)
+}
+
+func (h *InvitesHandler) invitesDelete(w http.ResponseWriter, r *http.Request) error {
+	inviteId := chi.URLParam(r, "inviteId")
+
+	if err := h.members.DeleteInvite(inviteId); err != nil {
+		if errors.Is(err, types.ErrMemberInviteNotFound) {
+			return utils.HttpNotFound("invite not found")
+		}
+
+		return utils.HttpInternalServerError().WithInternalErr(err)
+	}
+
+	return utils.HttpSuccess(w)
+}
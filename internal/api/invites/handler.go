@@ -0,0 +1,28 @@
+package invites
+
+import (
+	"github.com/demodesk/neko/pkg/auth"
+	"github.com/demodesk/neko/pkg/types"
+)
+
+type InvitesHandler struct {
+	members types.MemberManager
+}
+
+func New(
+	members types.MemberManager,
+) *InvitesHandler {
+	// Init
+
+	return &InvitesHandler{
+		members: members,
+	}
+}
+
+func (h *InvitesHandler) Route(r types.Router) {
+	r.With(auth.AdminsOnly).Group(func(r types.Router) {
+		r.Get("/", h.invitesList)
+		r.Post("/", h.invitesCreate)
+		r.Delete("/{inviteId}", h.invitesDelete)
+	})
+}
@@ -142,3 +142,17 @@ func (h *MembersHandler) membersDelete(w http.ResponseWriter, r *http.Request) e
 
 	return utils.HttpSuccess(w)
 }
+
+func (h *MembersHandler) membersResetTOTP(w http.ResponseWriter, r *http.Request) error {
+	member := GetMember(r)
+
+	if err := h.members.TOTPReset(member.ID); err != nil {
+		if errors.Is(err, types.ErrMemberTOTPUnsupported) {
+			return utils.HttpUnprocessableEntity("two-factor authentication is not supported")
+		}
+
+		return utils.HttpInternalServerError().WithInternalErr(err)
+	}
+
+	return utils.HttpSuccess(w)
+}
@@ -39,11 +39,29 @@ func (h *MembersHandler) Route(r types.Router) {
 			r.Get("/", h.membersRead)
 			r.Post("/", h.membersUpdateProfile)
 			r.Post("/password", h.membersUpdatePassword)
+			r.Delete("/totp", h.membersResetTOTP)
 			r.Delete("/", h.membersDelete)
 		})
 	})
 }
 
+func (h *MembersHandler) RouteLockouts(r types.Router) {
+	r.With(auth.AdminsOnly).Group(func(r types.Router) {
+		r.Get("/", h.lockoutsList)
+		r.Delete("/", h.lockoutsClearAll)
+		r.Delete("/username/{username}", h.lockoutsClearUsername)
+		r.Delete("/ip/{ip}", h.lockoutsClearIP)
+	})
+}
+
+func (h *MembersHandler) RouteRoles(r types.Router) {
+	r.With(auth.AdminsOnly).Group(func(r types.Router) {
+		r.Get("/", h.rolesList)
+		r.Post("/{roleName}", h.rolesUpdate)
+		r.Delete("/{roleName}", h.rolesDelete)
+	})
+}
+
 func (h *MembersHandler) RouteBulk(r types.Router) {
 	r.With(auth.AdminsOnly).Group(func(r types.Router) {
 		r.Post("/update", h.membersBulkUpdate)
@@ -0,0 +1,40 @@
+package members
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi"
+
+	"github.com/demodesk/neko/pkg/types"
+	"github.com/demodesk/neko/pkg/utils"
+)
+
+func (h *MembersHandler) lockoutsList(w http.ResponseWriter, r *http.Request) error {
+	return utils.HttpSuccess(w, h.members.ListLockouts())
+}
+
+func (h *MembersHandler) lockoutsClearAll(w http.ResponseWriter, r *http.Request) error {
+	h.members.ClearLockouts()
+	return utils.HttpSuccess(w)
+}
+
+func (h *MembersHandler) lockoutsClearUsername(w http.ResponseWriter, r *http.Request) error {
+	return h.lockoutsClear(w, chi.URLParam(r, "username"), "")
+}
+
+func (h *MembersHandler) lockoutsClearIP(w http.ResponseWriter, r *http.Request) error {
+	return h.lockoutsClear(w, "", chi.URLParam(r, "ip"))
+}
+
+func (h *MembersHandler) lockoutsClear(w http.ResponseWriter, username string, ip string) error {
+	if err := h.members.ClearLockout(username, ip); err != nil {
+		if errors.Is(err, types.ErrMemberLockoutNotFound) {
+			return utils.HttpNotFound("lockout not found")
+		}
+
+		return utils.HttpInternalServerError().WithInternalErr(err)
+	}
+
+	return utils.HttpSuccess(w)
+}
@@ -0,0 +1,40 @@
+package members
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi"
+
+	"github.com/demodesk/neko/pkg/types"
+	"github.com/demodesk/neko/pkg/utils"
+)
+
+func (h *MembersHandler) rolesList(w http.ResponseWriter, r *http.Request) error {
+	return utils.HttpSuccess(w, h.members.ListRoles())
+}
+
+func (h *MembersHandler) rolesUpdate(w http.ResponseWriter, r *http.Request) error {
+	data := &types.MemberProfile{}
+	if err := utils.HttpJsonRequest(w, r, data); err != nil {
+		return err
+	}
+
+	if err := h.members.UpdateRole(chi.URLParam(r, "roleName"), *data); err != nil {
+		return utils.HttpInternalServerError().WithInternalErr(err)
+	}
+
+	return utils.HttpSuccess(w)
+}
+
+func (h *MembersHandler) rolesDelete(w http.ResponseWriter, r *http.Request) error {
+	if err := h.members.DeleteRole(chi.URLParam(r, "roleName")); err != nil {
+		if errors.Is(err, types.ErrMemberRoleNotFound) {
+			return utils.HttpNotFound("role not found")
+		}
+
+		return utils.HttpInternalServerError().WithInternalErr(err)
+	}
+
+	return utils.HttpSuccess(w)
+}
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode"
+
+	"github.com/demodesk/neko/pkg/types"
+	"github.com/demodesk/neko/pkg/utils"
+)
+
+const (
+	// cookie holding state, nonce and redirect of pending oidc login
+	oidcCookieName   = "neko_oidc"
+	oidcCookieMaxAge = 600
+)
+
+func (api *ApiManagerCtx) LoginOIDC(w http.ResponseWriter, r *http.Request) error {
+	redirect := oidcRedirect(r.URL.Query().Get("redirect"))
+
+	state, err := utils.NewUID(32)
+	if err != nil {
+		return utils.HttpInternalServerError().WithInternalErr(err)
+	}
+
+	nonce, err := utils.NewUID(32)
+	if err != nil {
+		return utils.HttpInternalServerError().WithInternalErr(err)
+	}
+
+	authURL, err := api.members.OIDCAuthURL(state, nonce)
+	if err != nil {
+		if errors.Is(err, types.ErrMemberOIDCDisabled) {
+			return utils.HttpNotFound("oidc login is not enabled")
+		}
+		return utils.HttpInternalServerError().WithInternalErr(err)
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name: oidcCookieName,
+		Value: url.Values{
+			"state":    {state},
+			"nonce":    {nonce},
+			"redirect": {redirect},
+		}.Encode(),
+		Path:     "/",
+		MaxAge:   oidcCookieMaxAge,
+		Secure:   r.TLS != nil,
+		HttpOnly: true,
+		// must be sent on top-level redirect back from identity provider
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	http.Redirect(w, r, authURL, http.StatusFound)
+	return nil
+}
+
+func (api *ApiManagerCtx) LoginOIDCCallback(w http.ResponseWriter, r *http.Request) error {
+	query := r.URL.Query()
+
+	if errCode := query.Get("error"); errCode != "" {
+		return utils.HttpUnauthorized("oidc login failed: " + errCode).
+			WithInternalMsg(query.Get("error_description"))
+	}
+
+	cookie, err := r.Cookie(oidcCookieName)
+	if err != nil {
+		return utils.HttpBadRequest("oidc login was not started")
+	}
+
+	// login can be attempted only once
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcCookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+	})
+
+	pending, err := url.ParseQuery(cookie.Value)
+	if err != nil {
+		return utils.HttpBadRequest("oidc login was not started").WithInternalErr(err)
+	}
+
+	state := query.Get("state")
+	if state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(pending.Get("state"))) != 1 {
+		return utils.HttpBadRequest("invalid oidc state")
+	}
+
+	code := query.Get("code")
+	if code == "" {
+		return utils.HttpBadRequest("missing oidc code")
+	}
+
+	_, token, err := api.members.LoginOIDC(r.Context(), code, pending.Get("nonce"))
+	if err != nil {
+		if errors.Is(err, types.ErrMemberOIDCDisabled) {
+			return utils.HttpNotFound("oidc login is not enabled")
+		} else if errors.Is(err, types.ErrSessionAlreadyConnected) {
+			return utils.HttpUnprocessableEntity("session already connected")
+		} else if errors.Is(err, types.ErrSessionLoginDisabled) {
+			return utils.HttpForbidden("login is disabled for this member")
+		} else {
+			return utils.HttpUnauthorized().WithInternalErr(err)
+		}
+	}
+
+	redirect := oidcRedirect(pending.Get("redirect"))
+
+	if api.sessions.CookieEnabled() {
+		api.sessions.CookieSetToken(w, token)
+	} else {
+		// client reads token from url fragment, when cookies are disabled, so
+		// that it is not sent to servers or leaked in referer and access logs
+		redirectURL, err := url.Parse(redirect)
+		if err != nil {
+			return utils.HttpInternalServerError().WithInternalErr(err)
+		}
+
+		redirectURL.Fragment = url.Values{"token": {token}}.Encode()
+		redirect = redirectURL.String()
+	}
+
+	http.Redirect(w, r, redirect, http.StatusFound)
+	return nil
+}
+
+// oidcRedirect returns redirect after login, only local paths are allowed, to avoid open redirect.
+func oidcRedirect(redirect string) string {
+	// browsers treat backslash as slash and ignore some control characters
+	if strings.Contains(redirect, `\`) || strings.IndexFunc(redirect, unicode.IsControl) >= 0 {
+		return "/"
+	}
+
+	u, err := url.Parse(redirect)
+	if err != nil || u.Scheme != "" || u.Host != "" || u.User != nil {
+		return "/"
+	}
+
+	if !strings.HasPrefix(redirect, "/") || strings.HasPrefix(redirect, "//") || strings.HasPrefix(u.Path, "//") {
+		return "/"
+	}
+
+	return redirect
+}
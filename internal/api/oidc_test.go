@@ -0,0 +1,29 @@
+package api
+
+import "testing"
+
+func TestOIDCRedirect(t *testing.T) {
+	tests := []struct {
+		redirect string
+		want     string
+	}{
+		{"", "/"},
+		{"/", "/"},
+		{"/room?id=1#chat", "/room?id=1#chat"},
+		{"room", "/"},
+		{"//evil.com", "/"},
+		{"/%2F/evil.com", "/"},
+		{`/\evil.com`, "/"},
+		{`\\evil.com`, "/"},
+		{"/\t/evil.com", "/"},
+		{"https://evil.com/", "/"},
+		{"javascript:alert(1)", "/"},
+		{"?token=1", "/"},
+	}
+
+	for _, tt := range tests {
+		if got := oidcRedirect(tt.redirect); got != tt.want {
+			t.Errorf("oidcRedirect(%q) = %q, want %q", tt.redirect, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package room
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"net/http"
+	"time"
+
+	"github.com/demodesk/neko/pkg/auth"
+	"github.com/demodesk/neko/pkg/utils"
+)
+
+const (
+	// upper bound for sleep and wait actions
+	automationMaxDuration = 5 * time.Minute
+	// default values for wait action
+	automationWaitTimeout  = 10 * time.Second
+	automationWaitInterval = 100 * time.Millisecond
+)
+
+type AutomationRegion struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+type AutomationAction struct {
+	Type string `json:"type"`
+
+	// move, click
+	X *int `json:"x,omitempty"`
+	Y *int `json:"y,omitempty"`
+
+	// click
+	Button uint32 `json:"button,omitempty"`
+
+	// type
+	Text string `json:"text,omitempty"`
+
+	// key
+	Keys []uint32 `json:"keys,omitempty"`
+
+	// scroll
+	DeltaX     int  `json:"delta_x,omitempty"`
+	DeltaY     int  `json:"delta_y,omitempty"`
+	ControlKey bool `json:"control_key,omitempty"`
+
+	// sleep, wait (in milliseconds)
+	Duration int `json:"duration,omitempty"`
+	Timeout  int `json:"timeout,omitempty"`
+	Interval int `json:"interval,omitempty"`
+
+	// wait, whole screen if omitted
+	Region *AutomationRegion `json:"region,omitempty"`
+}
+
+type AutomationPayload struct {
+	Actions []AutomationAction `json:"actions"`
+}
+
+type AutomationResult struct {
+	Type     string `json:"type"`
+	Ok       bool   `json:"ok"`
+	Error    string `json:"error,omitempty"`
+	Duration int64  `json:"duration"`
+}
+
+type AutomationResultPayload struct {
+	Results []AutomationResult `json:"results"`
+}
+
+func (h *RoomHandler) automationMiddleware(w http.ResponseWriter, r *http.Request) (context.Context, error) {
+	session, ok := auth.GetSession(r)
+	if !ok || (!session.IsHost() && !session.Profile().IsAdmin) {
+		return nil, utils.HttpForbidden("only host or admin can run input actions")
+	}
+
+	return nil, nil
+}
+
+func (h *RoomHandler) automationRun(w http.ResponseWriter, r *http.Request) error {
+	data := &AutomationPayload{}
+	if err := utils.HttpJsonRequest(w, r, data); err != nil {
+		return err
+	}
+
+	if len(data.Actions) == 0 {
+		return utils.HttpBadRequest("no actions provided")
+	}
+
+	ctx := r.Context()
+	results := []AutomationResult{}
+
+	// actions are executed in order, stopping at the first failure
+	for _, action := range data.Actions {
+		start := time.Now()
+		err := h.automationAction(ctx, action)
+
+		result := AutomationResult{
+			Type:     action.Type,
+			Ok:       err == nil,
+			Duration: time.Since(start).Milliseconds(),
+		}
+
+		if err != nil {
+			result.Error = err.Error()
+		}
+
+		results = append(results, result)
+
+		if err != nil {
+			break
+		}
+	}
+
+	return utils.HttpSuccess(w, AutomationResultPayload{
+		Results: results,
+	})
+}
+
+func (h *RoomHandler) automationAction(ctx context.Context, action AutomationAction) error {
+	switch action.Type {
+	case "move":
+		if action.X == nil || action.Y == nil {
+			return fmt.Errorf("missing coordinates")
+		}
+
+		h.desktop.Move(*action.X, *action.Y)
+		return nil
+	case "click":
+		if action.X != nil && action.Y != nil {
+			h.desktop.Move(*action.X, *action.Y)
+		}
+
+		button := action.Button
+		if button == 0 {
+			button = 1 // left button
+		}
+
+		return h.desktop.ButtonPress(button)
+	case "type":
+		return h.desktop.TextInput(action.Text)
+	case "key":
+		if len(action.Keys) == 0 {
+			return fmt.Errorf("missing keys")
+		}
+
+		return h.desktop.KeyPress(action.Keys...)
+	case "scroll":
+		h.desktop.Scroll(action.DeltaX, action.DeltaY, action.ControlKey)
+		return nil
+	case "sleep":
+		return automationSleep(ctx, automationDuration(action.Duration, 0))
+	case "wait":
+		return h.automationWaitForChange(ctx, action)
+	default:
+		return fmt.Errorf("unknown action type %q", action.Type)
+	}
+}
+
+// automationWaitForChange waits until screenshot of given region differs from the initial one.
+func (h *RoomHandler) automationWaitForChange(ctx context.Context, action AutomationAction) error {
+	timeout := automationDuration(action.Timeout, automationWaitTimeout)
+	interval := automationDuration(action.Interval, automationWaitInterval)
+
+	initial, err := h.automationRegion(action.Region)
+	if err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if err := automationSleep(ctx, interval); err != nil {
+			return err
+		}
+
+		current, err := h.automationRegion(action.Region)
+		if err != nil {
+			return err
+		}
+
+		if !bytes.Equal(initial, current) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("region did not change within %v", timeout)
+}
+
+// automationRegion returns raw pixels of given screen region.
+func (h *RoomHandler) automationRegion(region *AutomationRegion) ([]byte, error) {
+	img := h.desktop.GetScreenshotImage()
+	if img == nil {
+		return nil, fmt.Errorf("unable to get screenshot")
+	}
+
+	rect := img.Bounds()
+	if region != nil {
+		rect = image.Rect(region.X, region.Y, region.X+region.Width, region.Y+region.Height).Intersect(rect)
+		if rect.Empty() {
+			return nil, fmt.Errorf("region is outside of the screen")
+		}
+	}
+
+	sub := img.SubImage(rect).(*image.RGBA)
+
+	pixels := make([]byte, 0, rect.Dx()*rect.Dy()*4)
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		offset := sub.PixOffset(rect.Min.X, y)
+		pixels = append(pixels, sub.Pix[offset:offset+rect.Dx()*4]...)
+	}
+
+	return pixels, nil
+}
+
+func automationDuration(ms int, def time.Duration) time.Duration {
+	if ms <= 0 {
+		return def
+	}
+
+	duration := time.Duration(ms) * time.Millisecond
+	if duration > automationMaxDuration {
+		return automationMaxDuration
+	}
+
+	return duration
+}
+
+func automationSleep(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
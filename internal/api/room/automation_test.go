@@ -0,0 +1,210 @@
+package room
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"image"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/demodesk/neko/pkg/types"
+)
+
+// fakeDesktop records input calls, methods that are not overridden panic.
+type fakeDesktop struct {
+	types.DesktopManager
+
+	calls       []string
+	err         error
+	screenshots []*image.RGBA
+}
+
+func (d *fakeDesktop) Move(x, y int) {
+	d.calls = append(d.calls, "move")
+}
+
+func (d *fakeDesktop) Scroll(deltaX, deltaY int, controlKey bool) {
+	d.calls = append(d.calls, "scroll")
+}
+
+func (d *fakeDesktop) ButtonPress(code uint32) error {
+	d.calls = append(d.calls, "button_press")
+	return d.err
+}
+
+func (d *fakeDesktop) KeyPress(codes ...uint32) error {
+	d.calls = append(d.calls, "key_press")
+	return d.err
+}
+
+func (d *fakeDesktop) TextInput(text string) error {
+	d.calls = append(d.calls, "text")
+	return d.err
+}
+
+// GetScreenshotImage returns screenshots in order, the last one is repeated.
+func (d *fakeDesktop) GetScreenshotImage() *image.RGBA {
+	if len(d.screenshots) == 0 {
+		return nil
+	}
+
+	img := d.screenshots[0]
+	if len(d.screenshots) > 1 {
+		d.screenshots = d.screenshots[1:]
+	}
+	return img
+}
+
+func newScreenshot(value byte) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	for i := range img.Pix {
+		img.Pix[i] = value
+	}
+	return img
+}
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func TestRoomHandler_automationAction(t *testing.T) {
+	errDesktop := errors.New("desktop error")
+
+	tests := []struct {
+		name    string
+		action  AutomationAction
+		err     error
+		wantErr bool
+		calls   string
+	}{
+		{"move", AutomationAction{Type: "move", X: intPtr(1), Y: intPtr(2)}, nil, false, "move"},
+		{"move without coordinates", AutomationAction{Type: "move", X: intPtr(1)}, nil, true, ""},
+		{"click", AutomationAction{Type: "click", X: intPtr(1), Y: intPtr(2)}, nil, false, "move,button_press"},
+		{"click without coordinates", AutomationAction{Type: "click"}, nil, false, "button_press"},
+		{"click error", AutomationAction{Type: "click"}, errDesktop, true, "button_press"},
+		{"type", AutomationAction{Type: "type", Text: "ahoj"}, nil, false, "text"},
+		{"type error", AutomationAction{Type: "type", Text: "ahoj"}, errDesktop, true, "text"},
+		{"key", AutomationAction{Type: "key", Keys: []uint32{65}}, nil, false, "key_press"},
+		{"key without keys", AutomationAction{Type: "key"}, nil, true, ""},
+		{"scroll", AutomationAction{Type: "scroll", DeltaY: 10}, nil, false, "scroll"},
+		{"sleep", AutomationAction{Type: "sleep", Duration: 1}, nil, false, ""},
+		{"unknown", AutomationAction{Type: "jump"}, nil, true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			desktop := &fakeDesktop{err: tt.err}
+			h := &RoomHandler{desktop: desktop}
+
+			err := h.automationAction(context.Background(), tt.action)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("h.automationAction() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if calls := strings.Join(desktop.calls, ","); calls != tt.calls {
+				t.Errorf("desktop calls = %q, want %q", calls, tt.calls)
+			}
+		})
+	}
+}
+
+func TestRoomHandler_automationWaitForChange(t *testing.T) {
+	tests := []struct {
+		name        string
+		screenshots []*image.RGBA
+		region      *AutomationRegion
+		wantErr     string
+	}{
+		{"changed", []*image.RGBA{newScreenshot(0), newScreenshot(0), newScreenshot(1)}, nil, ""},
+		{"changed in region", []*image.RGBA{newScreenshot(0), newScreenshot(1)}, &AutomationRegion{X: 1, Y: 1, Width: 2, Height: 2}, ""},
+		{"timeout", []*image.RGBA{newScreenshot(0)}, nil, "did not change"},
+		{"region outside of screen", []*image.RGBA{newScreenshot(0)}, &AutomationRegion{X: 10, Y: 10, Width: 2, Height: 2}, "outside of the screen"},
+		{"no screenshot", nil, nil, "unable to get screenshot"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &RoomHandler{desktop: &fakeDesktop{screenshots: tt.screenshots}}
+
+			err := h.automationWaitForChange(context.Background(), AutomationAction{
+				Type:     "wait",
+				Timeout:  50,
+				Interval: 5,
+				Region:   tt.region,
+			})
+
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("h.automationWaitForChange() returned error: %s", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("h.automationWaitForChange() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	// canceled request stops waiting
+	h := &RoomHandler{desktop: &fakeDesktop{screenshots: []*image.RGBA{newScreenshot(0)}}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := h.automationWaitForChange(ctx, AutomationAction{Type: "wait", Timeout: 1000})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("h.automationWaitForChange() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestAutomationDuration(t *testing.T) {
+	tests := []struct {
+		ms   int
+		def  time.Duration
+		want time.Duration
+	}{
+		{0, time.Second, time.Second},
+		{-1, time.Second, time.Second},
+		{250, time.Second, 250 * time.Millisecond},
+		{int(time.Hour / time.Millisecond), time.Second, automationMaxDuration},
+	}
+
+	for _, tt := range tests {
+		if got := automationDuration(tt.ms, tt.def); got != tt.want {
+			t.Errorf("automationDuration(%d, %s) = %s, want %s", tt.ms, tt.def, got, tt.want)
+		}
+	}
+}
+
+func TestRoomHandler_automationRun(t *testing.T) {
+	desktop := &fakeDesktop{}
+	h := &RoomHandler{desktop: desktop}
+
+	// actions after the first failure are not executed
+	body := `{"actions":[{"type":"key","keys":[65]},{"type":"move"},{"type":"type","text":"ahoj"}]}`
+	r := httptest.NewRequest("POST", "/input/", strings.NewReader(body))
+	w := httptest.NewRecorder()
+
+	if err := h.automationRun(w, r); err != nil {
+		t.Fatalf("h.automationRun() returned error: %s", err)
+	}
+
+	var res AutomationResultPayload
+	if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+		t.Fatalf("json.Decode() returned error: %s", err)
+	}
+
+	if len(res.Results) != 2 || !res.Results[0].Ok || res.Results[1].Ok || res.Results[1].Error == "" {
+		t.Errorf("h.automationRun() results = %+v", res.Results)
+	}
+
+	if calls := strings.Join(desktop.calls, ","); calls != "key_press" {
+		t.Errorf("desktop calls = %q, want %q", calls, "key_press")
+	}
+
+	// empty list is rejected
+	r = httptest.NewRequest("POST", "/input/", strings.NewReader(`{"actions":[]}`))
+	if err := h.automationRun(httptest.NewRecorder(), r); err == nil {
+		t.Errorf("h.automationRun() expected error for empty actions")
+	}
+}
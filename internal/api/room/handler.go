@@ -108,6 +108,16 @@ func (h *RoomHandler) Route(r types.Router) {
 		r.With(auth.AdminsOnly).Get("/shot.jpg", h.screenShotGet)
 	})
 
+	r.Route("/input", func(r types.Router) {
+		r.With(auth.CanHostOnly).With(h.automationMiddleware).Post("/", h.automationRun)
+
+		r.With(auth.AdminsOnly).Get("/record", h.inputRecordStatus)
+		r.With(auth.AdminsOnly).Post("/record/start", h.inputRecordStart)
+		r.With(auth.AdminsOnly).Post("/record/stop", h.inputRecordStop)
+		r.With(auth.AdminsOnly).Post("/replay/start", h.inputReplayStart)
+		r.With(auth.AdminsOnly).Post("/replay/stop", h.inputReplayStop)
+	})
+
 	r.With(h.uploadMiddleware).Route("/upload", func(r types.Router) {
 		r.Post("/drop", h.uploadDrop)
 		r.Post("/dialog", h.uploadDialogPost)
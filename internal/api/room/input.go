@@ -0,0 +1,98 @@
+package room
+
+import (
+	"errors"
+	"net/http"
+	"os"
+
+	"github.com/demodesk/neko/pkg/types"
+	"github.com/demodesk/neko/pkg/utils"
+)
+
+type InputRecordPayload struct {
+	Name string `json:"name"`
+}
+
+type InputReplayPayload struct {
+	Name  string  `json:"name"`
+	Speed float64 `json:"speed"`
+}
+
+func inputRecordError(err error) error {
+	switch {
+	case errors.Is(err, types.ErrInputRecordingDisabled):
+		return utils.HttpForbidden(err.Error())
+	case errors.Is(err, types.ErrInputRecordingName):
+		return utils.HttpBadRequest(err.Error())
+	case errors.Is(err, os.ErrNotExist):
+		return utils.HttpNotFound("recording not found")
+	case errors.Is(err, types.ErrInputRecordingActive),
+		errors.Is(err, types.ErrInputRecordingInactive),
+		errors.Is(err, types.ErrInputReplayActive),
+		errors.Is(err, types.ErrInputReplayInactive):
+		return utils.HttpUnprocessableEntity(err.Error())
+	default:
+		return utils.HttpInternalServerError().WithInternalErr(err)
+	}
+}
+
+func (h *RoomHandler) inputRecordStatus(w http.ResponseWriter, r *http.Request) error {
+	return utils.HttpSuccess(w, h.desktop.InputRecordStatus())
+}
+
+func (h *RoomHandler) inputRecordStart(w http.ResponseWriter, r *http.Request) error {
+	data := &InputRecordPayload{}
+	if err := utils.HttpJsonRequest(w, r, data); err != nil {
+		return err
+	}
+
+	if data.Name == "" {
+		return utils.HttpBadRequest("missing recording name")
+	}
+
+	if err := h.desktop.InputRecordStart(data.Name); err != nil {
+		return inputRecordError(err)
+	}
+
+	return utils.HttpSuccess(w)
+}
+
+func (h *RoomHandler) inputRecordStop(w http.ResponseWriter, r *http.Request) error {
+	if err := h.desktop.InputRecordStop(); err != nil {
+		return inputRecordError(err)
+	}
+
+	return utils.HttpSuccess(w)
+}
+
+func (h *RoomHandler) inputReplayStart(w http.ResponseWriter, r *http.Request) error {
+	data := &InputReplayPayload{
+		Speed: 1,
+	}
+
+	if err := utils.HttpJsonRequest(w, r, data); err != nil {
+		return err
+	}
+
+	if data.Name == "" {
+		return utils.HttpBadRequest("missing recording name")
+	}
+
+	if data.Speed <= 0 {
+		return utils.HttpBadRequest("speed must be positive")
+	}
+
+	if err := h.desktop.InputReplayStart(data.Name, data.Speed); err != nil {
+		return inputRecordError(err)
+	}
+
+	return utils.HttpSuccess(w)
+}
+
+func (h *RoomHandler) inputReplayStop(w http.ResponseWriter, r *http.Request) error {
+	if err := h.desktop.InputReplayStop(); err != nil {
+		return inputRecordError(err)
+	}
+
+	return utils.HttpSuccess(w)
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"net/http"
 
+	"github.com/demodesk/neko/internal/api/invites"
 	"github.com/demodesk/neko/internal/api/members"
 	"github.com/demodesk/neko/internal/api/room"
 	"github.com/demodesk/neko/pkg/auth"
@@ -38,6 +39,11 @@ func New(
 
 func (api *ApiManagerCtx) Route(r types.Router) {
 	r.Post("/login", api.Login)
+	r.Get("/login/oidc", api.LoginOIDC)
+	r.Get("/login/oidc/callback", api.LoginOIDCCallback)
+	r.Post("/login/invite", api.LoginInvite)
+	r.Post("/login/totp", api.LoginTOTP)
+	r.Post("/login/totp/enroll", api.LoginTOTPEnroll)
 
 	// Authenticated area
 	r.Group(func(r types.Router) {
@@ -45,11 +51,21 @@ func (api *ApiManagerCtx) Route(r types.Router) {
 
 		r.Post("/logout", api.Logout)
 		r.Get("/whoami", api.Whoami)
+		r.Post("/totp/enroll", api.TOTPEnroll)
+		r.Post("/totp/confirm", api.TOTPConfirm)
+		r.Post("/totp/disable", api.TOTPDisable)
 		r.Get("/sessions", api.Sessions)
+		r.Get("/sessions/{sessionId}/webrtc/stats", api.SessionWebRTCStats)
+		r.With(auth.AdminsOnly).Delete("/sessions/{sessionId}", api.SessionDelete)
 
 		membersHandler := members.New(api.members)
 		r.Route("/members", membersHandler.Route)
 		r.Route("/members_bulk", membersHandler.RouteBulk)
+		r.Route("/lockouts", membersHandler.RouteLockouts)
+		r.Route("/roles", membersHandler.RouteRoles)
+
+		invitesHandler := invites.New(api.members)
+		r.Route("/invites", invitesHandler.Route)
 
 		roomHandler := room.New(api.sessions, api.desktop, api.capture)
 		r.Route("/room", roomHandler.Route)
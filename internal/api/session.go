@@ -2,8 +2,11 @@ package api
 
 import (
 	"errors"
+	"net"
 	"net/http"
 
+	"github.com/go-chi/chi"
+
 	"github.com/demodesk/neko/pkg/auth"
 	"github.com/demodesk/neko/pkg/types"
 	"github.com/demodesk/neko/pkg/utils"
@@ -14,6 +17,11 @@ type SessionLoginPayload struct {
 	Password string `json:"password"`
 }
 
+type SessionLoginInvitePayload struct {
+	Token string `json:"token"`
+	Name  string `json:"name"`
+}
+
 type SessionDataPayload struct {
 	ID      string              `json:"id"`
 	Token   string              `json:"token,omitempty"`
@@ -27,17 +35,56 @@ func (api *ApiManagerCtx) Login(w http.ResponseWriter, r *http.Request) error {
 		return err
 	}
 
-	session, token, err := api.members.Login(data.Username, data.Password)
+	session, token, err := api.members.Login(data.Username, data.Password, remoteIP(r))
 	if err != nil {
+		// login must be finished with second factor
+		var challenge *types.MemberTOTPChallenge
+		if errors.As(err, &challenge) {
+			utils.HttpJsonResponse(w, http.StatusAccepted, SessionTOTPChallengePayload{
+				Challenge: challenge.Token,
+				Enroll:    challenge.Enroll,
+			})
+			return nil
+		}
+
 		if errors.Is(err, types.ErrSessionAlreadyConnected) {
 			return utils.HttpUnprocessableEntity("session already connected")
 		} else if errors.Is(err, types.ErrMemberDoesNotExist) || errors.Is(err, types.ErrMemberInvalidPassword) {
 			return utils.HttpUnauthorized().WithInternalErr(err)
+		} else if errors.Is(err, types.ErrMemberLockedOut) {
+			return utils.HttpError(http.StatusTooManyRequests, "too many failed login attempts, try again later").WithInternalErr(err)
+		} else if errors.Is(err, types.ErrMemberTOTPUnsupported) {
+			return utils.HttpForbidden("two-factor authentication is required, but not supported")
 		} else {
 			return utils.HttpInternalServerError().WithInternalErr(err)
 		}
 	}
 
+	return utils.HttpSuccess(w, api.loginData(w, session, token))
+}
+
+func (api *ApiManagerCtx) LoginInvite(w http.ResponseWriter, r *http.Request) error {
+	data := &SessionLoginInvitePayload{}
+	if err := utils.HttpJsonRequest(w, r, data); err != nil {
+		return err
+	}
+
+	session, token, err := api.members.LoginInvite(data.Token, data.Name)
+	if err != nil {
+		if errors.Is(err, types.ErrMemberInviteNotFound) || errors.Is(err, types.ErrMemberInviteUsedUp) {
+			return utils.HttpUnauthorized().WithInternalErr(err)
+		} else if errors.Is(err, types.ErrSessionLoginDisabled) {
+			return utils.HttpForbidden("login is disabled for this invite")
+		} else {
+			return utils.HttpInternalServerError().WithInternalErr(err)
+		}
+	}
+
+	return utils.HttpSuccess(w, api.loginData(w, session, token))
+}
+
+// loginData sets session token as cookie, or returns it in payload if cookies are disabled.
+func (api *ApiManagerCtx) loginData(w http.ResponseWriter, session types.Session, token string) SessionDataPayload {
 	sessionData := SessionDataPayload{
 		ID:      session.ID(),
 		Profile: session.Profile(),
@@ -50,7 +97,7 @@ func (api *ApiManagerCtx) Login(w http.ResponseWriter, r *http.Request) error {
 		sessionData.Token = token
 	}
 
-	return utils.HttpSuccess(w, sessionData)
+	return sessionData
 }
 
 func (api *ApiManagerCtx) Logout(w http.ResponseWriter, r *http.Request) error {
@@ -94,3 +141,50 @@ func (api *ApiManagerCtx) Sessions(w http.ResponseWriter, r *http.Request) error
 
 	return utils.HttpSuccess(w, sessions)
 }
+
+func (api *ApiManagerCtx) SessionDelete(w http.ResponseWriter, r *http.Request) error {
+	sessionId := chi.URLParam(r, "sessionId")
+
+	err := api.members.Logout(sessionId)
+	if err != nil {
+		if errors.Is(err, types.ErrSessionNotFound) {
+			return utils.HttpNotFound("session not found")
+		} else {
+			return utils.HttpInternalServerError().WithInternalErr(err)
+		}
+	}
+
+	return utils.HttpSuccess(w)
+}
+
+func (api *ApiManagerCtx) SessionWebRTCStats(w http.ResponseWriter, r *http.Request) error {
+	session, _ := auth.GetSession(r)
+	sessionId := chi.URLParam(r, "sessionId")
+
+	// only admins can see stats of other sessions
+	if session.ID() != sessionId && !session.Profile().IsAdmin {
+		return utils.HttpForbidden("session is not admin")
+	}
+
+	target, ok := api.sessions.Get(sessionId)
+	if !ok {
+		return utils.HttpNotFound("session not found")
+	}
+
+	peer := target.GetWebRTCPeer()
+	if peer == nil {
+		return utils.HttpUnprocessableEntity("session is not connected")
+	}
+
+	return utils.HttpSuccess(w, peer.Stats())
+}
+
+// remoteIP returns client IP address, when behind proxy it is already set from headers.
+func remoteIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return ip
+}
@@ -0,0 +1,146 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/demodesk/neko/pkg/auth"
+	"github.com/demodesk/neko/pkg/types"
+	"github.com/demodesk/neko/pkg/utils"
+)
+
+type SessionTOTPChallengePayload struct {
+	Challenge string `json:"challenge"`
+	// member must enroll TOTP before login
+	Enroll bool `json:"enroll"`
+}
+
+type SessionLoginTOTPPayload struct {
+	Challenge string `json:"challenge"`
+	// TOTP code or recovery code
+	Code string `json:"code"`
+}
+
+type SessionLoginTOTPDataPayload struct {
+	SessionDataPayload
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+}
+
+type TOTPEnrollPayload struct {
+	Secret string `json:"secret"`
+	URL    string `json:"url"`
+}
+
+type TOTPCodePayload struct {
+	Code string `json:"code"`
+}
+
+type TOTPRecoveryCodesPayload struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+func (api *ApiManagerCtx) LoginTOTP(w http.ResponseWriter, r *http.Request) error {
+	data := &SessionLoginTOTPPayload{}
+	if err := utils.HttpJsonRequest(w, r, data); err != nil {
+		return err
+	}
+
+	session, token, recoveryCodes, err := api.members.LoginTOTP(data.Challenge, data.Code, remoteIP(r))
+	if err != nil {
+		return totpError(err)
+	}
+
+	return utils.HttpSuccess(w, SessionLoginTOTPDataPayload{
+		SessionDataPayload: api.loginData(w, session, token),
+		RecoveryCodes:      recoveryCodes,
+	})
+}
+
+func (api *ApiManagerCtx) LoginTOTPEnroll(w http.ResponseWriter, r *http.Request) error {
+	data := &SessionLoginTOTPPayload{}
+	if err := utils.HttpJsonRequest(w, r, data); err != nil {
+		return err
+	}
+
+	secret, url, err := api.members.LoginTOTPEnroll(data.Challenge)
+	if err != nil {
+		return totpError(err)
+	}
+
+	return utils.HttpSuccess(w, TOTPEnrollPayload{
+		Secret: secret,
+		URL:    url,
+	})
+}
+
+func (api *ApiManagerCtx) TOTPEnroll(w http.ResponseWriter, r *http.Request) error {
+	session, _ := auth.GetSession(r)
+
+	secret, url, err := api.members.TOTPEnroll(session.ID())
+	if err != nil {
+		return totpError(err)
+	}
+
+	return utils.HttpSuccess(w, TOTPEnrollPayload{
+		Secret: secret,
+		URL:    url,
+	})
+}
+
+func (api *ApiManagerCtx) TOTPConfirm(w http.ResponseWriter, r *http.Request) error {
+	session, _ := auth.GetSession(r)
+
+	data := &TOTPCodePayload{}
+	if err := utils.HttpJsonRequest(w, r, data); err != nil {
+		return err
+	}
+
+	recoveryCodes, err := api.members.TOTPConfirm(session.ID(), data.Code)
+	if err != nil {
+		return totpError(err)
+	}
+
+	return utils.HttpSuccess(w, TOTPRecoveryCodesPayload{
+		RecoveryCodes: recoveryCodes,
+	})
+}
+
+func (api *ApiManagerCtx) TOTPDisable(w http.ResponseWriter, r *http.Request) error {
+	session, _ := auth.GetSession(r)
+
+	data := &TOTPCodePayload{}
+	if err := utils.HttpJsonRequest(w, r, data); err != nil {
+		return err
+	}
+
+	if err := api.members.TOTPDisable(session.ID(), data.Code); err != nil {
+		return totpError(err)
+	}
+
+	return utils.HttpSuccess(w)
+}
+
+func totpError(err error) error {
+	switch {
+	case errors.Is(err, types.ErrMemberTOTPChallengeInvalid):
+		return utils.HttpUnauthorized().WithInternalErr(err)
+	case errors.Is(err, types.ErrMemberTOTPInvalidCode):
+		return utils.HttpForbidden("invalid two-factor authentication code")
+	case errors.Is(err, types.ErrMemberLockedOut):
+		return utils.HttpError(http.StatusTooManyRequests, "too many failed login attempts, try again later").WithInternalErr(err)
+	case errors.Is(err, types.ErrMemberTOTPUnsupported):
+		return utils.HttpNotFound("two-factor authentication is not supported")
+	case errors.Is(err, types.ErrMemberTOTPNotEnrolled):
+		return utils.HttpUnprocessableEntity("two-factor authentication is not enrolled")
+	case errors.Is(err, types.ErrMemberTOTPEnabled):
+		return utils.HttpUnprocessableEntity("two-factor authentication is already enabled")
+	case errors.Is(err, types.ErrMemberTOTPRequired):
+		return utils.HttpForbidden("two-factor authentication is required")
+	case errors.Is(err, types.ErrMemberDoesNotExist):
+		return utils.HttpNotFound("member not found")
+	case errors.Is(err, types.ErrSessionAlreadyConnected):
+		return utils.HttpUnprocessableEntity("session already connected")
+	default:
+		return utils.HttpInternalServerError().WithInternalErr(err)
+	}
+}
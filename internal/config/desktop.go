@@ -19,6 +19,8 @@ type Desktop struct {
 	UseInputDriver bool
 	InputSocket    string
 
+	InputRecordings string
+
 	Unminimize        bool
 	UploadDrop        bool
 	FileChooserDialog bool
@@ -40,6 +42,11 @@ func (Desktop) Init(cmd *cobra.Command) error {
 		return err
 	}
 
+	cmd.PersistentFlags().String("desktop.input.recordings", "", "directory where recorded host input is stored and replayed from, empty to disable")
+	if err := viper.BindPFlag("desktop.input.recordings", cmd.PersistentFlags().Lookup("desktop.input.recordings")); err != nil {
+		return err
+	}
+
 	cmd.PersistentFlags().Bool("desktop.unminimize", true, "automatically unminimize window when it is minimized")
 	if err := viper.BindPFlag("desktop.unminimize", cmd.PersistentFlags().Lookup("desktop.unminimize")); err != nil {
 		return err
@@ -85,6 +92,7 @@ func (s *Desktop) Set() {
 
 	s.UseInputDriver = viper.GetBool("desktop.input.enabled")
 	s.InputSocket = viper.GetString("desktop.input.socket")
+	s.InputRecordings = viper.GetString("desktop.input.recordings")
 	s.Unminimize = viper.GetBool("desktop.unminimize")
 	s.UploadDrop = viper.GetBool("desktop.upload_drop")
 	s.FileChooserDialog = viper.GetBool("desktop.file_chooser_dialog")
@@ -1,13 +1,18 @@
 package config
 
 import (
+	"time"
+
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
 	"github.com/demodesk/neko/internal/member/file"
+	"github.com/demodesk/neko/internal/member/ldap"
 	"github.com/demodesk/neko/internal/member/multiuser"
 	"github.com/demodesk/neko/internal/member/object"
+	"github.com/demodesk/neko/internal/member/oidc"
+	"github.com/demodesk/neko/internal/member/sql"
 	"github.com/demodesk/neko/pkg/types"
 	"github.com/demodesk/neko/pkg/utils"
 )
@@ -15,10 +20,24 @@ import (
 type Member struct {
 	Provider string
 
+	TOTPIssuer       string
+	TOTPRequireAdmin bool
+
+	LockoutAttempts    int
+	LockoutIPAttempts  int
+	LockoutDuration    time.Duration
+	LockoutMaxDuration time.Duration
+
+	Roles     map[string]types.MemberProfile
+	RolesFile string
+
 	// providers
 	File      file.Config
 	Object    object.Config
 	Multiuser multiuser.Config
+	OIDC      oidc.Config
+	LDAP      ldap.Config
+	SQL       sql.Config
 }
 
 func (Member) Init(cmd *cobra.Command) error {
@@ -27,13 +46,56 @@ func (Member) Init(cmd *cobra.Command) error {
 		return err
 	}
 
+	// two-factor authentication
+	cmd.PersistentFlags().String("member.totp.issuer", "neko", "issuer shown in authenticator apps for TOTP enrollment")
+	if err := viper.BindPFlag("member.totp.issuer", cmd.PersistentFlags().Lookup("member.totp.issuer")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Bool("member.totp.require_admin", false, "require TOTP for admins, supported by file, object and sql providers, startup fails with other providers")
+	if err := viper.BindPFlag("member.totp.require_admin", cmd.PersistentFlags().Lookup("member.totp.require_admin")); err != nil {
+		return err
+	}
+
+	// roles
+	cmd.PersistentFlags().String("member.roles", "{}", "named roles in JSON format, that member profiles can reference, they extend built-in viewer, participant, presenter and admin roles")
+	if err := viper.BindPFlag("member.roles", cmd.PersistentFlags().Lookup("member.roles")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("member.roles_file", "", "if roles changed using API should be stored in a file, otherwise they will be stored only in memory")
+	if err := viper.BindPFlag("member.roles_file", cmd.PersistentFlags().Lookup("member.roles_file")); err != nil {
+		return err
+	}
+
+	// brute-force protection
+	cmd.PersistentFlags().Int("member.lockout.attempts", 5, "failed login attempts per username before temporary lockout, zero disables it")
+	if err := viper.BindPFlag("member.lockout.attempts", cmd.PersistentFlags().Lookup("member.lockout.attempts")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Int("member.lockout.ip_attempts", 20, "failed login attempts per IP address before temporary lockout, zero disables it")
+	if err := viper.BindPFlag("member.lockout.ip_attempts", cmd.PersistentFlags().Lookup("member.lockout.ip_attempts")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Duration("member.lockout.duration", time.Minute, "duration of first lockout, it doubles with every further failed attempt")
+	if err := viper.BindPFlag("member.lockout.duration", cmd.PersistentFlags().Lookup("member.lockout.duration")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Duration("member.lockout.max_duration", time.Hour, "maximum lockout duration, failed attempts are forgotten after this time without failures")
+	if err := viper.BindPFlag("member.lockout.max_duration", cmd.PersistentFlags().Lookup("member.lockout.max_duration")); err != nil {
+		return err
+	}
+
 	// file provider
 	cmd.PersistentFlags().String("member.file.path", "", "member file provider: storage path")
 	if err := viper.BindPFlag("member.file.path", cmd.PersistentFlags().Lookup("member.file.path")); err != nil {
 		return err
 	}
 
-	cmd.PersistentFlags().Bool("member.file.hash", true, "member file provider: whether to hash passwords using sha256 (recommended)")
+	cmd.PersistentFlags().Bool("member.file.hash", true, "member file provider: whether to hash passwords using argon2id (recommended)")
 	if err := viper.BindPFlag("member.file.hash", cmd.PersistentFlags().Lookup("member.file.hash")); err != nil {
 		return err
 	}
@@ -44,6 +106,22 @@ func (Member) Init(cmd *cobra.Command) error {
 		return err
 	}
 
+	// sql provider
+	cmd.PersistentFlags().String("member.sql.driver", "sqlite3", "member sql provider: database driver, sqlite3 or postgres")
+	if err := viper.BindPFlag("member.sql.driver", cmd.PersistentFlags().Lookup("member.sql.driver")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("member.sql.dsn", "members.db", "member sql provider: data source name, file path for sqlite3 or connection string for postgres")
+	if err := viper.BindPFlag("member.sql.dsn", cmd.PersistentFlags().Lookup("member.sql.dsn")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Bool("member.sql.hash", true, "member sql provider: whether to hash passwords using argon2id (recommended)")
+	if err := viper.BindPFlag("member.sql.hash", cmd.PersistentFlags().Lookup("member.sql.hash")); err != nil {
+		return err
+	}
+
 	// multiuser provider
 	cmd.PersistentFlags().String("member.multiuser.user_password", "neko", "member multiuser provider: user password")
 	if err := viper.BindPFlag("member.multiuser.user_password", cmd.PersistentFlags().Lookup("member.multiuser.user_password")); err != nil {
@@ -65,12 +143,139 @@ func (Member) Init(cmd *cobra.Command) error {
 		return err
 	}
 
+	// oidc provider
+	cmd.PersistentFlags().String("member.oidc.issuer", "", "member oidc provider: issuer URL, used for discovery")
+	if err := viper.BindPFlag("member.oidc.issuer", cmd.PersistentFlags().Lookup("member.oidc.issuer")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("member.oidc.client_id", "", "member oidc provider: client ID")
+	if err := viper.BindPFlag("member.oidc.client_id", cmd.PersistentFlags().Lookup("member.oidc.client_id")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("member.oidc.client_secret", "", "member oidc provider: client secret")
+	if err := viper.BindPFlag("member.oidc.client_secret", cmd.PersistentFlags().Lookup("member.oidc.client_secret")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("member.oidc.redirect_url", "", "member oidc provider: redirect URL pointing to /api/login/oidc/callback")
+	if err := viper.BindPFlag("member.oidc.redirect_url", cmd.PersistentFlags().Lookup("member.oidc.redirect_url")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().StringSlice("member.oidc.scopes", []string{"openid", "profile", "email"}, "member oidc provider: requested scopes")
+	if err := viper.BindPFlag("member.oidc.scopes", cmd.PersistentFlags().Lookup("member.oidc.scopes")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("member.oidc.id_claim", "sub", "member oidc provider: claim used as member ID")
+	if err := viper.BindPFlag("member.oidc.id_claim", cmd.PersistentFlags().Lookup("member.oidc.id_claim")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("member.oidc.name_claim", "preferred_username", "member oidc provider: claim used as member name")
+	if err := viper.BindPFlag("member.oidc.name_claim", cmd.PersistentFlags().Lookup("member.oidc.name_claim")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("member.oidc.groups_claim", "groups", "member oidc provider: claim with list of groups")
+	if err := viper.BindPFlag("member.oidc.groups_claim", cmd.PersistentFlags().Lookup("member.oidc.groups_claim")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("member.oidc.default_profile", "{}", "member oidc provider: profile before rules are applied in JSON format")
+	if err := viper.BindPFlag("member.oidc.default_profile", cmd.PersistentFlags().Lookup("member.oidc.default_profile")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("member.oidc.rules", "[]", "member oidc provider: rules mapping claims and groups to profile in JSON format")
+	if err := viper.BindPFlag("member.oidc.rules", cmd.PersistentFlags().Lookup("member.oidc.rules")); err != nil {
+		return err
+	}
+
+	// ldap provider
+	cmd.PersistentFlags().String("member.ldap.url", "", "member ldap provider: URL of directory server, e.g. ldaps://ldap.example.com")
+	if err := viper.BindPFlag("member.ldap.url", cmd.PersistentFlags().Lookup("member.ldap.url")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Bool("member.ldap.starttls", false, "member ldap provider: whether to upgrade connection using StartTLS")
+	if err := viper.BindPFlag("member.ldap.starttls", cmd.PersistentFlags().Lookup("member.ldap.starttls")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("member.ldap.bind_dn", "", "member ldap provider: service account DN used for searching, anonymous if empty")
+	if err := viper.BindPFlag("member.ldap.bind_dn", cmd.PersistentFlags().Lookup("member.ldap.bind_dn")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("member.ldap.bind_password", "", "member ldap provider: service account password")
+	if err := viper.BindPFlag("member.ldap.bind_password", cmd.PersistentFlags().Lookup("member.ldap.bind_password")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("member.ldap.base_dn", "", "member ldap provider: base DN for searching members")
+	if err := viper.BindPFlag("member.ldap.base_dn", cmd.PersistentFlags().Lookup("member.ldap.base_dn")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("member.ldap.user_filter", "(objectClass=person)", "member ldap provider: filter matching all members")
+	if err := viper.BindPFlag("member.ldap.user_filter", cmd.PersistentFlags().Lookup("member.ldap.user_filter")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("member.ldap.login_attribute", "uid", "member ldap provider: attribute matched against username on login")
+	if err := viper.BindPFlag("member.ldap.login_attribute", cmd.PersistentFlags().Lookup("member.ldap.login_attribute")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("member.ldap.id_attribute", "uid", "member ldap provider: attribute used as member ID")
+	if err := viper.BindPFlag("member.ldap.id_attribute", cmd.PersistentFlags().Lookup("member.ldap.id_attribute")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("member.ldap.name_attribute", "cn", "member ldap provider: attribute used as member name")
+	if err := viper.BindPFlag("member.ldap.name_attribute", cmd.PersistentFlags().Lookup("member.ldap.name_attribute")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("member.ldap.group_attribute", "memberOf", "member ldap provider: attribute listing DNs of member groups")
+	if err := viper.BindPFlag("member.ldap.group_attribute", cmd.PersistentFlags().Lookup("member.ldap.group_attribute")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("member.ldap.default_profile", "{}", "member ldap provider: profile before groups are applied in JSON format")
+	if err := viper.BindPFlag("member.ldap.default_profile", cmd.PersistentFlags().Lookup("member.ldap.default_profile")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("member.ldap.groups", "[]", "member ldap provider: groups mapped to profile in JSON format")
+	if err := viper.BindPFlag("member.ldap.groups", cmd.PersistentFlags().Lookup("member.ldap.groups")); err != nil {
+		return err
+	}
+
 	return nil
 }
 
 func (s *Member) Set() {
 	s.Provider = viper.GetString("member.provider")
 
+	s.TOTPIssuer = viper.GetString("member.totp.issuer")
+	s.TOTPRequireAdmin = viper.GetBool("member.totp.require_admin")
+
+	s.LockoutAttempts = viper.GetInt("member.lockout.attempts")
+	s.LockoutIPAttempts = viper.GetInt("member.lockout.ip_attempts")
+	s.LockoutDuration = viper.GetDuration("member.lockout.duration")
+	s.LockoutMaxDuration = viper.GetDuration("member.lockout.max_duration")
+
+	if err := viper.UnmarshalKey("member.roles", &s.Roles, viper.DecodeHook(
+		utils.JsonStringAutoDecode(s.Roles),
+	)); err != nil {
+		log.Warn().Err(err).Msgf("unable to parse member roles")
+	}
+	s.RolesFile = viper.GetString("member.roles_file")
+
 	// file provider
 	s.File.Path = viper.GetString("member.file.path")
 	s.File.Hash = viper.GetBool("member.file.hash")
@@ -82,6 +287,11 @@ func (s *Member) Set() {
 		log.Warn().Err(err).Msgf("unable to parse member object users")
 	}
 
+	// sql provider
+	s.SQL.Driver = viper.GetString("member.sql.driver")
+	s.SQL.DSN = viper.GetString("member.sql.dsn")
+	s.SQL.Hash = viper.GetBool("member.sql.hash")
+
 	// multiuser provider
 	s.Multiuser.UserPassword = viper.GetString("member.multiuser.user_password")
 	s.Multiuser.AdminPassword = viper.GetString("member.multiuser.admin_password")
@@ -125,4 +335,78 @@ func (s *Member) Set() {
 	)); err != nil {
 		log.Warn().Err(err).Msgf("unable to parse member multiuser admin profile")
 	}
+
+	// oidc provider
+	s.OIDC.Issuer = viper.GetString("member.oidc.issuer")
+	s.OIDC.ClientID = viper.GetString("member.oidc.client_id")
+	s.OIDC.ClientSecret = viper.GetString("member.oidc.client_secret")
+	s.OIDC.RedirectURL = viper.GetString("member.oidc.redirect_url")
+	s.OIDC.Scopes = viper.GetStringSlice("member.oidc.scopes")
+	s.OIDC.IDClaim = viper.GetString("member.oidc.id_claim")
+	s.OIDC.NameClaim = viper.GetString("member.oidc.name_claim")
+	s.OIDC.GroupsClaim = viper.GetString("member.oidc.groups_claim")
+
+	// default profile, members can only watch unless rules say otherwise
+	s.OIDC.DefaultProfile = types.MemberProfile{
+		IsAdmin:               false,
+		CanLogin:              true,
+		CanConnect:            true,
+		CanWatch:              true,
+		CanHost:               false,
+		CanShareMedia:         false,
+		CanAccessClipboard:    false,
+		SendsInactiveCursor:   true,
+		CanSeeInactiveCursors: false,
+	}
+
+	// override default profile
+	if err := viper.UnmarshalKey("member.oidc.default_profile", &s.OIDC.DefaultProfile, viper.DecodeHook(
+		utils.JsonStringAutoDecode(s.OIDC.DefaultProfile),
+	)); err != nil {
+		log.Warn().Err(err).Msgf("unable to parse member oidc default profile")
+	}
+
+	if err := viper.UnmarshalKey("member.oidc.rules", &s.OIDC.Rules, viper.DecodeHook(
+		utils.JsonStringAutoDecode(s.OIDC.Rules),
+	)); err != nil {
+		log.Warn().Err(err).Msgf("unable to parse member oidc rules")
+	}
+
+	// ldap provider
+	s.LDAP.URL = viper.GetString("member.ldap.url")
+	s.LDAP.StartTLS = viper.GetBool("member.ldap.starttls")
+	s.LDAP.BindDN = viper.GetString("member.ldap.bind_dn")
+	s.LDAP.BindPassword = viper.GetString("member.ldap.bind_password")
+	s.LDAP.BaseDN = viper.GetString("member.ldap.base_dn")
+	s.LDAP.UserFilter = viper.GetString("member.ldap.user_filter")
+	s.LDAP.LoginAttribute = viper.GetString("member.ldap.login_attribute")
+	s.LDAP.IDAttribute = viper.GetString("member.ldap.id_attribute")
+	s.LDAP.NameAttribute = viper.GetString("member.ldap.name_attribute")
+	s.LDAP.GroupAttribute = viper.GetString("member.ldap.group_attribute")
+
+	// default profile, members can only watch unless groups say otherwise
+	s.LDAP.DefaultProfile = types.MemberProfile{
+		IsAdmin:               false,
+		CanLogin:              true,
+		CanConnect:            true,
+		CanWatch:              true,
+		CanHost:               false,
+		CanShareMedia:         false,
+		CanAccessClipboard:    false,
+		SendsInactiveCursor:   true,
+		CanSeeInactiveCursors: false,
+	}
+
+	// override default profile
+	if err := viper.UnmarshalKey("member.ldap.default_profile", &s.LDAP.DefaultProfile, viper.DecodeHook(
+		utils.JsonStringAutoDecode(s.LDAP.DefaultProfile),
+	)); err != nil {
+		log.Warn().Err(err).Msgf("unable to parse member ldap default profile")
+	}
+
+	if err := viper.UnmarshalKey("member.ldap.groups", &s.LDAP.Groups, viper.DecodeHook(
+		utils.JsonStringAutoDecode(s.LDAP.Groups),
+	)); err != nil {
+		log.Warn().Err(err).Msgf("unable to parse member ldap groups")
+	}
 }
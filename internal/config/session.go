@@ -1,12 +1,20 @@
 package config
 
 import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
 	"time"
 
+	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
+const defaultReaperInterval = time.Minute
+
 type Session struct {
 	File string
 
@@ -17,10 +25,18 @@ type Session struct {
 	MercifulReconnect bool
 	APIToken          string
 
+	Lifetime       time.Duration
+	IdleTimeout    time.Duration
+	ReaperInterval time.Duration
+
 	CookieEnabled    bool
 	CookieName       string
 	CookieExpiration time.Duration
 	CookieSecure     bool
+
+	JWTSecret    string
+	JWTPublicKey *rsa.PublicKey
+	JWTIssuer    string
 }
 
 func (Session) Init(cmd *cobra.Command) error {
@@ -59,6 +75,21 @@ func (Session) Init(cmd *cobra.Command) error {
 		return err
 	}
 
+	cmd.PersistentFlags().Duration("session.lifetime", 0, "absolute session lifetime after which it is deleted, zero means unlimited")
+	if err := viper.BindPFlag("session.lifetime", cmd.PersistentFlags().Lookup("session.lifetime")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Duration("session.idle_timeout", 0, "delete session when it is not connected for this long, zero means unlimited")
+	if err := viper.BindPFlag("session.idle_timeout", cmd.PersistentFlags().Lookup("session.idle_timeout")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Duration("session.reaper_interval", defaultReaperInterval, "how often to check for expired sessions")
+	if err := viper.BindPFlag("session.reaper_interval", cmd.PersistentFlags().Lookup("session.reaper_interval")); err != nil {
+		return err
+	}
+
 	// cookie
 	cmd.PersistentFlags().Bool("session.cookie.enabled", true, "whether cookies authentication should be enabled")
 	if err := viper.BindPFlag("session.cookie.enabled", cmd.PersistentFlags().Lookup("session.cookie.enabled")); err != nil {
@@ -80,6 +111,22 @@ func (Session) Init(cmd *cobra.Command) error {
 		return err
 	}
 
+	// jwt
+	cmd.PersistentFlags().String("session.jwt.secret", "", "HMAC secret for verifying signed JWT login tokens (HS256, HS384, HS512)")
+	if err := viper.BindPFlag("session.jwt.secret", cmd.PersistentFlags().Lookup("session.jwt.secret")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("session.jwt.public_key", "", "path to PEM encoded RSA public key for verifying signed JWT login tokens (RS256, RS384, RS512)")
+	if err := viper.BindPFlag("session.jwt.public_key", cmd.PersistentFlags().Lookup("session.jwt.public_key")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("session.jwt.issuer", "", "if set, JWT login tokens must contain this issuer")
+	if err := viper.BindPFlag("session.jwt.issuer", cmd.PersistentFlags().Lookup("session.jwt.issuer")); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -93,8 +140,56 @@ func (s *Session) Set() {
 	s.MercifulReconnect = viper.GetBool("session.merciful_reconnect")
 	s.APIToken = viper.GetString("session.api_token")
 
+	s.Lifetime = viper.GetDuration("session.lifetime")
+	s.IdleTimeout = viper.GetDuration("session.idle_timeout")
+	s.ReaperInterval = viper.GetDuration("session.reaper_interval")
+	if s.ReaperInterval <= 0 {
+		log.Warn().Dur("reaper_interval", s.ReaperInterval).Msgf("reaper interval must be positive, using %s", defaultReaperInterval)
+		s.ReaperInterval = defaultReaperInterval
+	}
+
 	s.CookieEnabled = viper.GetBool("session.cookie.enabled")
 	s.CookieName = viper.GetString("session.cookie.name")
 	s.CookieExpiration = time.Duration(viper.GetInt("session.cookie.expiration")) * time.Hour
 	s.CookieSecure = viper.GetBool("session.cookie.secure")
+
+	s.JWTSecret = viper.GetString("session.jwt.secret")
+	s.JWTIssuer = viper.GetString("session.jwt.issuer")
+
+	if path := viper.GetString("session.jwt.public_key"); path != "" {
+		key, err := loadRSAPublicKey(path)
+		if err != nil {
+			log.Panic().Err(err).Str("path", path).Msg("unable to load JWT public key")
+		}
+		s.JWTPublicKey = key
+	}
+}
+
+func loadRSAPublicKey(path string) (*rsa.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	// accept both PKIX and PKCS#1 encoded keys
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	key, ok := pub.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("public key is not RSA")
+	}
+
+	return key, nil
 }
@@ -1,6 +1,9 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+	"net"
 	"strconv"
 	"strings"
 	"time"
@@ -21,6 +24,8 @@ type WebRTCEstimator struct {
 	Passive        bool
 	Debug          bool
 	InitialBitrate int
+	// name of bitrate controller deciding stream upgrades and downgrades
+	Controller string
 
 	// how often to read and process bandwidth estimation reports
 	ReadInterval time.Duration
@@ -38,20 +43,82 @@ type WebRTCEstimator struct {
 	DiffThreshold float64
 }
 
+type WebRTCRateLimit struct {
+	Enabled bool
+
+	// allowed events per second and maximum burst size
+	MoveRate    float64
+	MoveBurst   int
+	KeyRate     float64
+	KeyBurst    int
+	ButtonRate  float64
+	ButtonBurst int
+	ScrollRate  float64
+	ScrollBurst int
+
+	// how many excess events within the window lead to disconnect, 0 to never disconnect
+	MaxViolations   int
+	ViolationWindow time.Duration
+}
+
+type WebRTCRecovery struct {
+	Enabled bool
+
+	// how long to wait for connection to recover by itself before ICE restart
+	GracePeriod time.Duration
+	// how long to wait for connection after each ICE restart offer
+	AttemptTimeout time.Duration
+	// how many ICE restarts to try before the peer is destroyed
+	MaxAttempts int
+}
+
+type WebRTCTURNServer struct {
+	Enabled bool
+	Port    int
+	// public IP address used for relayed candidates
+	RelayIP string
+	// host advertised to clients, current relay IP if empty
+	Host  string
+	Realm string
+	// loopback, private and link-local peers are rejected, unless they are in these networks
+	AllowedPeers []*net.IPNet
+}
+
 type WebRTC struct {
 	ICELite            bool
 	ICETrickle         bool
 	ICEServersFrontend []types.ICEServer
 	ICEServersBackend  []types.ICEServer
+	// shared secret for ephemeral TURN credentials (TURN REST API)
+	TURNSecret         string
+	TURNCredentialsTTL time.Duration
 	EphemeralMin       uint16
 	EphemeralMax       uint16
 	TCPMux             int
 	UDPMux             int
+	// demultiplex ICE-TCP on the HTTP server port
+	SinglePort bool
+
+	NAT1To1IPs []string
+	// whether NAT1To1IPs were retrieved, not provided by the user
+	NAT1To1Retrieved bool
+
+	IpRetrievalUrl      string
+	IpRetrievalStun     string
+	IpRetrievalCommand  string
+	IpRetrievalInterval time.Duration
 
-	NAT1To1IPs     []string
-	IpRetrievalUrl string
+	// how often to send data channel pings to measure latency
+	PingInterval time.Duration
+
+	// use lightweight watch-only peers for sessions that cannot host
+	WatchOnly bool
+
+	TURNServer WebRTCTURNServer
 
 	Estimator WebRTCEstimator
+	RateLimit WebRTCRateLimit
+	Recovery  WebRTCRecovery
 }
 
 func (WebRTC) Init(cmd *cobra.Command) error {
@@ -81,6 +148,16 @@ func (WebRTC) Init(cmd *cobra.Command) error {
 		return err
 	}
 
+	cmd.PersistentFlags().String("webrtc.iceservers.turn_secret", "", "shared secret for generating ephemeral credentials for frontend TURN servers (coturn use-auth-secret)")
+	if err := viper.BindPFlag("webrtc.iceservers.turn_secret", cmd.PersistentFlags().Lookup("webrtc.iceservers.turn_secret")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Duration("webrtc.iceservers.turn_ttl", 24*time.Hour, "validity of ephemeral TURN credentials")
+	if err := viper.BindPFlag("webrtc.iceservers.turn_ttl", cmd.PersistentFlags().Lookup("webrtc.iceservers.turn_ttl")); err != nil {
+		return err
+	}
+
 	cmd.PersistentFlags().String("webrtc.epr", "", "limits the pool of ephemeral ports that ICE UDP connections can allocate from")
 	if err := viper.BindPFlag("webrtc.epr", cmd.PersistentFlags().Lookup("webrtc.epr")); err != nil {
 		return err
@@ -96,6 +173,11 @@ func (WebRTC) Init(cmd *cobra.Command) error {
 		return err
 	}
 
+	cmd.PersistentFlags().Bool("webrtc.singleport", false, "accept ICE-TCP connections on the HTTP server port, so that no other port is needed, replaces TCP mux and EPR")
+	if err := viper.BindPFlag("webrtc.singleport", cmd.PersistentFlags().Lookup("webrtc.singleport")); err != nil {
+		return err
+	}
+
 	cmd.PersistentFlags().StringSlice("webrtc.nat1to1", []string{}, "sets a list of external IP addresses of 1:1 (D)NAT and a candidate type for which the external IP address is used")
 	if err := viper.BindPFlag("webrtc.nat1to1", cmd.PersistentFlags().Lookup("webrtc.nat1to1")); err != nil {
 		return err
@@ -106,6 +188,61 @@ func (WebRTC) Init(cmd *cobra.Command) error {
 		return err
 	}
 
+	cmd.PersistentFlags().Bool("webrtc.turn.enabled", false, "enables embedded TURN server, that is automatically advertised to clients")
+	if err := viper.BindPFlag("webrtc.turn.enabled", cmd.PersistentFlags().Lookup("webrtc.turn.enabled")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Int("webrtc.turn.port", 3478, "UDP and TCP port for embedded TURN server")
+	if err := viper.BindPFlag("webrtc.turn.port", cmd.PersistentFlags().Lookup("webrtc.turn.port")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("webrtc.turn.relay_ip", "", "public IP address for relayed candidates of embedded TURN server, first NAT1To1 IP if empty")
+	if err := viper.BindPFlag("webrtc.turn.relay_ip", cmd.PersistentFlags().Lookup("webrtc.turn.relay_ip")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("webrtc.turn.host", "", "host of embedded TURN server advertised to clients, relay IP if empty")
+	if err := viper.BindPFlag("webrtc.turn.host", cmd.PersistentFlags().Lookup("webrtc.turn.host")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("webrtc.turn.realm", "neko", "realm of embedded TURN server")
+	if err := viper.BindPFlag("webrtc.turn.realm", cmd.PersistentFlags().Lookup("webrtc.turn.realm")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().StringSlice("webrtc.turn.allowed_peers", []string{}, "CIDR networks with loopback, private or link-local addresses, that embedded TURN server can relay to")
+	if err := viper.BindPFlag("webrtc.turn.allowed_peers", cmd.PersistentFlags().Lookup("webrtc.turn.allowed_peers")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Duration("webrtc.ping_interval", 0, "how often to send data channel pings to measure latency, clients must support server ping, 0 to disable")
+	if err := viper.BindPFlag("webrtc.ping_interval", cmd.PersistentFlags().Lookup("webrtc.ping_interval")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Bool("webrtc.watch_only", false, "use lightweight watch-only peers without data channel for sessions that can watch but cannot host")
+	if err := viper.BindPFlag("webrtc.watch_only", cmd.PersistentFlags().Lookup("webrtc.watch_only")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("webrtc.ip_retrieval_stun", "", "STUN server address (host:port) used for retrieval of the external IP address")
+	if err := viper.BindPFlag("webrtc.ip_retrieval_stun", cmd.PersistentFlags().Lookup("webrtc.ip_retrieval_stun")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("webrtc.ip_retrieval_command", "", "local shell command printing the external IP address")
+	if err := viper.BindPFlag("webrtc.ip_retrieval_command", cmd.PersistentFlags().Lookup("webrtc.ip_retrieval_command")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Duration("webrtc.ip_retrieval_interval", 0, "how often to refresh the external IP address, 0 to retrieve only at startup")
+	if err := viper.BindPFlag("webrtc.ip_retrieval_interval", cmd.PersistentFlags().Lookup("webrtc.ip_retrieval_interval")); err != nil {
+		return err
+	}
+
 	// bandwidth estimator
 
 	cmd.PersistentFlags().Bool("webrtc.estimator.enabled", false, "enables the bandwidth estimator")
@@ -128,6 +265,11 @@ func (WebRTC) Init(cmd *cobra.Command) error {
 		return err
 	}
 
+	cmd.PersistentFlags().String("webrtc.estimator.controller", "default", "bitrate controller used to select video stream, can be provided by plugins")
+	if err := viper.BindPFlag("webrtc.estimator.controller", cmd.PersistentFlags().Lookup("webrtc.estimator.controller")); err != nil {
+		return err
+	}
+
 	cmd.PersistentFlags().Duration("webrtc.estimator.read_interval", 2*time.Second, "how often to read and process bandwidth estimation reports")
 	if err := viper.BindPFlag("webrtc.estimator.read_interval", cmd.PersistentFlags().Lookup("webrtc.estimator.read_interval")); err != nil {
 		return err
@@ -163,6 +305,85 @@ func (WebRTC) Init(cmd *cobra.Command) error {
 		return err
 	}
 
+	// input rate limit
+
+	cmd.PersistentFlags().Bool("webrtc.ratelimit.enabled", false, "enables rate limiting of input events received over data channel")
+	if err := viper.BindPFlag("webrtc.ratelimit.enabled", cmd.PersistentFlags().Lookup("webrtc.ratelimit.enabled")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Float64("webrtc.ratelimit.move_rate", 250, "allowed cursor move events per second, excess moves are coalesced")
+	if err := viper.BindPFlag("webrtc.ratelimit.move_rate", cmd.PersistentFlags().Lookup("webrtc.ratelimit.move_rate")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Int("webrtc.ratelimit.move_burst", 50, "maximum burst of cursor move events")
+	if err := viper.BindPFlag("webrtc.ratelimit.move_burst", cmd.PersistentFlags().Lookup("webrtc.ratelimit.move_burst")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Float64("webrtc.ratelimit.key_rate", 50, "allowed key down events per second, excess events are dropped")
+	if err := viper.BindPFlag("webrtc.ratelimit.key_rate", cmd.PersistentFlags().Lookup("webrtc.ratelimit.key_rate")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Int("webrtc.ratelimit.key_burst", 20, "maximum burst of key down events")
+	if err := viper.BindPFlag("webrtc.ratelimit.key_burst", cmd.PersistentFlags().Lookup("webrtc.ratelimit.key_burst")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Float64("webrtc.ratelimit.button_rate", 20, "allowed button down events per second, excess events are dropped")
+	if err := viper.BindPFlag("webrtc.ratelimit.button_rate", cmd.PersistentFlags().Lookup("webrtc.ratelimit.button_rate")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Int("webrtc.ratelimit.button_burst", 10, "maximum burst of button down events")
+	if err := viper.BindPFlag("webrtc.ratelimit.button_burst", cmd.PersistentFlags().Lookup("webrtc.ratelimit.button_burst")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Float64("webrtc.ratelimit.scroll_rate", 60, "allowed scroll events per second, excess events are dropped")
+	if err := viper.BindPFlag("webrtc.ratelimit.scroll_rate", cmd.PersistentFlags().Lookup("webrtc.ratelimit.scroll_rate")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Int("webrtc.ratelimit.scroll_burst", 20, "maximum burst of scroll events")
+	if err := viper.BindPFlag("webrtc.ratelimit.scroll_burst", cmd.PersistentFlags().Lookup("webrtc.ratelimit.scroll_burst")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Int("webrtc.ratelimit.max_violations", 1000, "how many dropped events within violation window lead to disconnect, coalesced moves are not counted, 0 to never disconnect")
+	if err := viper.BindPFlag("webrtc.ratelimit.max_violations", cmd.PersistentFlags().Lookup("webrtc.ratelimit.max_violations")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Duration("webrtc.ratelimit.violation_window", 10*time.Second, "time window in which excess events are counted")
+	if err := viper.BindPFlag("webrtc.ratelimit.violation_window", cmd.PersistentFlags().Lookup("webrtc.ratelimit.violation_window")); err != nil {
+		return err
+	}
+
+	// connection recovery
+
+	cmd.PersistentFlags().Bool("webrtc.recovery.enabled", false, "enables server initiated ICE restart when peer connection is disconnected or failed")
+	if err := viper.BindPFlag("webrtc.recovery.enabled", cmd.PersistentFlags().Lookup("webrtc.recovery.enabled")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Duration("webrtc.recovery.grace_period", 2*time.Second, "how long to wait for connection to recover by itself before ICE restart")
+	if err := viper.BindPFlag("webrtc.recovery.grace_period", cmd.PersistentFlags().Lookup("webrtc.recovery.grace_period")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Duration("webrtc.recovery.attempt_timeout", 10*time.Second, "how long to wait for connection after each ICE restart offer")
+	if err := viper.BindPFlag("webrtc.recovery.attempt_timeout", cmd.PersistentFlags().Lookup("webrtc.recovery.attempt_timeout")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Int("webrtc.recovery.max_attempts", 3, "how many ICE restarts to try before the peer is destroyed")
+	if err := viper.BindPFlag("webrtc.recovery.max_attempts", cmd.PersistentFlags().Lookup("webrtc.recovery.max_attempts")); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -209,8 +430,17 @@ func (s *WebRTC) Set() {
 		s.ICEServersBackend = append(s.ICEServersBackend, iceServers...)
 	}
 
+	s.TURNSecret = viper.GetString("webrtc.iceservers.turn_secret")
+	s.TURNCredentialsTTL = viper.GetDuration("webrtc.iceservers.turn_ttl")
+
 	s.TCPMux = viper.GetInt("webrtc.tcpmux")
 	s.UDPMux = viper.GetInt("webrtc.udpmux")
+	s.SinglePort = viper.GetBool("webrtc.singleport")
+
+	if s.SinglePort && s.TCPMux != 0 {
+		log.Warn().Msgf("TCP mux is ignored in single port mode")
+		s.TCPMux = 0
+	}
 
 	epr := viper.GetString("webrtc.epr")
 	if epr != "" {
@@ -235,7 +465,7 @@ func (s *WebRTC) Set() {
 		}
 	}
 
-	if epr == "" && s.TCPMux == 0 && s.UDPMux == 0 {
+	if epr == "" && s.TCPMux == 0 && s.UDPMux == 0 && !s.SinglePort {
 		// using default epr range
 		s.EphemeralMin = 59000
 		s.EphemeralMax = 59100
@@ -248,8 +478,13 @@ func (s *WebRTC) Set() {
 
 	s.NAT1To1IPs = viper.GetStringSlice("webrtc.nat1to1")
 	s.IpRetrievalUrl = viper.GetString("webrtc.ip_retrieval_url")
-	if s.IpRetrievalUrl != "" && len(s.NAT1To1IPs) == 0 {
-		ip, err := utils.HttpRequestGET(s.IpRetrievalUrl)
+	s.IpRetrievalStun = viper.GetString("webrtc.ip_retrieval_stun")
+	s.IpRetrievalCommand = viper.GetString("webrtc.ip_retrieval_command")
+	s.IpRetrievalInterval = viper.GetDuration("webrtc.ip_retrieval_interval")
+	if len(s.NAT1To1IPs) == 0 && (s.IpRetrievalUrl != "" || s.IpRetrievalStun != "" || s.IpRetrievalCommand != "") {
+		s.NAT1To1Retrieved = true
+
+		ip, err := s.RetrieveIP()
 		if err == nil {
 			s.NAT1To1IPs = append(s.NAT1To1IPs, ip)
 		} else {
@@ -257,12 +492,41 @@ func (s *WebRTC) Set() {
 		}
 	}
 
+	s.PingInterval = viper.GetDuration("webrtc.ping_interval")
+	s.WatchOnly = viper.GetBool("webrtc.watch_only")
+
+	// embedded turn server
+
+	s.TURNServer.Enabled = viper.GetBool("webrtc.turn.enabled")
+	s.TURNServer.Port = viper.GetInt("webrtc.turn.port")
+	s.TURNServer.RelayIP = viper.GetString("webrtc.turn.relay_ip")
+	if s.TURNServer.RelayIP == "" && len(s.NAT1To1IPs) > 0 {
+		s.TURNServer.RelayIP = s.NAT1To1IPs[0]
+	}
+	s.TURNServer.Host = viper.GetString("webrtc.turn.host")
+	s.TURNServer.Realm = viper.GetString("webrtc.turn.realm")
+	s.TURNServer.AllowedPeers = []*net.IPNet{}
+	for _, cidr := range viper.GetStringSlice("webrtc.turn.allowed_peers") {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			log.Warn().Err(err).Str("cidr", cidr).Msg("invalid TURN allowed peer network, skipping")
+			continue
+		}
+		s.TURNServer.AllowedPeers = append(s.TURNServer.AllowedPeers, network)
+	}
+
+	if s.TURNServer.Enabled && s.TURNServer.RelayIP == "" {
+		log.Warn().Msgf("embedded TURN server is enabled, but no relay IP is known. TURN server will be disabled.")
+		s.TURNServer.Enabled = false
+	}
+
 	// bandwidth estimator
 
 	s.Estimator.Enabled = viper.GetBool("webrtc.estimator.enabled")
 	s.Estimator.Passive = viper.GetBool("webrtc.estimator.passive")
 	s.Estimator.Debug = viper.GetBool("webrtc.estimator.debug")
 	s.Estimator.InitialBitrate = viper.GetInt("webrtc.estimator.initial_bitrate")
+	s.Estimator.Controller = viper.GetString("webrtc.estimator.controller")
 	s.Estimator.ReadInterval = viper.GetDuration("webrtc.estimator.read_interval")
 	s.Estimator.StableDuration = viper.GetDuration("webrtc.estimator.stable_duration")
 	s.Estimator.UnstableDuration = viper.GetDuration("webrtc.estimator.unstable_duration")
@@ -270,4 +534,60 @@ func (s *WebRTC) Set() {
 	s.Estimator.DowngradeBackoff = viper.GetDuration("webrtc.estimator.downgrade_backoff")
 	s.Estimator.UpgradeBackoff = viper.GetDuration("webrtc.estimator.upgrade_backoff")
 	s.Estimator.DiffThreshold = viper.GetFloat64("webrtc.estimator.diff_threshold")
+
+	// input rate limit
+
+	s.RateLimit.Enabled = viper.GetBool("webrtc.ratelimit.enabled")
+	s.RateLimit.MoveRate = viper.GetFloat64("webrtc.ratelimit.move_rate")
+	s.RateLimit.MoveBurst = viper.GetInt("webrtc.ratelimit.move_burst")
+	s.RateLimit.KeyRate = viper.GetFloat64("webrtc.ratelimit.key_rate")
+	s.RateLimit.KeyBurst = viper.GetInt("webrtc.ratelimit.key_burst")
+	s.RateLimit.ButtonRate = viper.GetFloat64("webrtc.ratelimit.button_rate")
+	s.RateLimit.ButtonBurst = viper.GetInt("webrtc.ratelimit.button_burst")
+	s.RateLimit.ScrollRate = viper.GetFloat64("webrtc.ratelimit.scroll_rate")
+	s.RateLimit.ScrollBurst = viper.GetInt("webrtc.ratelimit.scroll_burst")
+	s.RateLimit.MaxViolations = viper.GetInt("webrtc.ratelimit.max_violations")
+	s.RateLimit.ViolationWindow = viper.GetDuration("webrtc.ratelimit.violation_window")
+
+	// connection recovery
+
+	s.Recovery.Enabled = viper.GetBool("webrtc.recovery.enabled")
+	s.Recovery.GracePeriod = viper.GetDuration("webrtc.recovery.grace_period")
+	s.Recovery.AttemptTimeout = viper.GetDuration("webrtc.recovery.attempt_timeout")
+	s.Recovery.MaxAttempts = viper.GetInt("webrtc.recovery.max_attempts")
+}
+
+// RetrieveIP returns external IP address using the first successful retrieval strategy.
+func (s *WebRTC) RetrieveIP() (string, error) {
+	var errs []error
+
+	if s.IpRetrievalCommand != "" {
+		ip, err := utils.IpRetrievalCommand(s.IpRetrievalCommand)
+		if err == nil {
+			return ip, nil
+		}
+		errs = append(errs, fmt.Errorf("command: %w", err))
+	}
+
+	if s.IpRetrievalStun != "" {
+		ip, err := utils.IpRetrievalSTUN(s.IpRetrievalStun)
+		if err == nil {
+			return ip, nil
+		}
+		errs = append(errs, fmt.Errorf("stun: %w", err))
+	}
+
+	if s.IpRetrievalUrl != "" {
+		ip, err := utils.IpRetrievalHTTP(s.IpRetrievalUrl)
+		if err == nil {
+			return ip, nil
+		}
+		errs = append(errs, fmt.Errorf("http: %w", err))
+	}
+
+	if len(errs) == 0 {
+		return "", errors.New("no IP retrieval strategy configured")
+	}
+
+	return "", errors.Join(errs...)
 }
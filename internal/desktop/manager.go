@@ -25,6 +25,8 @@ type DesktopManagerCtx struct {
 	config     *config.Desktop
 	screenSize types.ScreenSize // cached screen size
 	input      xinput.Driver
+
+	inputRecord inputRecordCtx
 }
 
 func New(config *config.Desktop) *DesktopManagerCtx {
@@ -130,6 +132,9 @@ func (manager *DesktopManagerCtx) OnAfterScreenSizeChange(listener func()) {
 func (manager *DesktopManagerCtx) Shutdown() error {
 	manager.logger.Info().Msgf("shutdown")
 
+	// stop input recording, if active
+	_ = manager.InputRecordStop()
+
 	close(manager.shutdown)
 	manager.wg.Wait()
 
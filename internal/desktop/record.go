@@ -0,0 +1,288 @@
+package desktop
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/demodesk/neko/pkg/types"
+)
+
+type inputRecordCtx struct {
+	mu sync.Mutex
+
+	// recording
+	name    string
+	file    *os.File
+	encoder *json.Encoder
+	started time.Time
+
+	// replay
+	replayName string
+	replayStop chan struct{}
+}
+
+func (manager *DesktopManagerCtx) inputRecordPath(name string) (string, error) {
+	if manager.config.InputRecordings == "" {
+		return "", types.ErrInputRecordingDisabled
+	}
+
+	if name == "" || name == "." || name == ".." || filepath.Base(name) != name {
+		return "", types.ErrInputRecordingName
+	}
+
+	return filepath.Join(manager.config.InputRecordings, name), nil
+}
+
+// ApplyInput applies user input event and appends it to the active recording, if there is one.
+// It is called by input handlers, so that automated input (e.g. API actions) is not recorded.
+// Events that failed to apply are not recorded.
+func (manager *DesktopManagerCtx) ApplyInput(event types.InputEvent) error {
+	if err := manager.applyInputEvent(event); err != nil {
+		return err
+	}
+
+	manager.recordInput(event)
+	return nil
+}
+
+func (manager *DesktopManagerCtx) recordInput(event types.InputEvent) {
+	rec := &manager.inputRecord
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	if rec.encoder == nil {
+		return
+	}
+
+	event.Time = time.Since(rec.started).Milliseconds()
+	if err := rec.encoder.Encode(event); err != nil {
+		manager.logger.Warn().Err(err).Str("name", rec.name).Msg("unable to write input event")
+	}
+}
+
+func (manager *DesktopManagerCtx) InputRecordStart(name string) error {
+	path, err := manager.inputRecordPath(name)
+	if err != nil {
+		return err
+	}
+
+	rec := &manager.inputRecord
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	if rec.file != nil {
+		return types.ErrInputRecordingActive
+	}
+
+	if rec.replayStop != nil {
+		return types.ErrInputReplayActive
+	}
+
+	if err := os.MkdirAll(manager.config.InputRecordings, 0755); err != nil {
+		return err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	rec.name = name
+	rec.file = file
+	rec.encoder = json.NewEncoder(file)
+	rec.started = time.Now()
+
+	manager.logger.Info().Str("name", name).Msg("input recording started")
+	return nil
+}
+
+func (manager *DesktopManagerCtx) InputRecordStop() error {
+	rec := &manager.inputRecord
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	if rec.file == nil {
+		return types.ErrInputRecordingInactive
+	}
+
+	err := rec.file.Close()
+	manager.logger.Info().Str("name", rec.name).Msg("input recording stopped")
+
+	rec.name = ""
+	rec.file = nil
+	rec.encoder = nil
+	return err
+}
+
+func (manager *DesktopManagerCtx) InputReplayStart(name string, speed float64) error {
+	path, err := manager.inputRecordPath(name)
+	if err != nil {
+		return err
+	}
+
+	if speed <= 0 {
+		speed = 1
+	}
+
+	rec := &manager.inputRecord
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	if rec.replayStop != nil {
+		return types.ErrInputReplayActive
+	}
+
+	if rec.file != nil {
+		return types.ErrInputRecordingActive
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	stop := make(chan struct{})
+	rec.replayName = name
+	rec.replayStop = stop
+
+	manager.logger.Info().Str("name", name).Float64("speed", speed).Msg("input replay started")
+
+	manager.wg.Add(1)
+	go func() {
+		defer manager.wg.Done()
+		defer file.Close()
+
+		err := manager.inputReplay(file, speed, stop, manager.applyInputEvent)
+		if err != nil {
+			manager.logger.Err(err).Str("name", name).Msg("input replay failed")
+		} else {
+			manager.logger.Info().Str("name", name).Msg("input replay finished")
+		}
+
+		// release everything that could have been left pressed
+		manager.ResetKeys()
+
+		rec.mu.Lock()
+		if rec.replayStop == stop {
+			rec.replayName = ""
+			rec.replayStop = nil
+		}
+		rec.mu.Unlock()
+	}()
+
+	return nil
+}
+
+func (manager *DesktopManagerCtx) inputReplay(r io.Reader, speed float64, stop chan struct{}, apply func(types.InputEvent) error) error {
+	decoder := json.NewDecoder(r)
+
+	var last int64
+	for {
+		var event types.InputEvent
+		if err := decoder.Decode(&event); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		delay := time.Duration(float64(event.Time-last) * float64(time.Millisecond) / speed)
+		last = event.Time
+
+		if delay > 0 {
+			timer := time.NewTimer(delay)
+			select {
+			case <-stop:
+				timer.Stop()
+				return nil
+			case <-manager.shutdown:
+				timer.Stop()
+				return nil
+			case <-timer.C:
+			}
+		}
+
+		if err := apply(event); err != nil {
+			manager.logger.Warn().Err(err).Str("type", event.Type).Msg("unable to replay input event")
+		}
+	}
+}
+
+func (manager *DesktopManagerCtx) applyInputEvent(event types.InputEvent) error {
+	switch event.Type {
+	case "move":
+		manager.Move(event.X, event.Y)
+	case "scroll":
+		manager.Scroll(event.X, event.Y, event.ControlKey)
+	case "button_down":
+		return manager.ButtonDown(event.Code)
+	case "button_up":
+		return manager.ButtonUp(event.Code)
+	case "button_press":
+		return manager.ButtonPress(event.Code)
+	case "key_down":
+		return manager.KeyDown(event.Code)
+	case "key_up":
+		return manager.KeyUp(event.Code)
+	case "key_press":
+		return manager.KeyPress(event.Codes...)
+	case "text":
+		return manager.TextInput(event.Text)
+	case "touch_begin":
+		return manager.TouchBegin(event.TouchId, event.X, event.Y, event.Pressure)
+	case "touch_update":
+		return manager.TouchUpdate(event.TouchId, event.X, event.Y, event.Pressure)
+	case "touch_end":
+		return manager.TouchEnd(event.TouchId, event.X, event.Y, event.Pressure)
+	default:
+		return fmt.Errorf("unknown input event type %q", event.Type)
+	}
+
+	return nil
+}
+
+func (manager *DesktopManagerCtx) InputReplayStop() error {
+	rec := &manager.inputRecord
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	if rec.replayStop == nil {
+		return types.ErrInputReplayInactive
+	}
+
+	close(rec.replayStop)
+	rec.replayName = ""
+	rec.replayStop = nil
+	return nil
+}
+
+func (manager *DesktopManagerCtx) InputRecordStatus() types.InputRecordStatus {
+	rec := &manager.inputRecord
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	status := types.InputRecordStatus{
+		Recording: rec.file != nil,
+		Replaying: rec.replayStop != nil,
+	}
+
+	if status.Recording {
+		status.Name = rec.name
+	} else if status.Replaying {
+		status.Name = rec.replayName
+	}
+
+	return status
+}
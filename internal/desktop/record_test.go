@@ -0,0 +1,118 @@
+package desktop
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/demodesk/neko/internal/config"
+	"github.com/demodesk/neko/pkg/types"
+)
+
+func TestDesktopManagerCtx_recordInput(t *testing.T) {
+	dir := t.TempDir()
+	manager := New(&config.Desktop{InputRecordings: dir})
+
+	// nothing is recorded without active recording
+	manager.recordInput(types.InputEvent{Type: "move", X: 1, Y: 1})
+
+	if err := manager.InputRecordStart("../escape"); !errors.Is(err, types.ErrInputRecordingName) {
+		t.Errorf("manager.InputRecordStart() error = %v, want %v", err, types.ErrInputRecordingName)
+	}
+
+	if err := manager.InputRecordStart("test"); err != nil {
+		t.Fatalf("manager.InputRecordStart() returned error: %s", err)
+	}
+
+	if status := manager.InputRecordStatus(); !status.Recording || status.Name != "test" {
+		t.Errorf("manager.InputRecordStatus() = %+v", status)
+	}
+
+	manager.recordInput(types.InputEvent{Type: "move", X: 10, Y: 20})
+	manager.recordInput(types.InputEvent{Type: "key_down", Code: 65})
+	manager.recordInput(types.InputEvent{Type: "text", Text: "ahoj"})
+
+	if err := manager.InputRecordStop(); err != nil {
+		t.Fatalf("manager.InputRecordStop() returned error: %s", err)
+	}
+
+	if err := manager.InputRecordStop(); !errors.Is(err, types.ErrInputRecordingInactive) {
+		t.Errorf("manager.InputRecordStop() error = %v, want %v", err, types.ErrInputRecordingInactive)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "test"))
+	if err != nil {
+		t.Fatalf("os.ReadFile() returned error: %s", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	want := []types.InputEvent{
+		{Type: "move", X: 10, Y: 20},
+		{Type: "key_down", Code: 65},
+		{Type: "text", Text: "ahoj"},
+	}
+
+	if len(lines) != len(want) {
+		t.Fatalf("recording has %d events, want %d", len(lines), len(want))
+	}
+
+	for i, line := range lines {
+		var event types.InputEvent
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("json.Unmarshal() returned error: %s", err)
+		}
+
+		event.Time = 0
+		if event.Type != want[i].Type || event.X != want[i].X || event.Y != want[i].Y || event.Code != want[i].Code || event.Text != want[i].Text {
+			t.Errorf("event %d = %+v, want %+v", i, event, want[i])
+		}
+	}
+}
+
+func TestDesktopManagerCtx_inputReplay(t *testing.T) {
+	manager := New(&config.Desktop{})
+
+	recording := `{"t":0,"type":"move","x":1,"y":2}
+{"t":40,"type":"key_down","code":65}
+{"t":80,"type":"key_up","code":65}
+`
+
+	var replayed []string
+	apply := func(event types.InputEvent) error {
+		replayed = append(replayed, event.Type)
+		return nil
+	}
+
+	// double speed halves delays between events
+	start := time.Now()
+	err := manager.inputReplay(strings.NewReader(recording), 2, make(chan struct{}), apply)
+	if err != nil {
+		t.Fatalf("manager.inputReplay() returned error: %s", err)
+	}
+
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond || elapsed > time.Second {
+		t.Errorf("manager.inputReplay() took %s, want about 40ms", elapsed)
+	}
+
+	if strings.Join(replayed, ",") != "move,key_down,key_up" {
+		t.Errorf("replayed events = %v", replayed)
+	}
+
+	// stopped replay does not apply remaining events
+	replayed = nil
+	stop := make(chan struct{})
+	close(stop)
+
+	err = manager.inputReplay(strings.NewReader(recording), 1, stop, apply)
+	if err != nil {
+		t.Fatalf("manager.inputReplay() returned error: %s", err)
+	}
+
+	if strings.Join(replayed, ",") != "move" {
+		t.Errorf("replayed events after stop = %v", replayed)
+	}
+}
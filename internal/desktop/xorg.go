@@ -5,6 +5,7 @@ import (
 	"os/exec"
 	"regexp"
 	"time"
+	"unicode/utf8"
 
 	"github.com/demodesk/neko/pkg/types"
 	"github.com/demodesk/neko/pkg/xorg"
@@ -62,6 +63,20 @@ func (manager *DesktopManagerCtx) KeyPress(codes ...uint32) error {
 	return nil
 }
 
+func (manager *DesktopManagerCtx) TextInput(text string) error {
+	if utf8.RuneCountInString(text) > types.TextInputMaxLength {
+		return types.ErrTextInputTooLong
+	}
+
+	for _, r := range text {
+		if err := xorg.KeyType(xorg.KeysymFromRune(r)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (manager *DesktopManagerCtx) ResetKeys() {
 	xorg.ResetKeys()
 }
@@ -0,0 +1,207 @@
+package http
+
+import (
+	"bufio"
+	"encoding/binary"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// how long to wait for first bytes of a new connection
+	demuxSniffTimeout = 10 * time.Second
+	// RFC 4571 length prefix followed by STUN header up to the magic cookie
+	demuxSniffLength = 10
+	// STUN magic cookie, RFC 5389
+	stunMagicCookie = 0x2112A442
+)
+
+// Demux accepts connections on a single TCP port and splits them between
+// HTTP server and ICE-TCP (RFC 4571 framed STUN) by sniffing the first bytes.
+type Demux struct {
+	logger   zerolog.Logger
+	listener net.Listener
+
+	http *demuxListener
+	ice  *demuxListener
+
+	mu     sync.Mutex
+	closed int
+}
+
+func NewDemux(addr string) (*Demux, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	demux := &Demux{
+		logger: log.With().
+			Str("module", "http").
+			Str("submodule", "demux").
+			Logger(),
+		listener: listener,
+	}
+
+	demux.http = newDemuxListener(demux)
+	demux.ice = newDemuxListener(demux)
+
+	go demux.accept()
+	return demux, nil
+}
+
+// HTTP returns listener with all connections, that are not ICE-TCP.
+func (d *Demux) HTTP() net.Listener {
+	return d.http
+}
+
+// ICE returns listener with ICE-TCP connections.
+func (d *Demux) ICE() net.Listener {
+	return d.ice
+}
+
+func (d *Demux) accept() {
+	d.logger.Info().Msgf("demultiplexing ICE-TCP on %s", d.listener.Addr())
+
+	for {
+		conn, err := d.listener.Accept()
+		if err != nil {
+			d.http.shutdown(err)
+			d.ice.shutdown(err)
+			return
+		}
+
+		go d.handle(conn)
+	}
+}
+
+func (d *Demux) handle(conn net.Conn) {
+	reader := bufio.NewReader(conn)
+
+	// both HTTP and ICE-TCP clients send data first
+	_ = conn.SetReadDeadline(time.Now().Add(demuxSniffTimeout))
+	head, err := reader.Peek(demuxSniffLength)
+	_ = conn.SetReadDeadline(time.Time{})
+
+	if err != nil {
+		d.logger.Debug().Err(err).Str("remote", conn.RemoteAddr().String()).Msg("unable to sniff connection")
+		conn.Close()
+		return
+	}
+
+	peeked := &peekedConn{
+		Conn:   conn,
+		reader: reader,
+	}
+
+	if isICETCP(head) {
+		d.ice.deliver(peeked)
+	} else {
+		d.http.deliver(peeked)
+	}
+}
+
+// release closes underlying listener once both child listeners are closed.
+func (d *Demux) release() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.closed++
+	if d.closed == 2 {
+		d.listener.Close()
+	}
+}
+
+// isICETCP checks for RFC 4571 length prefix followed by STUN header. HTTP starts with
+// a method name and TLS with a record type byte, neither has STUN magic cookie at this offset.
+func isICETCP(head []byte) bool {
+	if len(head) < demuxSniffLength {
+		return false
+	}
+
+	// two most significant bits of STUN message type are zeros
+	if head[2]&0xC0 != 0 {
+		return false
+	}
+
+	// framed packet must fit at least STUN header
+	if binary.BigEndian.Uint16(head[0:2]) < 20 {
+		return false
+	}
+
+	return binary.BigEndian.Uint32(head[6:10]) == stunMagicCookie
+}
+
+// peekedConn reads sniffed bytes before the rest of the connection.
+type peekedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *peekedConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
+
+type demuxListener struct {
+	demux *Demux
+	conns chan net.Conn
+
+	once sync.Once
+	done chan struct{}
+	err  error
+}
+
+func newDemuxListener(demux *Demux) *demuxListener {
+	return &demuxListener{
+		demux: demux,
+		conns: make(chan net.Conn),
+		done:  make(chan struct{}),
+		err:   net.ErrClosed,
+	}
+}
+
+func (l *demuxListener) deliver(conn net.Conn) {
+	select {
+	case l.conns <- conn:
+	case <-l.done:
+		conn.Close()
+	}
+}
+
+func (l *demuxListener) shutdown(err error) {
+	l.once.Do(func() {
+		l.err = err
+		close(l.done)
+	})
+}
+
+func (l *demuxListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.done:
+		return nil, l.err
+	}
+}
+
+func (l *demuxListener) Close() error {
+	closed := false
+	l.once.Do(func() {
+		closed = true
+		close(l.done)
+	})
+
+	if closed {
+		l.demux.release()
+	}
+
+	return nil
+}
+
+func (l *demuxListener) Addr() net.Addr {
+	return l.demux.listener.Addr()
+}
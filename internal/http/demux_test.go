@@ -0,0 +1,108 @@
+package http
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// stunBindingRequest returns RFC 4571 framed STUN binding request.
+func stunBindingRequest() []byte {
+	msg := make([]byte, 2+20)
+	binary.BigEndian.PutUint16(msg[0:2], 20)
+	binary.BigEndian.PutUint16(msg[2:4], 0x0001)
+	binary.BigEndian.PutUint16(msg[4:6], 0)
+	binary.BigEndian.PutUint32(msg[6:10], stunMagicCookie)
+	copy(msg[10:], "transaction1")
+	return msg
+}
+
+func TestIsICETCP(t *testing.T) {
+	tests := []struct {
+		name string
+		head []byte
+		want bool
+	}{
+		{"stun binding request", stunBindingRequest(), true},
+		{"http get", []byte("GET / HTTP/1.1\r\n"), false},
+		{"http post", []byte("POST /api/login HTTP/1.1\r\n"), false},
+		{"tls client hello", []byte{0x16, 0x03, 0x01, 0x02, 0x00, 0x01, 0x00, 0x01, 0xfc, 0x03, 0x03}, false},
+		{"stun without magic cookie", append(stunBindingRequest()[:6], 0, 0, 0, 0), false},
+		{"framed packet shorter than stun header", func() []byte {
+			msg := stunBindingRequest()
+			binary.BigEndian.PutUint16(msg[0:2], 19)
+			return msg
+		}(), false},
+		{"rtp or dtls instead of stun", func() []byte {
+			msg := stunBindingRequest()
+			msg[2] = 0x80
+			return msg
+		}(), false},
+		{"partial header", stunBindingRequest()[:6], false},
+		{"empty", []byte{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isICETCP(tt.head); got != tt.want {
+				t.Errorf("isICETCP() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDemux(t *testing.T) {
+	demux, err := NewDemux("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("NewDemux() returned error: %s", err)
+	}
+	defer demux.HTTP().Close()
+	defer demux.ICE().Close()
+
+	addr := demux.listener.Addr().String()
+
+	// send data in chunks, so that sniffing must wait for more bytes
+	dial := func(chunks ...[]byte) {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Errorf("net.Dial() returned error: %s", err)
+			return
+		}
+		t.Cleanup(func() { conn.Close() })
+
+		for _, chunk := range chunks {
+			if _, err := conn.Write(chunk); err != nil {
+				t.Errorf("conn.Write() returned error: %s", err)
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	accept := func(listener net.Listener, want []byte) {
+		conn, err := listener.Accept()
+		if err != nil {
+			t.Fatalf("listener.Accept() returned error: %s", err)
+		}
+		defer conn.Close()
+
+		// sniffed bytes must not be lost
+		got := make([]byte, len(want))
+		if _, err := io.ReadFull(conn, got); err != nil {
+			t.Fatalf("io.ReadFull() returned error: %s", err)
+		}
+		if string(got) != string(want) {
+			t.Errorf("read %q, want %q", got, want)
+		}
+	}
+
+	stun := stunBindingRequest()
+	go dial(stun[:3], stun[3:8], stun[8:])
+	accept(demux.ICE(), stun)
+
+	request := []byte("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n")
+	go dial(request[:4], request[4:])
+	accept(demux.HTTP(), request)
+}
@@ -2,6 +2,7 @@ package http
 
 import (
 	"context"
+	"net"
 	"net/http"
 	"os"
 
@@ -18,6 +19,8 @@ type HttpManagerCtx struct {
 	config *config.Server
 	router types.Router
 	http   *http.Server
+	// optional listener, replaces listening on bind address
+	listener net.Listener
 }
 
 func New(WebSocketManager types.WebSocketManager, ApiManager types.ApiManager, config *config.Server) *HttpManagerCtx {
@@ -107,17 +110,39 @@ func New(WebSocketManager types.WebSocketManager, ApiManager types.ApiManager, c
 	}
 }
 
+// SetListener makes server accept connections from given listener instead
+// of bind address, it must be called before Start.
+func (manager *HttpManagerCtx) SetListener(listener net.Listener) {
+	manager.listener = listener
+}
+
+func (manager *HttpManagerCtx) listenAndServe() error {
+	if manager.listener != nil {
+		return manager.http.Serve(manager.listener)
+	}
+
+	return manager.http.ListenAndServe()
+}
+
+func (manager *HttpManagerCtx) listenAndServeTLS(certFile, keyFile string) error {
+	if manager.listener != nil {
+		return manager.http.ServeTLS(manager.listener, certFile, keyFile)
+	}
+
+	return manager.http.ListenAndServeTLS(certFile, keyFile)
+}
+
 func (manager *HttpManagerCtx) Start() {
 	if manager.config.Cert != "" && manager.config.Key != "" {
 		go func() {
-			if err := manager.http.ListenAndServeTLS(manager.config.Cert, manager.config.Key); err != http.ErrServerClosed {
+			if err := manager.listenAndServeTLS(manager.config.Cert, manager.config.Key); err != http.ErrServerClosed {
 				manager.logger.Panic().Err(err).Msg("unable to start https server")
 			}
 		}()
 		manager.logger.Info().Msgf("https listening on %s", manager.http.Addr)
 	} else {
 		go func() {
-			if err := manager.http.ListenAndServe(); err != http.ErrServerClosed {
+			if err := manager.listenAndServe(); err != http.ErrServerClosed {
 				manager.logger.Panic().Err(err).Msg("unable to start http server")
 			}
 		}()
@@ -1,35 +1,36 @@
 package file
 
 import (
-	"crypto/sha256"
-	"encoding/base64"
 	"encoding/json"
 	"io"
 	"os"
 
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
 	"github.com/demodesk/neko/pkg/types"
+	"github.com/demodesk/neko/pkg/utils"
 )
 
 func New(config Config) types.MemberProvider {
 	return &MemberProviderCtx{
+		logger: log.With().Str("module", "member").Str("submodule", "file").Logger(),
 		config: config,
 	}
 }
 
 type MemberProviderCtx struct {
+	logger zerolog.Logger
 	config Config
 }
 
-func (provider *MemberProviderCtx) hash(password string) string {
+func (provider *MemberProviderCtx) hash(password string) (string, error) {
 	// if hash is disabled, return password as plain text
 	if !provider.config.Hash {
-		return password
+		return password, nil
 	}
 
-	sha256 := sha256.New()
-	sha256.Write([]byte(password))
-	hashedPassword := sha256.Sum(nil)
-	return base64.StdEncoding.EncodeToString(hashedPassword)
+	return utils.HashPassword(utils.PasswordHashArgon2id, password)
 }
 
 func (provider *MemberProviderCtx) Connect() error {
@@ -49,10 +50,18 @@ func (provider *MemberProviderCtx) Authenticate(username string, password string
 		return "", types.MemberProfile{}, err
 	}
 
-	if entry.Password != provider.hash(password) {
+	ok, rehash := utils.VerifyStoredPassword(entry.Password, password, provider.config.Hash)
+	if !ok {
 		return "", types.MemberProfile{}, types.ErrMemberInvalidPassword
 	}
 
+	// replace legacy hash, now that we know the password
+	if rehash {
+		if err := provider.UpdatePassword(id, password); err != nil {
+			provider.logger.Warn().Err(err).Str("id", id).Msg("unable to rehash password")
+		}
+	}
+
 	return id, entry.Profile, nil
 }
 
@@ -70,8 +79,13 @@ func (provider *MemberProviderCtx) Insert(username string, password string, prof
 		return "", types.ErrMemberAlreadyExists
 	}
 
+	hashedPassword, err := provider.hash(password)
+	if err != nil {
+		return "", err
+	}
+
 	entries[id] = MemberEntry{
-		Password: provider.hash(password),
+		Password: hashedPassword,
 		Profile:  profile,
 	}
 
@@ -106,7 +120,47 @@ func (provider *MemberProviderCtx) UpdatePassword(id string, password string) er
 		return types.ErrMemberDoesNotExist
 	}
 
-	entry.Password = provider.hash(password)
+	hashedPassword, err := provider.hash(password)
+	if err != nil {
+		return err
+	}
+
+	entry.Password = hashedPassword
+	entries[id] = entry
+
+	return provider.serialize(entries)
+}
+
+func (provider *MemberProviderCtx) SelectTOTP(id string) (types.MemberTOTP, error) {
+	entry, err := provider.getEntry(id)
+	if err != nil {
+		return types.MemberTOTP{}, err
+	}
+
+	if entry.TOTP == nil {
+		return types.MemberTOTP{}, nil
+	}
+
+	return *entry.TOTP, nil
+}
+
+func (provider *MemberProviderCtx) UpdateTOTP(id string, totp types.MemberTOTP) error {
+	entries, err := provider.deserialize()
+	if err != nil {
+		return err
+	}
+
+	entry, ok := entries[id]
+	if !ok {
+		return types.ErrMemberDoesNotExist
+	}
+
+	// do not store empty state
+	if totp.Secret == "" {
+		entry.TOTP = nil
+	} else {
+		entry.TOTP = &totp
+	}
 	entries[id] = entry
 
 	return provider.serialize(entries)
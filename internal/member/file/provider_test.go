@@ -2,8 +2,12 @@ package file
 
 import (
 	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/demodesk/neko/pkg/types"
 	"github.com/demodesk/neko/pkg/utils"
 )
 
@@ -26,7 +30,10 @@ func TestMemberProviderCtx_hash(t *testing.T) {
 	}
 
 	for _, password := range passwords {
-		hashedPassword := provider.hash(password)
+		hashedPassword, err := provider.hash(password)
+		if err != nil {
+			t.Errorf("provider.hash() returned error: %s", err)
+		}
 
 		// json encode password hash
 		hashedPasswordJSON, err := json.Marshal(hashedPassword)
@@ -46,3 +53,41 @@ func TestMemberProviderCtx_hash(t *testing.T) {
 		}
 	}
 }
+
+// Ensure that legacy sha256 hashes are accepted and replaced on successful login
+func TestMemberProviderCtx_rehash(t *testing.T) {
+	provider := &MemberProviderCtx{
+		config: Config{
+			Path: filepath.Join(t.TempDir(), "members.json"),
+			Hash: true,
+		},
+	}
+
+	err := provider.serialize(map[string]MemberEntry{
+		"alice": {Password: utils.LegacyPasswordHash("secret")},
+	})
+	if err != nil {
+		t.Fatalf("provider.serialize() returned error: %s", err)
+	}
+
+	if _, _, err := provider.Authenticate("alice", "wrong"); !errors.Is(err, types.ErrMemberInvalidPassword) {
+		t.Errorf("provider.Authenticate() error = %v, want %v", err, types.ErrMemberInvalidPassword)
+	}
+
+	if _, _, err := provider.Authenticate("alice", "secret"); err != nil {
+		t.Fatalf("provider.Authenticate() returned error: %s", err)
+	}
+
+	entry, err := provider.getEntry("alice")
+	if err != nil {
+		t.Fatalf("provider.getEntry() returned error: %s", err)
+	}
+
+	if !strings.HasPrefix(entry.Password, "$argon2id$") {
+		t.Errorf("password was not rehashed: %s", entry.Password)
+	}
+
+	if _, _, err := provider.Authenticate("alice", "secret"); err != nil {
+		t.Errorf("provider.Authenticate() returned error after rehash: %s", err)
+	}
+}
@@ -7,6 +7,7 @@ import (
 type MemberEntry struct {
 	Password string              `json:"password"`
 	Profile  types.MemberProfile `json:"profile"`
+	TOTP     *types.MemberTOTP   `json:"totp,omitempty"`
 }
 
 type Config struct {
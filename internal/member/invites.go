@@ -0,0 +1,180 @@
+package member
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/demodesk/neko/pkg/types"
+	"github.com/demodesk/neko/pkg/utils"
+)
+
+// invites are held in memory only, they do not survive restart. Their sessions
+// expire together with the invite, so that they are reaped even after restart.
+type invite struct {
+	types.MemberInvite
+	timer *time.Timer
+}
+
+func (inv *invite) data() types.MemberInvite {
+	data := inv.MemberInvite
+	data.Sessions = append([]string{}, inv.Sessions...)
+	return data
+}
+
+func (manager *MemberManagerCtx) CreateInvite(profile types.MemberProfile, expiresAt time.Time, maxUses int) (types.MemberInvite, error) {
+	if !expiresAt.After(time.Now()) {
+		return types.MemberInvite{}, errors.New("invite expiration must be in the future")
+	}
+
+	id, err := utils.NewUID(16)
+	if err != nil {
+		return types.MemberInvite{}, err
+	}
+
+	token, err := utils.NewUID(32)
+	if err != nil {
+		return types.MemberInvite{}, err
+	}
+
+	inv := &invite{
+		MemberInvite: types.MemberInvite{
+			ID:        id,
+			Token:     token,
+			Profile:   profile,
+			ExpiresAt: expiresAt,
+			MaxUses:   maxUses,
+			Sessions:  []string{},
+		},
+	}
+
+	manager.invitesMu.Lock()
+	defer manager.invitesMu.Unlock()
+
+	inv.timer = time.AfterFunc(time.Until(expiresAt), func() {
+		manager.expireInvite(id)
+	})
+
+	manager.invites[id] = inv
+	manager.logger.Info().
+		Str("invite_id", id).
+		Time("expires_at", expiresAt).
+		Int("max_uses", maxUses).
+		Msg("invite created")
+
+	return inv.data(), nil
+}
+
+func (manager *MemberManagerCtx) ListInvites() []types.MemberInvite {
+	manager.invitesMu.Lock()
+	defer manager.invitesMu.Unlock()
+
+	invites := []types.MemberInvite{}
+	for _, inv := range manager.invites {
+		invites = append(invites, inv.data())
+	}
+
+	return invites
+}
+
+func (manager *MemberManagerCtx) DeleteInvite(id string) error {
+	manager.invitesMu.Lock()
+	inv, ok := manager.invites[id]
+	if !ok {
+		manager.invitesMu.Unlock()
+		return types.ErrMemberInviteNotFound
+	}
+
+	inv.timer.Stop()
+	delete(manager.invites, id)
+	manager.invitesMu.Unlock()
+
+	manager.revokeInvite(inv, "invite revoked")
+	return nil
+}
+
+func (manager *MemberManagerCtx) LoginInvite(token string, name string) (types.Session, string, error) {
+	manager.invitesMu.Lock()
+	defer manager.invitesMu.Unlock()
+
+	var inv *invite
+	for _, i := range manager.invites {
+		if subtle.ConstantTimeCompare([]byte(i.Token), []byte(token)) == 1 {
+			inv = i
+			break
+		}
+	}
+
+	// invite might have expired, but timer did not fire yet
+	if inv == nil || !time.Now().Before(inv.ExpiresAt) {
+		return nil, "", types.ErrMemberInviteNotFound
+	}
+
+	if inv.MaxUses > 0 && inv.Uses >= inv.MaxUses {
+		return nil, "", types.ErrMemberInviteUsedUp
+	}
+
+	profile := manager.resolve(inv.Profile)
+	if !profile.CanLogin {
+		return nil, "", types.ErrSessionLoginDisabled
+	}
+
+	id := fmt.Sprintf("invite-%s-%d", inv.ID, inv.Uses+1)
+	if name != "" {
+		profile.Name = name
+	} else if profile.Name == "" {
+		profile.Name = id
+	}
+
+	session, sessionToken, err := manager.sessions.CreateExpiring(id, profile, inv.ExpiresAt)
+	if err != nil {
+		return nil, "", err
+	}
+
+	inv.Uses++
+	inv.Sessions = append(inv.Sessions, id)
+	return session, sessionToken, nil
+}
+
+func (manager *MemberManagerCtx) expireInvite(id string) {
+	manager.invitesMu.Lock()
+	inv, ok := manager.invites[id]
+	if ok {
+		delete(manager.invites, id)
+	}
+	manager.invitesMu.Unlock()
+
+	if !ok {
+		return
+	}
+
+	manager.logger.Info().Str("invite_id", id).Msg("invite expired")
+	manager.revokeInvite(inv, "invite expired")
+}
+
+// revokeInvite disconnects and deletes all sessions created by invite.
+func (manager *MemberManagerCtx) revokeInvite(inv *invite, reason string) {
+	for _, id := range inv.Sessions {
+		session, ok := manager.sessions.Get(id)
+		if !ok {
+			continue
+		}
+
+		session.DestroyWebSocketPeer(reason)
+
+		err := manager.sessions.Delete(id)
+		if err != nil && !errors.Is(err, types.ErrSessionNotFound) {
+			manager.logger.Err(err).Str("session_id", id).Msg("error while deleting invite session")
+		}
+	}
+}
+
+func (manager *MemberManagerCtx) stopInvites() {
+	manager.invitesMu.Lock()
+	defer manager.invitesMu.Unlock()
+
+	for _, inv := range manager.invites {
+		inv.timer.Stop()
+	}
+}
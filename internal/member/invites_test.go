@@ -0,0 +1,93 @@
+package member
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/demodesk/neko/internal/config"
+	"github.com/demodesk/neko/internal/session"
+	"github.com/demodesk/neko/pkg/types"
+)
+
+func TestMemberManagerCtx_invites(t *testing.T) {
+	sessions := session.New(&config.Session{})
+	manager := New(sessions, &config.Member{})
+
+	profile := types.MemberProfile{
+		Name:     "Customer",
+		CanLogin: true,
+		CanWatch: true,
+	}
+
+	invite, err := manager.CreateInvite(profile, time.Now().Add(200*time.Millisecond), 2)
+	if err != nil {
+		t.Fatalf("manager.CreateInvite() returned error: %s", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		session, _, err := manager.LoginInvite(invite.Token, "")
+		if err != nil {
+			t.Fatalf("manager.LoginInvite() returned error: %s", err)
+		}
+		if session.Profile().Name != "Customer" || !session.Profile().CanWatch {
+			t.Errorf("manager.LoginInvite() profile = %+v", session.Profile())
+		}
+	}
+
+	if _, _, err := manager.LoginInvite(invite.Token, ""); !errors.Is(err, types.ErrMemberInviteUsedUp) {
+		t.Errorf("manager.LoginInvite() error = %v, want %v", err, types.ErrMemberInviteUsedUp)
+	}
+
+	if _, _, err := manager.LoginInvite("wrong", ""); !errors.Is(err, types.ErrMemberInviteNotFound) {
+		t.Errorf("manager.LoginInvite() error = %v, want %v", err, types.ErrMemberInviteNotFound)
+	}
+
+	invites := manager.ListInvites()
+	if len(invites) != 1 || invites[0].Uses != 2 || len(invites[0].Sessions) != 2 {
+		t.Fatalf("manager.ListInvites() = %+v", invites)
+	}
+
+	// expired invite revokes its sessions
+	time.Sleep(400 * time.Millisecond)
+
+	if len(manager.ListInvites()) != 0 {
+		t.Errorf("manager.ListInvites() expected expired invite to be removed")
+	}
+
+	for _, id := range invites[0].Sessions {
+		if _, ok := sessions.Get(id); ok {
+			t.Errorf("session %s expected to be revoked", id)
+		}
+	}
+}
+
+func TestMemberManagerCtx_DeleteInvite(t *testing.T) {
+	sessions := session.New(&config.Session{})
+	manager := New(sessions, &config.Member{})
+
+	invite, err := manager.CreateInvite(types.MemberProfile{CanLogin: true}, time.Now().Add(time.Hour), 0)
+	if err != nil {
+		t.Fatalf("manager.CreateInvite() returned error: %s", err)
+	}
+
+	session, _, err := manager.LoginInvite(invite.Token, "Guest")
+	if err != nil {
+		t.Fatalf("manager.LoginInvite() returned error: %s", err)
+	}
+	if session.Profile().Name != "Guest" {
+		t.Errorf("manager.LoginInvite() name = %s, want Guest", session.Profile().Name)
+	}
+
+	if err := manager.DeleteInvite(invite.ID); err != nil {
+		t.Fatalf("manager.DeleteInvite() returned error: %s", err)
+	}
+
+	if _, ok := sessions.Get(session.ID()); ok {
+		t.Errorf("session expected to be revoked")
+	}
+
+	if err := manager.DeleteInvite(invite.ID); !errors.Is(err, types.ErrMemberInviteNotFound) {
+		t.Errorf("manager.DeleteInvite() error = %v, want %v", err, types.ErrMemberInviteNotFound)
+	}
+}
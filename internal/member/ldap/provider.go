@@ -0,0 +1,297 @@
+package ldap
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"sync"
+
+	goldap "github.com/go-ldap/ldap/v3"
+	"github.com/mitchellh/mapstructure"
+
+	"github.com/demodesk/neko/pkg/types"
+)
+
+// page size used when searching, directories usually limit size of single result
+const searchPagingSize = 500
+
+var errReadOnly = errors.New("ldap provider is read-only, members are managed in directory")
+
+func New(config Config) *MemberProviderCtx {
+	return &MemberProviderCtx{
+		config:   config,
+		profiles: map[string]types.MemberProfile{},
+	}
+}
+
+type MemberProviderCtx struct {
+	config Config
+
+	// profiles changed at runtime, valid until next login
+	profiles   map[string]types.MemberProfile
+	profilesMu sync.Mutex
+}
+
+func (provider *MemberProviderCtx) Connect() error {
+	// check that directory is reachable and service account is valid
+	conn, err := provider.dial()
+	if err != nil {
+		return err
+	}
+
+	return conn.Close()
+}
+
+func (provider *MemberProviderCtx) Disconnect() error {
+	return nil
+}
+
+// dial opens new connection bound as service account, it must be closed after use.
+func (provider *MemberProviderCtx) dial() (*goldap.Conn, error) {
+	conn, err := goldap.DialURL(provider.config.URL)
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to ldap server: %w", err)
+	}
+
+	if provider.config.StartTLS {
+		u, err := url.Parse(provider.config.URL)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+
+		if err := conn.StartTLS(&tls.Config{ServerName: u.Hostname()}); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("unable to start tls: %w", err)
+		}
+	}
+
+	if provider.config.BindDN != "" {
+		if err := conn.Bind(provider.config.BindDN, provider.config.BindPassword); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("unable to bind service account: %w", err)
+		}
+	}
+
+	return conn, nil
+}
+
+func (provider *MemberProviderCtx) search(conn *goldap.Conn, filter string) ([]*goldap.Entry, error) {
+	req := goldap.NewSearchRequest(
+		provider.config.BaseDN,
+		goldap.ScopeWholeSubtree,
+		goldap.NeverDerefAliases,
+		0, 0, false,
+		filter,
+		[]string{
+			provider.config.IDAttribute,
+			provider.config.NameAttribute,
+			provider.config.GroupAttribute,
+		},
+		nil,
+	)
+
+	res, err := conn.SearchWithPaging(req, searchPagingSize)
+	if err != nil {
+		return nil, fmt.Errorf("unable to search directory: %w", err)
+	}
+
+	return res.Entries, nil
+}
+
+// filter matches members with attribute equal to value.
+func (provider *MemberProviderCtx) filter(attribute string, value string) string {
+	return fmt.Sprintf("(&%s(%s=%s))", provider.config.UserFilter, attribute, goldap.EscapeFilter(value))
+}
+
+// profile applies all matching groups on top of default profile.
+func (provider *MemberProviderCtx) profile(entry *goldap.Entry) (string, types.MemberProfile, error) {
+	id := entry.GetAttributeValue(provider.config.IDAttribute)
+	if id == "" {
+		return "", types.MemberProfile{}, fmt.Errorf("entry %q is missing %q attribute", entry.DN, provider.config.IDAttribute)
+	}
+
+	profile := provider.config.DefaultProfile
+
+	// groups must not modify plugins map of default profile
+	if profile.Plugins != nil {
+		plugins := make(map[string]any, len(profile.Plugins))
+		for key, value := range profile.Plugins {
+			plugins[key] = value
+		}
+		profile.Plugins = plugins
+	}
+
+	memberOf := entry.GetAttributeValues(provider.config.GroupAttribute)
+	for i, group := range provider.config.Groups {
+		matched := false
+		for _, dn := range memberOf {
+			if group.Match(dn) {
+				matched = true
+				break
+			}
+		}
+
+		if !matched {
+			continue
+		}
+
+		if err := mapstructure.Decode(group.Profile, &profile); err != nil {
+			return "", types.MemberProfile{}, fmt.Errorf("unable to apply group %d: %w", i, err)
+		}
+	}
+
+	if profile.Name == "" {
+		profile.Name = entry.GetAttributeValue(provider.config.NameAttribute)
+	}
+	if profile.Name == "" {
+		profile.Name = id
+	}
+
+	return id, profile, nil
+}
+
+func (provider *MemberProviderCtx) Authenticate(username string, password string) (string, types.MemberProfile, error) {
+	// empty password would result in unauthenticated bind, that always succeeds
+	if username == "" || password == "" {
+		return "", types.MemberProfile{}, types.ErrMemberInvalidPassword
+	}
+
+	conn, err := provider.dial()
+	if err != nil {
+		return "", types.MemberProfile{}, err
+	}
+	defer conn.Close()
+
+	entries, err := provider.search(conn, provider.filter(provider.config.LoginAttribute, username))
+	if err != nil {
+		return "", types.MemberProfile{}, err
+	}
+
+	if len(entries) == 0 {
+		return "", types.MemberProfile{}, types.ErrMemberDoesNotExist
+	}
+
+	if len(entries) > 1 {
+		return "", types.MemberProfile{}, fmt.Errorf("username %q matches %d entries", username, len(entries))
+	}
+
+	entry := entries[0]
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if goldap.IsErrorWithCode(err, goldap.LDAPResultInvalidCredentials) {
+			return "", types.MemberProfile{}, types.ErrMemberInvalidPassword
+		}
+		return "", types.MemberProfile{}, fmt.Errorf("unable to bind member: %w", err)
+	}
+
+	id, profile, err := provider.profile(entry)
+	if err != nil {
+		return "", types.MemberProfile{}, err
+	}
+
+	// directory is the source of truth on login
+	provider.profilesMu.Lock()
+	delete(provider.profiles, id)
+	provider.profilesMu.Unlock()
+
+	return id, profile, nil
+}
+
+func (provider *MemberProviderCtx) Insert(username string, password string, profile types.MemberProfile) (string, error) {
+	return "", errReadOnly
+}
+
+func (provider *MemberProviderCtx) UpdateProfile(id string, profile types.MemberProfile) error {
+	if _, err := provider.Select(id); err != nil {
+		return err
+	}
+
+	provider.profilesMu.Lock()
+	defer provider.profilesMu.Unlock()
+
+	provider.profiles[id] = profile
+	return nil
+}
+
+func (provider *MemberProviderCtx) UpdatePassword(id string, password string) error {
+	return errReadOnly
+}
+
+func (provider *MemberProviderCtx) Select(id string) (types.MemberProfile, error) {
+	conn, err := provider.dial()
+	if err != nil {
+		return types.MemberProfile{}, err
+	}
+	defer conn.Close()
+
+	entries, err := provider.search(conn, provider.filter(provider.config.IDAttribute, id))
+	if err != nil {
+		return types.MemberProfile{}, err
+	}
+
+	if len(entries) != 1 {
+		return types.MemberProfile{}, types.ErrMemberDoesNotExist
+	}
+
+	_, profile, err := provider.profile(entries[0])
+	if err != nil {
+		return types.MemberProfile{}, err
+	}
+
+	provider.profilesMu.Lock()
+	defer provider.profilesMu.Unlock()
+
+	if override, ok := provider.profiles[id]; ok {
+		return override, nil
+	}
+
+	return profile, nil
+}
+
+func (provider *MemberProviderCtx) SelectAll(limit int, offset int) (map[string]types.MemberProfile, error) {
+	conn, err := provider.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	entries, err := provider.search(conn, provider.config.UserFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	// stable order, so that offset points to the same members
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].DN < entries[j].DN
+	})
+
+	provider.profilesMu.Lock()
+	defer provider.profilesMu.Unlock()
+
+	profiles := make(map[string]types.MemberProfile)
+
+	i := 0
+	for _, entry := range entries {
+		id, profile, err := provider.profile(entry)
+		if err != nil {
+			// skip entries that cannot be members
+			continue
+		}
+
+		if i >= offset && (limit == 0 || i < offset+limit) {
+			if override, ok := provider.profiles[id]; ok {
+				profile = override
+			}
+			profiles[id] = profile
+		}
+		i = i + 1
+	}
+
+	return profiles, nil
+}
+
+func (provider *MemberProviderCtx) Delete(id string) error {
+	return errReadOnly
+}
@@ -0,0 +1,386 @@
+package ldap
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	goldap "github.com/go-ldap/ldap/v3"
+
+	"github.com/demodesk/neko/pkg/types"
+)
+
+type mockEntry struct {
+	dn         string
+	password   string
+	attributes map[string][]string
+}
+
+// mockServer is a minimal in-process directory, it supports simple bind and search.
+type mockServer struct {
+	listener net.Listener
+	entries  []mockEntry
+}
+
+func newMockServer(t *testing.T, entries []mockEntry) *mockServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() returned error: %s", err)
+	}
+
+	server := &mockServer{
+		listener: listener,
+		entries:  entries,
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.handle(conn)
+		}
+	}()
+
+	t.Cleanup(func() { listener.Close() })
+	return server
+}
+
+func (s *mockServer) URL() string {
+	return "ldap://" + s.listener.Addr().String()
+}
+
+func (s *mockServer) handle(conn net.Conn) {
+	defer conn.Close()
+
+	bound := ""
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+
+		id, _ := packet.Children[0].Value.(int64)
+		op := packet.Children[1]
+
+		switch op.Tag {
+		case goldap.ApplicationBindRequest:
+			name, _ := op.Children[1].Value.(string)
+			password := op.Children[2].Data.String()
+
+			code := uint16(goldap.LDAPResultInvalidCredentials)
+			if name == "" && password == "" {
+				code = goldap.LDAPResultSuccess
+			}
+			for _, entry := range s.entries {
+				if strings.EqualFold(entry.dn, name) && entry.password != "" && entry.password == password {
+					code = goldap.LDAPResultSuccess
+				}
+			}
+			if code == goldap.LDAPResultSuccess {
+				bound = name
+			}
+
+			s.write(conn, id, s.result(goldap.ApplicationBindResponse, code))
+		case goldap.ApplicationSearchRequest:
+			if bound == "" {
+				s.write(conn, id, s.result(goldap.ApplicationSearchResultDone, goldap.LDAPResultInsufficientAccessRights))
+				continue
+			}
+
+			baseDN, _ := op.Children[0].Value.(string)
+			filter := op.Children[6]
+
+			for _, entry := range s.entries {
+				if !strings.HasSuffix(strings.ToLower(entry.dn), ","+strings.ToLower(baseDN)) || !entry.match(filter) {
+					continue
+				}
+				s.write(conn, id, entry.packet())
+			}
+
+			s.write(conn, id, s.result(goldap.ApplicationSearchResultDone, goldap.LDAPResultSuccess))
+		case goldap.ApplicationUnbindRequest:
+			return
+		}
+	}
+}
+
+func (s *mockServer) result(tag ber.Tag, code uint16) *ber.Packet {
+	res := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "")
+	res.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(code), ""))
+	res.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", ""))
+	res.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", ""))
+	return res
+}
+
+func (s *mockServer) write(conn net.Conn, id int64, op *ber.Packet) {
+	packet := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, ""))
+	packet.AppendChild(op)
+	_, _ = conn.Write(packet.Bytes())
+}
+
+func (e *mockEntry) packet() *ber.Packet {
+	res := ber.Encode(ber.ClassApplication, ber.TypeConstructed, goldap.ApplicationSearchResultEntry, nil, "")
+	res.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, e.dn, ""))
+
+	attributes := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "")
+	for name, values := range e.attributes {
+		attribute := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "")
+		attribute.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, ""))
+
+		set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "")
+		for _, value := range values {
+			set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, ""))
+		}
+
+		attribute.AppendChild(set)
+		attributes.AppendChild(attribute)
+	}
+
+	res.AppendChild(attributes)
+	return res
+}
+
+func (e *mockEntry) values(name string) []string {
+	for key, values := range e.attributes {
+		if strings.EqualFold(key, name) {
+			return values
+		}
+	}
+	return nil
+}
+
+// match evaluates subset of search filters, that is used by the provider.
+func (e *mockEntry) match(filter *ber.Packet) bool {
+	switch filter.Tag {
+	case goldap.FilterAnd:
+		for _, child := range filter.Children {
+			if !e.match(child) {
+				return false
+			}
+		}
+		return true
+	case goldap.FilterOr:
+		for _, child := range filter.Children {
+			if e.match(child) {
+				return true
+			}
+		}
+		return false
+	case goldap.FilterNot:
+		return !e.match(filter.Children[0])
+	case goldap.FilterPresent:
+		return len(e.values(filter.Data.String())) > 0
+	case goldap.FilterEqualityMatch:
+		name, _ := filter.Children[0].Value.(string)
+		value, _ := filter.Children[1].Value.(string)
+		for _, v := range e.values(name) {
+			if strings.EqualFold(v, value) {
+				return true
+			}
+		}
+		return false
+	default:
+		return false
+	}
+}
+
+func newTestProvider(t *testing.T) *MemberProviderCtx {
+	server := newMockServer(t, []mockEntry{
+		{
+			dn:       "cn=service,dc=example,dc=com",
+			password: "service",
+		},
+		{
+			dn:       "uid=alice,ou=people,dc=example,dc=com",
+			password: "alice",
+			attributes: map[string][]string{
+				"objectClass": {"person"},
+				"uid":         {"alice"},
+				"cn":          {"Alice"},
+				"memberOf":    {"cn=users,ou=groups,dc=example,dc=com", "cn=neko-admins,ou=groups,dc=example,dc=com"},
+			},
+		},
+		{
+			dn:       "uid=bob,ou=people,dc=example,dc=com",
+			password: "bob",
+			attributes: map[string][]string{
+				"objectClass": {"person"},
+				"uid":         {"bob"},
+				"cn":          {"Bob"},
+				"memberOf":    {"cn=users,ou=groups,dc=example,dc=com"},
+			},
+		},
+		{
+			dn:       "uid=carol,ou=people,dc=example,dc=com",
+			password: "carol",
+			attributes: map[string][]string{
+				"objectClass": {"person"},
+				"uid":         {"carol"},
+			},
+		},
+	})
+
+	provider := New(Config{
+		URL:            server.URL(),
+		BindDN:         "cn=service,dc=example,dc=com",
+		BindPassword:   "service",
+		BaseDN:         "dc=example,dc=com",
+		UserFilter:     "(objectClass=person)",
+		LoginAttribute: "uid",
+		IDAttribute:    "uid",
+		NameAttribute:  "cn",
+		GroupAttribute: "memberOf",
+		DefaultProfile: types.MemberProfile{
+			CanLogin: true,
+			CanWatch: true,
+		},
+		Groups: []Group{
+			{DN: "cn=users,ou=groups,dc=example,dc=com", Profile: map[string]any{"can_host": true}},
+			{DN: "cn=neko-admins", Profile: map[string]any{"is_admin": true}},
+		},
+	})
+
+	if err := provider.Connect(); err != nil {
+		t.Fatalf("provider.Connect() returned error: %s", err)
+	}
+
+	return provider
+}
+
+func TestMemberProviderCtx_Authenticate(t *testing.T) {
+	provider := newTestProvider(t)
+
+	tests := []struct {
+		name     string
+		username string
+		password string
+		id       string
+		profile  types.MemberProfile
+		err      error
+	}{
+		{
+			name:     "admin group",
+			username: "alice",
+			password: "alice",
+			id:       "alice",
+			profile:  types.MemberProfile{Name: "Alice", IsAdmin: true, CanLogin: true, CanWatch: true, CanHost: true},
+		},
+		{
+			name:     "user group",
+			username: "bob",
+			password: "bob",
+			id:       "bob",
+			profile:  types.MemberProfile{Name: "Bob", CanLogin: true, CanWatch: true, CanHost: true},
+		},
+		{
+			name:     "no group",
+			username: "carol",
+			password: "carol",
+			id:       "carol",
+			profile:  types.MemberProfile{Name: "carol", CanLogin: true, CanWatch: true},
+		},
+		{
+			name:     "invalid password",
+			username: "bob",
+			password: "alice",
+			err:      types.ErrMemberInvalidPassword,
+		},
+		{
+			name:     "empty password",
+			username: "bob",
+			password: "",
+			err:      types.ErrMemberInvalidPassword,
+		},
+		{
+			name:     "unknown member",
+			username: "dave",
+			password: "dave",
+			err:      types.ErrMemberDoesNotExist,
+		},
+		{
+			name:     "filter injection",
+			username: "*",
+			password: "alice",
+			err:      types.ErrMemberDoesNotExist,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, profile, err := provider.Authenticate(tt.username, tt.password)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Errorf("provider.Authenticate() error = %v, want %v", err, tt.err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("provider.Authenticate() returned error: %s", err)
+			}
+
+			if id != tt.id {
+				t.Errorf("provider.Authenticate() id = %s, want %s", id, tt.id)
+			}
+
+			if profile.Name != tt.profile.Name || profile.IsAdmin != tt.profile.IsAdmin ||
+				profile.CanLogin != tt.profile.CanLogin || profile.CanWatch != tt.profile.CanWatch ||
+				profile.CanHost != tt.profile.CanHost {
+				t.Errorf("provider.Authenticate() profile = %+v, want %+v", profile, tt.profile)
+			}
+		})
+	}
+}
+
+func TestMemberProviderCtx_Select(t *testing.T) {
+	provider := newTestProvider(t)
+
+	profile, err := provider.Select("alice")
+	if err != nil {
+		t.Fatalf("provider.Select() returned error: %s", err)
+	}
+	if !profile.IsAdmin {
+		t.Errorf("provider.Select() expected admin profile")
+	}
+
+	if _, err := provider.Select("dave"); !errors.Is(err, types.ErrMemberDoesNotExist) {
+		t.Errorf("provider.Select() error = %v, want %v", err, types.ErrMemberDoesNotExist)
+	}
+
+	profiles, err := provider.SelectAll(0, 0)
+	if err != nil {
+		t.Fatalf("provider.SelectAll() returned error: %s", err)
+	}
+	if len(profiles) != 3 {
+		t.Errorf("provider.SelectAll() returned %d members, want 3", len(profiles))
+	}
+
+	profiles, err = provider.SelectAll(1, 1)
+	if err != nil {
+		t.Fatalf("provider.SelectAll() returned error: %s", err)
+	}
+	if _, ok := profiles["bob"]; !ok || len(profiles) != 1 {
+		t.Errorf("provider.SelectAll() = %v, want only bob", profiles)
+	}
+}
+
+func TestMemberProviderCtx_ReadOnly(t *testing.T) {
+	provider := newTestProvider(t)
+
+	if _, err := provider.Insert("dave", "dave", types.MemberProfile{}); !errors.Is(err, errReadOnly) {
+		t.Errorf("provider.Insert() error = %v, want %v", err, errReadOnly)
+	}
+
+	if err := provider.UpdatePassword("alice", "new"); !errors.Is(err, errReadOnly) {
+		t.Errorf("provider.UpdatePassword() error = %v, want %v", err, errReadOnly)
+	}
+
+	if err := provider.Delete("alice"); !errors.Is(err, errReadOnly) {
+		t.Errorf("provider.Delete() error = %v, want %v", err, errReadOnly)
+	}
+}
@@ -0,0 +1,48 @@
+package ldap
+
+import (
+	"strings"
+
+	"github.com/demodesk/neko/pkg/types"
+)
+
+// Group changes member profile if member belongs to the group.
+type Group struct {
+	// full group DN, or its first RDN e.g. cn=neko-admins
+	DN string `json:"dn"      mapstructure:"dn"`
+	// profile fields that are set, e.g. {"is_admin": true}
+	Profile map[string]any `json:"profile" mapstructure:"profile"`
+}
+
+func (g *Group) Match(dn string) bool {
+	dn = strings.ToLower(dn)
+	group := strings.ToLower(g.DN)
+	return dn == group || strings.HasPrefix(dn, group+",")
+}
+
+type Config struct {
+	// ldap:// or ldaps:// URL of directory server
+	URL      string
+	StartTLS bool
+
+	// service account used for searching, anonymous if empty
+	BindDN       string
+	BindPassword string
+
+	BaseDN string
+	// filter matching all members
+	UserFilter string
+	// attribute matched against username on login
+	LoginAttribute string
+	// attribute used as member ID
+	IDAttribute string
+	// attribute used as member name
+	NameAttribute string
+	// attribute listing DNs of member groups
+	GroupAttribute string
+
+	// profile before groups are applied
+	DefaultProfile types.MemberProfile
+	// groups are applied in order, later groups override earlier ones
+	Groups []Group
+}
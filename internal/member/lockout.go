@@ -0,0 +1,228 @@
+package member
+
+import (
+	"math/bits"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/demodesk/neko/internal/config"
+	"github.com/demodesk/neko/pkg/types"
+)
+
+// metrics are shared by all managers, so that they are registered only once
+var (
+	loginFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name:      "login_failures_total",
+		Namespace: "neko",
+		Subsystem: "member",
+		Help:      "Total number of failed login attempts.",
+	}, []string{"reason"})
+
+	loginLockouts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name:      "login_lockouts_total",
+		Namespace: "neko",
+		Subsystem: "member",
+		Help:      "Total number of temporary login lockouts.",
+	}, []string{"type"})
+)
+
+// maximum number of tracked usernames, so that guessing random usernames cannot exhaust memory
+const lockoutMaxUsernames = 10000
+
+type attempts struct {
+	failures    int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+// lockoutCtx counts failed login attempts per username and per IP address,
+// lockout duration doubles with every failed attempt over the limit.
+type lockoutCtx struct {
+	config    *config.Member
+	mu        sync.Mutex
+	usernames map[string]*attempts
+	ips       map[string]*attempts
+}
+
+func newLockout(config *config.Member) *lockoutCtx {
+	return &lockoutCtx{
+		config:    config,
+		usernames: make(map[string]*attempts),
+		ips:       make(map[string]*attempts),
+	}
+}
+
+// locked checks if login for username or from IP address is temporarily not allowed.
+func (l *lockoutCtx) locked(username string, ip string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+
+	if a, ok := l.usernames[username]; ok && now.Before(a.lockedUntil) {
+		return true
+	}
+
+	if a, ok := l.ips[ip]; ok && now.Before(a.lockedUntil) {
+		return true
+	}
+
+	return false
+}
+
+// fail records failed attempt and returns number of failures for username,
+// empty username records failed attempt only for IP address.
+func (l *lockoutCtx) fail(username string, ip string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.cleanup(now)
+
+	// new usernames are not tracked when full, IP address counter still applies
+	if _, ok := l.usernames[username]; !ok && len(l.usernames) >= lockoutMaxUsernames {
+		username = ""
+	}
+
+	failures := l.record(l.usernames, username, l.config.LockoutAttempts, "username", now)
+	l.record(l.ips, ip, l.config.LockoutIPAttempts, "ip", now)
+
+	return failures
+}
+
+// success resets failed attempts for username, IP address counter is kept,
+// so that one valid account cannot be used to unlock guessing of others.
+func (l *lockoutCtx) success(username string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.usernames, username)
+}
+
+func (l *lockoutCtx) record(entries map[string]*attempts, key string, max int, kind string, now time.Time) int {
+	// zero limit disables lockout
+	if max <= 0 || key == "" {
+		return 0
+	}
+
+	a, ok := entries[key]
+	if !ok {
+		a = &attempts{}
+		entries[key] = a
+	}
+
+	a.failures++
+	a.lastFailure = now
+
+	if a.failures >= max {
+		// clamp exponent, so that shift cannot overflow
+		shift := a.failures - max
+		if limit := 62 - bits.Len64(uint64(l.config.LockoutDuration)); shift > limit {
+			shift = limit
+		}
+
+		duration := l.config.LockoutDuration << shift
+		// invalid or over maximum
+		if duration <= 0 || duration > l.config.LockoutMaxDuration {
+			duration = l.config.LockoutMaxDuration
+		}
+
+		a.lockedUntil = now.Add(duration)
+		loginLockouts.WithLabelValues(kind).Inc()
+	}
+
+	return a.failures
+}
+
+// cleanup forgets failed attempts, that are not locked and older than maximum lockout duration.
+func (l *lockoutCtx) cleanup(now time.Time) {
+	for _, entries := range []map[string]*attempts{l.usernames, l.ips} {
+		for key, a := range entries {
+			if now.After(a.lockedUntil) && now.Sub(a.lastFailure) > l.config.LockoutMaxDuration {
+				delete(entries, key)
+			}
+		}
+	}
+}
+
+func (l *lockoutCtx) list() []types.MemberLockout {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.cleanup(now)
+
+	lockouts := []types.MemberLockout{}
+	add := func(a *attempts, lockout types.MemberLockout) {
+		lockout.Failures = a.failures
+		if now.Before(a.lockedUntil) {
+			lockedUntil := a.lockedUntil
+			lockout.LockedUntil = &lockedUntil
+		}
+		lockouts = append(lockouts, lockout)
+	}
+
+	for username, a := range l.usernames {
+		add(a, types.MemberLockout{Username: username})
+	}
+
+	for ip, a := range l.ips {
+		add(a, types.MemberLockout{IP: ip})
+	}
+
+	sort.Slice(lockouts, func(i, j int) bool {
+		if lockouts[i].Username != lockouts[j].Username {
+			return lockouts[i].Username < lockouts[j].Username
+		}
+		return lockouts[i].IP < lockouts[j].IP
+	})
+
+	return lockouts
+}
+
+func (l *lockoutCtx) clear(username string, ip string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	_, okUsername := l.usernames[username]
+	delete(l.usernames, username)
+
+	_, okIP := l.ips[ip]
+	delete(l.ips, ip)
+
+	return okUsername || okIP
+}
+
+func (l *lockoutCtx) clearAll() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.usernames = make(map[string]*attempts)
+	l.ips = make(map[string]*attempts)
+}
+
+//
+// manager
+//
+
+func (manager *MemberManagerCtx) ListLockouts() []types.MemberLockout {
+	return manager.lockout.list()
+}
+
+func (manager *MemberManagerCtx) ClearLockout(username string, ip string) error {
+	if !manager.lockout.clear(username, ip) {
+		return types.ErrMemberLockoutNotFound
+	}
+
+	manager.logger.Info().Str("username", username).Str("ip", ip).Msg("login lockout cleared")
+	return nil
+}
+
+func (manager *MemberManagerCtx) ClearLockouts() {
+	manager.lockout.clearAll()
+	manager.logger.Info().Msg("all login lockouts cleared")
+}
@@ -0,0 +1,124 @@
+package member
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/demodesk/neko/internal/config"
+	"github.com/demodesk/neko/internal/member/object"
+	"github.com/demodesk/neko/internal/session"
+	"github.com/demodesk/neko/pkg/types"
+)
+
+func TestLockoutCtx_backoff(t *testing.T) {
+	lockout := newLockout(&config.Member{
+		LockoutAttempts:    3,
+		LockoutIPAttempts:  10,
+		LockoutDuration:    time.Minute,
+		LockoutMaxDuration: 5 * time.Minute,
+	})
+
+	for i := 0; i < 2; i++ {
+		lockout.fail("alice", "10.0.0.1")
+	}
+	if lockout.locked("alice", "10.0.0.2") {
+		t.Fatalf("lockout.locked() before reaching limit")
+	}
+
+	start := time.Now()
+	for i, want := range []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute} {
+		lockout.fail("alice", "10.0.0.1")
+
+		lockedUntil := lockout.usernames["alice"].lockedUntil
+		if got := lockedUntil.Sub(start).Round(time.Minute); got != want {
+			t.Errorf("failure %d: lockout duration = %s, want %s", i+3, got, want)
+		}
+	}
+
+	if !lockout.locked("alice", "10.0.0.2") {
+		t.Errorf("lockout.locked() expected username to be locked from other IP")
+	}
+	if lockout.locked("bob", "10.0.0.1") {
+		t.Errorf("lockout.locked() expected IP to be below its limit")
+	}
+
+	// long running attempts must not overflow lockout duration
+	lockout.usernames["alice"].failures = 100
+	lockout.fail("alice", "10.0.0.1")
+	if got := time.Until(lockout.usernames["alice"].lockedUntil).Round(time.Minute); got != 5*time.Minute {
+		t.Errorf("lockout duration after many failures = %s, want %s", got, 5*time.Minute)
+	}
+
+	lockout.success("alice")
+	if lockout.locked("alice", "10.0.0.2") {
+		t.Errorf("lockout.locked() expected username to be unlocked after success")
+	}
+
+	// number of tracked usernames is limited
+	for i := 0; i < lockoutMaxUsernames+10; i++ {
+		lockout.fail(fmt.Sprintf("user%d", i), "")
+	}
+	if len(lockout.usernames) != lockoutMaxUsernames {
+		t.Errorf("lockout tracks %d usernames, want %d", len(lockout.usernames), lockoutMaxUsernames)
+	}
+}
+
+func TestMemberManagerCtx_Login_lockout(t *testing.T) {
+	manager := New(session.New(&config.Session{}), &config.Member{
+		Provider: "object",
+		Object: object.Config{
+			Users: []object.User{
+				{Username: "alice", Password: "secret", Profile: types.MemberProfile{CanLogin: true}},
+			},
+		},
+		LockoutAttempts:    2,
+		LockoutIPAttempts:  10,
+		LockoutDuration:    time.Minute,
+		LockoutMaxDuration: time.Hour,
+	})
+
+	if err := manager.Connect(); err != nil {
+		t.Fatalf("manager.Connect() returned error: %s", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, _, err := manager.Login("alice", "wrong", "10.0.0.1"); !errors.Is(err, types.ErrMemberInvalidPassword) {
+			t.Errorf("manager.Login() error = %v, want %v", err, types.ErrMemberInvalidPassword)
+		}
+	}
+
+	// correct password is rejected while locked out
+	if _, _, err := manager.Login("alice", "secret", "10.0.0.1"); !errors.Is(err, types.ErrMemberLockedOut) {
+		t.Errorf("manager.Login() error = %v, want %v", err, types.ErrMemberLockedOut)
+	}
+
+	lockouts := manager.ListLockouts()
+	if len(lockouts) != 2 || lockouts[0].IP != "10.0.0.1" || lockouts[1].Username != "alice" || lockouts[1].LockedUntil == nil {
+		t.Errorf("manager.ListLockouts() = %+v", lockouts)
+	}
+
+	if err := manager.ClearLockout("alice", ""); err != nil {
+		t.Fatalf("manager.ClearLockout() returned error: %s", err)
+	}
+
+	if _, _, err := manager.Login("alice", "secret", "10.0.0.1"); err != nil {
+		t.Errorf("manager.Login() returned error: %s", err)
+	}
+
+	if err := manager.ClearLockout("alice", ""); !errors.Is(err, types.ErrMemberLockoutNotFound) {
+		t.Errorf("manager.ClearLockout() error = %v, want %v", err, types.ErrMemberLockoutNotFound)
+	}
+
+	// nonexistent usernames are counted only for IP address
+	if _, _, err := manager.Login("nobody", "secret", "10.0.0.2"); !errors.Is(err, types.ErrMemberDoesNotExist) {
+		t.Errorf("manager.Login() error = %v, want %v", err, types.ErrMemberDoesNotExist)
+	}
+	if err := manager.ClearLockout("nobody", ""); !errors.Is(err, types.ErrMemberLockoutNotFound) {
+		t.Errorf("manager.ClearLockout() error = %v, want %v", err, types.ErrMemberLockoutNotFound)
+	}
+	if err := manager.ClearLockout("", "10.0.0.2"); err != nil {
+		t.Errorf("manager.ClearLockout() returned error: %s", err)
+	}
+}
@@ -1,7 +1,9 @@
 package member
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"sync"
 
 	"github.com/rs/zerolog"
@@ -9,9 +11,12 @@ import (
 
 	"github.com/demodesk/neko/internal/config"
 	"github.com/demodesk/neko/internal/member/file"
+	"github.com/demodesk/neko/internal/member/ldap"
 	"github.com/demodesk/neko/internal/member/multiuser"
 	"github.com/demodesk/neko/internal/member/noauth"
 	"github.com/demodesk/neko/internal/member/object"
+	"github.com/demodesk/neko/internal/member/oidc"
+	"github.com/demodesk/neko/internal/member/sql"
 	"github.com/demodesk/neko/pkg/types"
 )
 
@@ -20,15 +25,28 @@ func New(sessions types.SessionManager, config *config.Member) *MemberManagerCtx
 		logger:   log.With().Str("module", "member").Logger(),
 		sessions: sessions,
 		config:   config,
+		invites:  make(map[string]*invite),
+
+		totpChallenges: make(map[string]*totpChallenge),
+
+		lockout: newLockout(config),
 	}
 
+	manager.loadRoles()
+
 	switch config.Provider {
 	case "file":
 		manager.provider = file.New(config.File)
 	case "object":
 		manager.provider = object.New(config.Object)
+	case "sql":
+		manager.provider = sql.New(config.SQL)
 	case "multiuser":
 		manager.provider = multiuser.New(config.Multiuser)
+	case "oidc":
+		manager.provider = oidc.New(config.OIDC)
+	case "ldap":
+		manager.provider = ldap.New(config.LDAP)
 	case "noauth":
 		fallthrough
 	default:
@@ -45,9 +63,24 @@ type MemberManagerCtx struct {
 	providerMu sync.Mutex
 	provider   types.MemberProvider
 	loginMu    sync.Mutex
+	invites    map[string]*invite
+	invitesMu  sync.Mutex
+
+	totpChallenges map[string]*totpChallenge
+	totpMu         sync.Mutex
+
+	lockout *lockoutCtx
+
+	roles   map[string]types.MemberProfile
+	rolesMu sync.Mutex
 }
 
 func (manager *MemberManagerCtx) Connect() error {
+	// otherwise every admin login would fail
+	if _, ok := manager.totpProvider(); manager.config.TOTPRequireAdmin && !ok {
+		return fmt.Errorf("member.totp.require_admin cannot be used with %q provider: %w", manager.config.Provider, types.ErrMemberTOTPUnsupported)
+	}
+
 	manager.providerMu.Lock()
 	defer manager.providerMu.Unlock()
 
@@ -55,6 +88,8 @@ func (manager *MemberManagerCtx) Connect() error {
 }
 
 func (manager *MemberManagerCtx) Disconnect() error {
+	manager.stopInvites()
+
 	manager.providerMu.Lock()
 	defer manager.providerMu.Unlock()
 
@@ -100,7 +135,7 @@ func (manager *MemberManagerCtx) UpdateProfile(id string, profile types.MemberPr
 	defer manager.providerMu.Unlock()
 
 	// update corresponding session, if exists
-	err := manager.sessions.Update(id, profile)
+	err := manager.sessions.Update(id, manager.resolve(profile))
 	if err != nil && !errors.Is(err, types.ErrSessionNotFound) {
 		manager.logger.Err(err).Msg("error while updating session")
 	}
@@ -132,15 +167,95 @@ func (manager *MemberManagerCtx) Delete(id string) error {
 // member -> session
 //
 
-func (manager *MemberManagerCtx) Login(username string, password string) (types.Session, string, error) {
+func (manager *MemberManagerCtx) Login(username string, password string, ip string) (types.Session, string, error) {
 	manager.loginMu.Lock()
 	defer manager.loginMu.Unlock()
 
+	if manager.lockout.locked(username, ip) {
+		loginFailures.WithLabelValues("locked_out").Inc()
+		manager.logger.Warn().
+			Str("username", username).
+			Str("ip", ip).
+			Msg("login attempt while locked out")
+		return nil, "", types.ErrMemberLockedOut
+	}
+
+	// provider can rewrite stored password hash, so it must not race with other writes
+	manager.providerMu.Lock()
 	id, profile, err := manager.provider.Authenticate(username, password)
+	manager.providerMu.Unlock()
+
+	if errors.Is(err, types.ErrMemberDoesNotExist) || errors.Is(err, types.ErrMemberInvalidPassword) {
+		// only existing members are locked out, nonexistent usernames count towards IP address
+		lockoutUsername := username
+		if errors.Is(err, types.ErrMemberDoesNotExist) {
+			lockoutUsername = ""
+		}
+
+		failures := manager.lockout.fail(lockoutUsername, ip)
+
+		loginFailures.WithLabelValues("invalid_credentials").Inc()
+		manager.logger.Warn().Err(err).
+			Str("username", username).
+			Str("ip", ip).
+			Int("failures", failures).
+			Msg("failed login attempt")
+		return nil, "", err
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	profile = manager.resolve(profile)
+
+	// second factor is verified by LoginTOTP, failed attempts are not reset until then
+	if err := manager.totpRequired(id, username, profile); err != nil {
+		return nil, "", err
+	}
+
+	session, token, err := manager.login(id, profile)
 	if err != nil {
 		return nil, "", err
 	}
 
+	manager.lockout.success(username)
+	return session, token, nil
+}
+
+func (manager *MemberManagerCtx) OIDCAuthURL(state string, nonce string) (string, error) {
+	provider, ok := manager.provider.(types.MemberOIDCProvider)
+	if !ok {
+		return "", types.ErrMemberOIDCDisabled
+	}
+
+	return provider.AuthCodeURL(state, nonce), nil
+}
+
+func (manager *MemberManagerCtx) LoginOIDC(ctx context.Context, code string, nonce string) (types.Session, string, error) {
+	provider, ok := manager.provider.(types.MemberOIDCProvider)
+	if !ok {
+		return nil, "", types.ErrMemberOIDCDisabled
+	}
+
+	// exchange code outside of login lock, it calls identity provider
+	id, profile, err := provider.Exchange(ctx, code, nonce)
+	if err != nil {
+		return nil, "", err
+	}
+
+	profile = manager.resolve(profile)
+
+	if !profile.CanLogin {
+		return nil, "", types.ErrSessionLoginDisabled
+	}
+
+	manager.loginMu.Lock()
+	defer manager.loginMu.Unlock()
+
+	return manager.login(id, profile)
+}
+
+func (manager *MemberManagerCtx) login(id string, profile types.MemberProfile) (types.Session, string, error) {
 	session, ok := manager.sessions.Get(id)
 	if ok {
 		if session.State().IsConnected {
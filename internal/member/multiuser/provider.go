@@ -37,7 +37,7 @@ func (provider *MemberProviderCtx) Authenticate(username string, password string
 	id := fmt.Sprintf("%s-%s", username, token)
 
 	// if logged in as administrator
-	if provider.config.AdminPassword == password {
+	if utils.CheckPassword(provider.config.AdminPassword, password) {
 		profile := provider.config.AdminProfile
 		if profile.Name == "" {
 			profile.Name = username
@@ -46,7 +46,7 @@ func (provider *MemberProviderCtx) Authenticate(username string, password string
 	}
 
 	// if logged in as user
-	if provider.config.UserPassword == password {
+	if utils.CheckPassword(provider.config.UserPassword, password) {
 		profile := provider.config.UserProfile
 		if profile.Name == "" {
 			profile.Name = username
@@ -3,6 +3,7 @@ package multiuser
 import "github.com/demodesk/neko/pkg/types"
 
 type Config struct {
+	// plain text or hash created by hash command
 	AdminPassword string
 	UserPassword  string
 	AdminProfile  types.MemberProfile
@@ -2,6 +2,7 @@ package object
 
 import (
 	"github.com/demodesk/neko/pkg/types"
+	"github.com/demodesk/neko/pkg/utils"
 )
 
 func New(config Config) types.MemberProvider {
@@ -20,7 +21,8 @@ func (provider *MemberProviderCtx) Connect() error {
 	var err error
 
 	for _, entry := range provider.config.Users {
-		_, err = provider.Insert(entry.Username, entry.Password, entry.Profile)
+		// passwords from config are already hashed or plain text
+		_, err = provider.insert(entry.Username, entry.Password, entry.Profile)
 	}
 
 	return err
@@ -39,7 +41,6 @@ func (provider *MemberProviderCtx) Authenticate(username string, password string
 		return "", types.MemberProfile{}, types.ErrMemberDoesNotExist
 	}
 
-	// TODO: Use hash function.
 	if !entry.CheckPassword(password) {
 		return "", types.MemberProfile{}, types.ErrMemberInvalidPassword
 	}
@@ -48,6 +49,15 @@ func (provider *MemberProviderCtx) Authenticate(username string, password string
 }
 
 func (provider *MemberProviderCtx) Insert(username string, password string, profile types.MemberProfile) (string, error) {
+	hashedPassword, err := utils.HashPassword(utils.PasswordHashArgon2id, password)
+	if err != nil {
+		return "", err
+	}
+
+	return provider.insert(username, hashedPassword, profile)
+}
+
+func (provider *MemberProviderCtx) insert(username string, password string, profile types.MemberProfile) (string, error) {
 	// id will be also username
 	id := username
 
@@ -57,7 +67,6 @@ func (provider *MemberProviderCtx) Insert(username string, password string, prof
 	}
 
 	provider.entries[id] = &memberEntry{
-		// TODO: Use hash function.
 		password: password,
 		profile:  profile,
 	}
@@ -82,8 +91,33 @@ func (provider *MemberProviderCtx) UpdatePassword(id string, password string) er
 		return types.ErrMemberDoesNotExist
 	}
 
-	// TODO: Use hash function.
-	entry.password = password
+	hashedPassword, err := utils.HashPassword(utils.PasswordHashArgon2id, password)
+	if err != nil {
+		return err
+	}
+
+	entry.password = hashedPassword
+
+	return nil
+}
+
+// TOTP state is kept only in memory, it is lost on restart.
+func (provider *MemberProviderCtx) SelectTOTP(id string) (types.MemberTOTP, error) {
+	entry, ok := provider.entries[id]
+	if !ok {
+		return types.MemberTOTP{}, types.ErrMemberDoesNotExist
+	}
+
+	return entry.totp, nil
+}
+
+func (provider *MemberProviderCtx) UpdateTOTP(id string, totp types.MemberTOTP) error {
+	entry, ok := provider.entries[id]
+	if !ok {
+		return types.ErrMemberDoesNotExist
+	}
+
+	entry.totp = totp
 
 	return nil
 }
@@ -2,19 +2,23 @@ package object
 
 import (
 	"github.com/demodesk/neko/pkg/types"
+	"github.com/demodesk/neko/pkg/utils"
 )
 
 type memberEntry struct {
 	password string
 	profile  types.MemberProfile
+	totp     types.MemberTOTP
 }
 
+// CheckPassword compares password with stored hash, or plain text from config.
 func (m *memberEntry) CheckPassword(password string) bool {
-	return m.password == password
+	return utils.CheckPassword(m.password, password)
 }
 
 type User struct {
 	Username string
+	// plain text or hash created by hash command
 	Password string
 	Profile  types.MemberProfile
 }
@@ -0,0 +1,230 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	gooidc "github.com/coreos/go-oidc/v3/oidc"
+	"github.com/mitchellh/mapstructure"
+	"golang.org/x/oauth2"
+
+	"github.com/demodesk/neko/pkg/types"
+)
+
+var errReadOnly = errors.New("members are managed by identity provider in oidc mode")
+
+func New(config Config) *MemberProviderCtx {
+	return &MemberProviderCtx{
+		config:  config,
+		members: map[string]types.MemberProfile{},
+	}
+}
+
+type MemberProviderCtx struct {
+	config   Config
+	oauth2   oauth2.Config
+	verifier *gooidc.IDTokenVerifier
+
+	// members that logged in since start
+	members   map[string]types.MemberProfile
+	membersMu sync.Mutex
+}
+
+func (provider *MemberProviderCtx) Connect() error {
+	// retrieve endpoints and keys using discovery
+	idp, err := gooidc.NewProvider(context.Background(), provider.config.Issuer)
+	if err != nil {
+		return fmt.Errorf("unable to discover oidc provider: %w", err)
+	}
+
+	provider.verifier = idp.Verifier(&gooidc.Config{
+		ClientID: provider.config.ClientID,
+	})
+
+	provider.oauth2 = oauth2.Config{
+		ClientID:     provider.config.ClientID,
+		ClientSecret: provider.config.ClientSecret,
+		RedirectURL:  provider.config.RedirectURL,
+		Endpoint:     idp.Endpoint(),
+		Scopes:       provider.config.Scopes,
+	}
+
+	return nil
+}
+
+func (provider *MemberProviderCtx) Disconnect() error {
+	return nil
+}
+
+//
+// oidc
+//
+
+func (provider *MemberProviderCtx) AuthCodeURL(state string, nonce string) string {
+	return provider.oauth2.AuthCodeURL(state, gooidc.Nonce(nonce))
+}
+
+func (provider *MemberProviderCtx) Exchange(ctx context.Context, code string, nonce string) (string, types.MemberProfile, error) {
+	token, err := provider.oauth2.Exchange(ctx, code)
+	if err != nil {
+		return "", types.MemberProfile{}, fmt.Errorf("unable to exchange code: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return "", types.MemberProfile{}, errors.New("no id_token in token response")
+	}
+
+	// verifies signature against JWKS, issuer, audience and expiry
+	idToken, err := provider.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return "", types.MemberProfile{}, fmt.Errorf("unable to verify id_token: %w", err)
+	}
+
+	if idToken.Nonce != nonce {
+		return "", types.MemberProfile{}, errors.New("id_token nonce does not match")
+	}
+
+	claims := map[string]any{}
+	if err := idToken.Claims(&claims); err != nil {
+		return "", types.MemberProfile{}, fmt.Errorf("unable to parse id_token claims: %w", err)
+	}
+
+	id := claimString(claims, provider.config.IDClaim)
+	if id == "" {
+		return "", types.MemberProfile{}, fmt.Errorf("id_token is missing %q claim", provider.config.IDClaim)
+	}
+
+	profile, err := provider.profile(claims)
+	if err != nil {
+		return "", types.MemberProfile{}, err
+	}
+
+	if profile.Name == "" {
+		profile.Name = claimString(claims, provider.config.NameClaim)
+	}
+	if profile.Name == "" {
+		profile.Name = id
+	}
+
+	provider.membersMu.Lock()
+	provider.members[id] = profile
+	provider.membersMu.Unlock()
+
+	return id, profile, nil
+}
+
+// profile applies all matching rules on top of default profile.
+func (provider *MemberProviderCtx) profile(claims map[string]any) (types.MemberProfile, error) {
+	profile := provider.config.DefaultProfile
+
+	// rules must not modify plugins map of default profile
+	if profile.Plugins != nil {
+		plugins := make(map[string]any, len(profile.Plugins))
+		for key, value := range profile.Plugins {
+			plugins[key] = value
+		}
+		profile.Plugins = plugins
+	}
+
+	groups := []string{}
+	if values, ok := claims[provider.config.GroupsClaim].([]any); ok {
+		for _, value := range values {
+			if group, ok := value.(string); ok {
+				groups = append(groups, group)
+			}
+		}
+	}
+
+	for i, rule := range provider.config.Rules {
+		if !rule.Match(claims, groups) {
+			continue
+		}
+
+		if err := mapstructure.Decode(rule.Profile, &profile); err != nil {
+			return types.MemberProfile{}, fmt.Errorf("unable to apply rule %d: %w", i, err)
+		}
+	}
+
+	return profile, nil
+}
+
+func claimString(claims map[string]any, name string) string {
+	value, ok := claims[name].(string)
+	if !ok {
+		return ""
+	}
+	return value
+}
+
+//
+// member provider
+//
+
+func (provider *MemberProviderCtx) Authenticate(username string, password string) (string, types.MemberProfile, error) {
+	return "", types.MemberProfile{}, fmt.Errorf("%w: use oidc login", types.ErrMemberInvalidPassword)
+}
+
+func (provider *MemberProviderCtx) Insert(username string, password string, profile types.MemberProfile) (string, error) {
+	return "", errReadOnly
+}
+
+func (provider *MemberProviderCtx) UpdateProfile(id string, profile types.MemberProfile) error {
+	provider.membersMu.Lock()
+	defer provider.membersMu.Unlock()
+
+	if _, ok := provider.members[id]; !ok {
+		return types.ErrMemberDoesNotExist
+	}
+
+	// profile is valid until next login, when rules are applied again
+	provider.members[id] = profile
+	return nil
+}
+
+func (provider *MemberProviderCtx) UpdatePassword(id string, password string) error {
+	return errReadOnly
+}
+
+func (provider *MemberProviderCtx) Select(id string) (types.MemberProfile, error) {
+	provider.membersMu.Lock()
+	defer provider.membersMu.Unlock()
+
+	profile, ok := provider.members[id]
+	if !ok {
+		return types.MemberProfile{}, types.ErrMemberDoesNotExist
+	}
+
+	return profile, nil
+}
+
+func (provider *MemberProviderCtx) SelectAll(limit int, offset int) (map[string]types.MemberProfile, error) {
+	provider.membersMu.Lock()
+	defer provider.membersMu.Unlock()
+
+	profiles := make(map[string]types.MemberProfile)
+
+	i := 0
+	for id, profile := range provider.members {
+		if i >= offset && (limit == 0 || i < offset+limit) {
+			profiles[id] = profile
+		}
+		i = i + 1
+	}
+
+	return profiles, nil
+}
+
+func (provider *MemberProviderCtx) Delete(id string) error {
+	provider.membersMu.Lock()
+	defer provider.membersMu.Unlock()
+
+	if _, ok := provider.members[id]; !ok {
+		return types.ErrMemberDoesNotExist
+	}
+
+	delete(provider.members, id)
+	return nil
+}
@@ -0,0 +1,238 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+
+	"github.com/demodesk/neko/pkg/types"
+)
+
+// mockIdP is a minimal identity provider, that issues ID token with given claims for any code.
+type mockIdP struct {
+	server  *httptest.Server
+	key     *rsa.PrivateKey
+	signKey *rsa.PrivateKey
+	claims  map[string]any
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey() returned error: %s", err)
+	}
+
+	idp := &mockIdP{
+		key:     key,
+		signKey: key,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"issuer":                                idp.server.URL,
+			"authorization_endpoint":                idp.server.URL + "/auth",
+			"token_endpoint":                        idp.server.URL + "/token",
+			"jwks_uri":                              idp.server.URL + "/keys",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(jose.JSONWebKeySet{
+			Keys: []jose.JSONWebKey{{
+				Key:       &idp.key.PublicKey,
+				KeyID:     "test",
+				Algorithm: string(jose.RS256),
+				Use:       "sig",
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     idp.sign(t),
+		})
+	})
+
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+func (idp *mockIdP) sign(t *testing.T) string {
+	claims := map[string]any{
+		"iss": idp.server.URL,
+		"aud": "neko",
+		"iat": time.Now().Unix(),
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+	for key, value := range idp.claims {
+		claims[key] = value
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatalf("json.Marshal() returned error: %s", err)
+	}
+
+	signer, err := jose.NewSigner(
+		jose.SigningKey{Algorithm: jose.RS256, Key: idp.signKey},
+		(&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", "test"),
+	)
+	if err != nil {
+		t.Fatalf("jose.NewSigner() returned error: %s", err)
+	}
+
+	object, err := signer.Sign(payload)
+	if err != nil {
+		t.Fatalf("signer.Sign() returned error: %s", err)
+	}
+
+	token, err := object.CompactSerialize()
+	if err != nil {
+		t.Fatalf("object.CompactSerialize() returned error: %s", err)
+	}
+
+	return token
+}
+
+func newTestProvider(t *testing.T, idp *mockIdP) *MemberProviderCtx {
+	provider := New(Config{
+		Issuer:      idp.server.URL,
+		ClientID:    "neko",
+		RedirectURL: "http://localhost/api/login/oidc/callback",
+		Scopes:      []string{"openid"},
+		IDClaim:     "sub",
+		NameClaim:   "preferred_username",
+		GroupsClaim: "groups",
+		DefaultProfile: types.MemberProfile{
+			CanLogin: true,
+			CanWatch: true,
+		},
+		Rules: []Rule{
+			{Group: "admins", Profile: map[string]any{"is_admin": true, "can_host": true}},
+			{Claim: "email_verified", Value: "false", Profile: map[string]any{"can_login": false}},
+		},
+	})
+
+	if err := provider.Connect(); err != nil {
+		t.Fatalf("provider.Connect() returned error: %s", err)
+	}
+
+	return provider
+}
+
+func TestMemberProviderCtx_Exchange(t *testing.T) {
+	idp := newMockIdP(t)
+	provider := newTestProvider(t, idp)
+
+	tests := []struct {
+		name    string
+		claims  map[string]any
+		profile types.MemberProfile
+	}{
+		{
+			name:   "default profile",
+			claims: map[string]any{"sub": "user", "nonce": "nonce", "preferred_username": "User"},
+			profile: types.MemberProfile{
+				Name:     "User",
+				CanLogin: true,
+				CanWatch: true,
+			},
+		},
+		{
+			name:   "group rule",
+			claims: map[string]any{"sub": "admin", "nonce": "nonce", "groups": []string{"users", "admins"}},
+			profile: types.MemberProfile{
+				Name:     "admin",
+				IsAdmin:  true,
+				CanLogin: true,
+				CanWatch: true,
+				CanHost:  true,
+			},
+		},
+		{
+			name:   "claim rule",
+			claims: map[string]any{"sub": "guest", "nonce": "nonce", "email_verified": false},
+			profile: types.MemberProfile{
+				Name:     "guest",
+				CanLogin: false,
+				CanWatch: true,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp.claims = tt.claims
+
+			id, profile, err := provider.Exchange(context.Background(), "code", "nonce")
+			if err != nil {
+				t.Fatalf("provider.Exchange() returned error: %s", err)
+			}
+
+			if id != tt.claims["sub"] {
+				t.Errorf("provider.Exchange() id = %s, want %s", id, tt.claims["sub"])
+			}
+
+			gotJSON, _ := json.Marshal(profile)
+			wantJSON, _ := json.Marshal(tt.profile)
+			if string(gotJSON) != string(wantJSON) {
+				t.Errorf("provider.Exchange() profile = %s, want %s", gotJSON, wantJSON)
+			}
+
+			selected, err := provider.Select(id)
+			if err != nil {
+				t.Errorf("provider.Select() returned error: %s", err)
+			} else if selected.Name != profile.Name {
+				t.Errorf("provider.Select() name = %s, want %s", selected.Name, profile.Name)
+			}
+		})
+	}
+}
+
+func TestMemberProviderCtx_ExchangeInvalid(t *testing.T) {
+	idp := newMockIdP(t)
+	provider := newTestProvider(t, idp)
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey() returned error: %s", err)
+	}
+
+	t.Run("nonce mismatch", func(t *testing.T) {
+		idp.claims = map[string]any{"sub": "user", "nonce": "other"}
+
+		if _, _, err := provider.Exchange(context.Background(), "code", "nonce"); err == nil {
+			t.Errorf("provider.Exchange() expected error for nonce mismatch")
+		}
+	})
+
+	t.Run("invalid signature", func(t *testing.T) {
+		idp.claims = map[string]any{"sub": "user", "nonce": "nonce"}
+		idp.signKey = otherKey
+		defer func() { idp.signKey = idp.key }()
+
+		if _, _, err := provider.Exchange(context.Background(), "code", "nonce"); err == nil {
+			t.Errorf("provider.Exchange() expected error for invalid signature")
+		}
+	})
+
+	t.Run("missing id claim", func(t *testing.T) {
+		idp.claims = map[string]any{"nonce": "nonce"}
+
+		if _, _, err := provider.Exchange(context.Background(), "code", "nonce"); err == nil {
+			t.Errorf("provider.Exchange() expected error for missing id claim")
+		}
+	})
+}
@@ -0,0 +1,71 @@
+package oidc
+
+import (
+	"fmt"
+
+	"github.com/demodesk/neko/pkg/types"
+	"github.com/demodesk/neko/pkg/utils"
+)
+
+// Rule changes member profile if ID token claims match, all specified conditions must match.
+type Rule struct {
+	// member must be in the group, from groups claim
+	Group string `json:"group"   mapstructure:"group"`
+	// claim must be equal to value, or contain value if it is an array
+	Claim string `json:"claim"   mapstructure:"claim"`
+	Value string `json:"value"   mapstructure:"value"`
+	// profile fields that are set, e.g. {"is_admin": true}
+	Profile map[string]any `json:"profile" mapstructure:"profile"`
+}
+
+func (r *Rule) Match(claims map[string]any, groups []string) bool {
+	if r.Group != "" {
+		if ok, _ := utils.ArrayIn(r.Group, groups); !ok {
+			return false
+		}
+	}
+
+	if r.Claim != "" {
+		value, ok := claims[r.Claim]
+		if !ok {
+			return false
+		}
+
+		switch v := value.(type) {
+		case []any:
+			values := make([]string, 0, len(v))
+			for _, item := range v {
+				values = append(values, fmt.Sprint(item))
+			}
+			if ok, _ := utils.ArrayIn(r.Value, values); !ok {
+				return false
+			}
+		default:
+			if fmt.Sprint(v) != r.Value {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+type Config struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+
+	// claim used as member ID
+	IDClaim string
+	// claim used as member name
+	NameClaim string
+	// claim with list of groups
+	GroupsClaim string
+
+	// profile before rules are applied
+	DefaultProfile types.MemberProfile
+	// rules are applied in order, later rules override earlier ones
+	Rules []Rule
+}
@@ -0,0 +1,194 @@
+package member
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+
+	"github.com/mitchellh/mapstructure"
+
+	"github.com/demodesk/neko/pkg/types"
+)
+
+// built-in roles, that can be redefined in config or using API
+func defaultRoles() map[string]types.MemberProfile {
+	viewer := types.MemberProfile{
+		CanLogin:              true,
+		CanConnect:            true,
+		CanWatch:              true,
+		CanSeeInactiveCursors: true,
+	}
+
+	participant := viewer
+	participant.CanHost = true
+	participant.CanAccessClipboard = true
+	participant.SendsInactiveCursor = true
+
+	presenter := participant
+	presenter.CanShareMedia = true
+
+	admin := presenter
+	admin.IsAdmin = true
+
+	return map[string]types.MemberProfile{
+		"viewer":      viewer,
+		"participant": participant,
+		"presenter":   presenter,
+		"admin":       admin,
+	}
+}
+
+// loadRoles merges built-in roles with roles from config and roles file.
+func (manager *MemberManagerCtx) loadRoles() {
+	roles := defaultRoles()
+	for name, profile := range manager.config.Roles {
+		roles[name] = roleTemplate(profile)
+	}
+
+	if manager.config.RolesFile != "" {
+		data, err := os.ReadFile(manager.config.RolesFile)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			manager.logger.Error().Err(err).
+				Str("file", manager.config.RolesFile).
+				Msg("failed to read roles from a file")
+		}
+
+		if len(data) > 0 {
+			var fileRoles map[string]types.MemberProfile
+			if err := json.Unmarshal(data, &fileRoles); err != nil {
+				manager.logger.Error().Err(err).Msg("failed to unmarshal roles")
+			}
+
+			for name, profile := range fileRoles {
+				roles[name] = roleTemplate(profile)
+			}
+		}
+	}
+
+	manager.roles = roles
+}
+
+func (manager *MemberManagerCtx) saveRoles() {
+	if manager.config.RolesFile == "" {
+		return
+	}
+
+	data, err := json.Marshal(manager.roles)
+	if err != nil {
+		manager.logger.Error().Err(err).Msg("failed to marshal roles")
+		return
+	}
+
+	if err := os.WriteFile(manager.config.RolesFile, data, 0644); err != nil {
+		manager.logger.Error().Err(err).
+			Str("file", manager.config.RolesFile).
+			Msg("failed to write roles to a file")
+	}
+}
+
+// roleTemplate removes fields, that do not make sense in role.
+func roleTemplate(profile types.MemberProfile) types.MemberProfile {
+	profile.Name = ""
+	profile.Role = ""
+	profile.Overrides = nil
+	return profile
+}
+
+// resolve returns effective profile, when it references a role.
+func (manager *MemberManagerCtx) resolve(profile types.MemberProfile) types.MemberProfile {
+	if profile.Role == "" {
+		return profile
+	}
+
+	manager.rolesMu.Lock()
+	role, ok := manager.roles[profile.Role]
+	manager.rolesMu.Unlock()
+
+	// unknown role does not grant any permissions
+	if !ok {
+		manager.logger.Warn().Str("role", profile.Role).Msg("profile references unknown role")
+		role = types.MemberProfile{}
+	}
+
+	resolved := role
+	resolved.Name = profile.Name
+	resolved.Role = profile.Role
+	resolved.Overrides = profile.Overrides
+
+	// member plugin settings take precedence over role
+	resolved.Plugins = make(map[string]any, len(role.Plugins)+len(profile.Plugins))
+	for key, value := range role.Plugins {
+		resolved.Plugins[key] = value
+	}
+	for key, value := range profile.Plugins {
+		resolved.Plugins[key] = value
+	}
+
+	if len(profile.Overrides) > 0 {
+		if err := mapstructure.Decode(profile.Overrides, &resolved); err != nil {
+			manager.logger.Warn().Err(err).Str("role", profile.Role).Msg("unable to apply profile overrides")
+		}
+
+		// overrides cannot change role itself
+		resolved.Role = profile.Role
+		resolved.Overrides = profile.Overrides
+	}
+
+	return resolved
+}
+
+func (manager *MemberManagerCtx) ListRoles() map[string]types.MemberProfile {
+	manager.rolesMu.Lock()
+	defer manager.rolesMu.Unlock()
+
+	roles := make(map[string]types.MemberProfile, len(manager.roles))
+	for name, profile := range manager.roles {
+		roles[name] = profile
+	}
+
+	return roles
+}
+
+func (manager *MemberManagerCtx) UpdateRole(name string, profile types.MemberProfile) error {
+	if name == "" {
+		return errors.New("role name cannot be empty")
+	}
+
+	manager.rolesMu.Lock()
+	manager.roles[name] = roleTemplate(profile)
+	manager.saveRoles()
+	manager.rolesMu.Unlock()
+
+	manager.roleChanged(name)
+	return nil
+}
+
+func (manager *MemberManagerCtx) DeleteRole(name string) error {
+	manager.rolesMu.Lock()
+	if _, ok := manager.roles[name]; !ok {
+		manager.rolesMu.Unlock()
+		return types.ErrMemberRoleNotFound
+	}
+
+	delete(manager.roles, name)
+	manager.saveRoles()
+	manager.rolesMu.Unlock()
+
+	manager.roleChanged(name)
+	return nil
+}
+
+// roleChanged updates profiles of all active sessions, that reference the role.
+func (manager *MemberManagerCtx) roleChanged(name string) {
+	for _, session := range manager.sessions.List() {
+		profile := session.Profile()
+		if profile.Role != name {
+			continue
+		}
+
+		err := manager.sessions.Update(session.ID(), manager.resolve(profile))
+		if err != nil && !errors.Is(err, types.ErrSessionNotFound) {
+			manager.logger.Err(err).Str("session_id", session.ID()).Msg("error while updating session role")
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package member
+
+import (
+	"testing"
+
+	"github.com/demodesk/neko/internal/config"
+	"github.com/demodesk/neko/internal/member/object"
+	"github.com/demodesk/neko/internal/session"
+	"github.com/demodesk/neko/pkg/types"
+)
+
+func TestMemberManagerCtx_resolve(t *testing.T) {
+	manager := New(session.New(&config.Session{}), &config.Member{
+		Provider: "object",
+		Roles: map[string]types.MemberProfile{
+			"viewer": {CanLogin: true, CanWatch: true},
+		},
+	})
+
+	profile := manager.resolve(types.MemberProfile{
+		Name:      "alice",
+		Role:      "viewer",
+		Overrides: map[string]any{"can_host": true},
+	})
+	if !profile.CanLogin || !profile.CanWatch || !profile.CanHost || profile.CanConnect || profile.Name != "alice" {
+		t.Errorf("manager.resolve() = %+v", profile)
+	}
+
+	profile = manager.resolve(types.MemberProfile{Role: "unknown", CanLogin: true})
+	if profile.CanLogin {
+		t.Errorf("manager.resolve() expected unknown role to grant no permissions")
+	}
+}
+
+func TestMemberManagerCtx_UpdateRole(t *testing.T) {
+	sessions := session.New(&config.Session{})
+	manager := New(sessions, &config.Member{
+		Provider: "object",
+		Object: object.Config{
+			Users: []object.User{
+				{Username: "alice", Password: "secret", Profile: types.MemberProfile{Role: "participant"}},
+			},
+		},
+	})
+
+	if err := manager.Connect(); err != nil {
+		t.Fatalf("manager.Connect() returned error: %s", err)
+	}
+
+	session, _, err := manager.Login("alice", "secret", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("manager.Login() returned error: %s", err)
+	}
+	if !session.Profile().CanHost {
+		t.Errorf("session.Profile() expected participant to be able to host")
+	}
+
+	err = manager.UpdateRole("participant", types.MemberProfile{CanLogin: true, CanConnect: true, CanWatch: true})
+	if err != nil {
+		t.Fatalf("manager.UpdateRole() returned error: %s", err)
+	}
+
+	if profile := session.Profile(); profile.CanHost || !profile.CanWatch || profile.Role != "participant" {
+		t.Errorf("session.Profile() = %+v, expected updated role", profile)
+	}
+}
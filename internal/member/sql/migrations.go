@@ -0,0 +1,62 @@
+package sql
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// migrations are applied in order and must never be changed once released,
+// every statement must work on both sqlite3 and postgres.
+var migrations = []string{
+	// 1: members table, username is also member ID
+	`CREATE TABLE members (
+		username   VARCHAR(255) NOT NULL PRIMARY KEY,
+		password   TEXT         NOT NULL,
+		profile    TEXT         NOT NULL,
+		created_at TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`,
+	// 2: second factor state in JSON, empty if not enrolled
+	`ALTER TABLE members ADD COLUMN totp TEXT NOT NULL DEFAULT ''`,
+}
+
+func migrate(db *sql.DB) error {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS members_migrations (
+		version    INTEGER   NOT NULL PRIMARY KEY,
+		applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`); err != nil {
+		return fmt.Errorf("unable to create migrations table: %w", err)
+	}
+
+	var version int
+	if err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM members_migrations`).Scan(&version); err != nil {
+		return fmt.Errorf("unable to get schema version: %w", err)
+	}
+
+	if version > len(migrations) {
+		return fmt.Errorf("schema version %d is newer than supported %d", version, len(migrations))
+	}
+
+	for i := version; i < len(migrations); i++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(migrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("unable to apply migration %d: %w", i+1, err)
+		}
+
+		if _, err := tx.Exec(`INSERT INTO members_migrations (version) VALUES ($1)`, i+1); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("unable to record migration %d: %w", i+1, err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("unable to commit migration %d: %w", i+1, err)
+		}
+	}
+
+	return nil
+}
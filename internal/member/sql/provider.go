@@ -0,0 +1,285 @@
+package sql
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	// database drivers
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/demodesk/neko/pkg/types"
+	"github.com/demodesk/neko/pkg/utils"
+)
+
+func New(config Config) types.MemberProvider {
+	return &MemberProviderCtx{
+		logger: log.With().Str("module", "member").Str("submodule", "sql").Logger(),
+		config: config,
+	}
+}
+
+type MemberProviderCtx struct {
+	logger zerolog.Logger
+	config Config
+	db     *sql.DB
+}
+
+func (provider *MemberProviderCtx) hash(password string) (string, error) {
+	// if hash is disabled, return password as plain text
+	if !provider.config.Hash {
+		return password, nil
+	}
+
+	return utils.HashPassword(utils.PasswordHashArgon2id, password)
+}
+
+func (provider *MemberProviderCtx) Connect() error {
+	db, err := sql.Open(provider.config.Driver, provider.config.DSN)
+	if err != nil {
+		return fmt.Errorf("unable to open database: %w", err)
+	}
+
+	if provider.config.Driver == "sqlite3" {
+		// sqlite allows single writer, serialize access instead of failing on busy database
+		db.SetMaxOpenConns(1)
+
+		// allow reading from other processes while writing
+		if _, err := db.Exec(`PRAGMA journal_mode=WAL`); err != nil {
+			db.Close()
+			return fmt.Errorf("unable to enable wal mode: %w", err)
+		}
+	}
+
+	if err := migrate(db); err != nil {
+		db.Close()
+		return err
+	}
+
+	provider.db = db
+	return nil
+}
+
+func (provider *MemberProviderCtx) Disconnect() error {
+	if provider.db == nil {
+		return nil
+	}
+
+	err := provider.db.Close()
+	provider.db = nil
+	return err
+}
+
+func (provider *MemberProviderCtx) Authenticate(username string, password string) (string, types.MemberProfile, error) {
+	var hashedPassword, rawProfile string
+	err := provider.db.QueryRow(
+		`SELECT password, profile FROM members WHERE username = $1`,
+		username,
+	).Scan(&hashedPassword, &rawProfile)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", types.MemberProfile{}, types.ErrMemberDoesNotExist
+	}
+	if err != nil {
+		return "", types.MemberProfile{}, err
+	}
+
+	ok, rehash := utils.VerifyStoredPassword(hashedPassword, password, provider.config.Hash)
+	if !ok {
+		return "", types.MemberProfile{}, types.ErrMemberInvalidPassword
+	}
+
+	var profile types.MemberProfile
+	if err := json.Unmarshal([]byte(rawProfile), &profile); err != nil {
+		return "", types.MemberProfile{}, err
+	}
+
+	// replace legacy hash, now that we know the password
+	if rehash {
+		if err := provider.UpdatePassword(username, password); err != nil {
+			provider.logger.Warn().Err(err).Str("id", username).Msg("unable to rehash password")
+		}
+	}
+
+	// id will be also username
+	return username, profile, nil
+}
+
+func (provider *MemberProviderCtx) Insert(username string, password string, profile types.MemberProfile) (string, error) {
+	rawProfile, err := json.Marshal(profile)
+	if err != nil {
+		return "", err
+	}
+
+	hashedPassword, err := provider.hash(password)
+	if err != nil {
+		return "", err
+	}
+
+	res, err := provider.db.Exec(
+		`INSERT INTO members (username, password, profile) VALUES ($1, $2, $3) ON CONFLICT (username) DO NOTHING`,
+		username, hashedPassword, string(rawProfile),
+	)
+	if err != nil {
+		return "", err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return "", err
+	}
+
+	if affected == 0 {
+		return "", types.ErrMemberAlreadyExists
+	}
+
+	// id will be also username
+	return username, nil
+}
+
+func (provider *MemberProviderCtx) UpdateProfile(id string, profile types.MemberProfile) error {
+	rawProfile, err := json.Marshal(profile)
+	if err != nil {
+		return err
+	}
+
+	return provider.update(
+		`UPDATE members SET profile = $1, updated_at = CURRENT_TIMESTAMP WHERE username = $2`,
+		string(rawProfile), id,
+	)
+}
+
+func (provider *MemberProviderCtx) UpdatePassword(id string, password string) error {
+	hashedPassword, err := provider.hash(password)
+	if err != nil {
+		return err
+	}
+
+	return provider.update(
+		`UPDATE members SET password = $1, updated_at = CURRENT_TIMESTAMP WHERE username = $2`,
+		hashedPassword, id,
+	)
+}
+
+func (provider *MemberProviderCtx) SelectTOTP(id string) (types.MemberTOTP, error) {
+	var rawTOTP string
+	err := provider.db.QueryRow(
+		`SELECT totp FROM members WHERE username = $1`,
+		id,
+	).Scan(&rawTOTP)
+	if errors.Is(err, sql.ErrNoRows) {
+		return types.MemberTOTP{}, types.ErrMemberDoesNotExist
+	}
+	if err != nil {
+		return types.MemberTOTP{}, err
+	}
+
+	var totp types.MemberTOTP
+	if rawTOTP == "" {
+		return totp, nil
+	}
+
+	err = json.Unmarshal([]byte(rawTOTP), &totp)
+	return totp, err
+}
+
+func (provider *MemberProviderCtx) UpdateTOTP(id string, totp types.MemberTOTP) error {
+	// do not store empty state
+	var rawTOTP []byte
+	if totp.Secret != "" {
+		var err error
+		rawTOTP, err = json.Marshal(totp)
+		if err != nil {
+			return err
+		}
+	}
+
+	return provider.update(
+		`UPDATE members SET totp = $1, updated_at = CURRENT_TIMESTAMP WHERE username = $2`,
+		string(rawTOTP), id,
+	)
+}
+
+func (provider *MemberProviderCtx) Select(id string) (types.MemberProfile, error) {
+	var rawProfile string
+	err := provider.db.QueryRow(
+		`SELECT profile FROM members WHERE username = $1`,
+		id,
+	).Scan(&rawProfile)
+	if errors.Is(err, sql.ErrNoRows) {
+		return types.MemberProfile{}, types.ErrMemberDoesNotExist
+	}
+	if err != nil {
+		return types.MemberProfile{}, err
+	}
+
+	var profile types.MemberProfile
+	err = json.Unmarshal([]byte(rawProfile), &profile)
+	return profile, err
+}
+
+func (provider *MemberProviderCtx) SelectAll(limit int, offset int) (map[string]types.MemberProfile, error) {
+	profiles := map[string]types.MemberProfile{}
+
+	// zero limit means no limit, sqlite uses negative limit and postgres null
+	var limitArg any = limit
+	if limit <= 0 {
+		if provider.config.Driver == "sqlite3" {
+			limitArg = -1
+		} else {
+			limitArg = nil
+		}
+	}
+
+	rows, err := provider.db.Query(
+		`SELECT username, profile FROM members ORDER BY username LIMIT $1 OFFSET $2`,
+		limitArg, offset,
+	)
+	if err != nil {
+		return profiles, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, rawProfile string
+		if err := rows.Scan(&id, &rawProfile); err != nil {
+			return profiles, err
+		}
+
+		var profile types.MemberProfile
+		if err := json.Unmarshal([]byte(rawProfile), &profile); err != nil {
+			return profiles, fmt.Errorf("unable to parse profile of %q: %w", id, err)
+		}
+
+		profiles[id] = profile
+	}
+
+	return profiles, rows.Err()
+}
+
+func (provider *MemberProviderCtx) Delete(id string) error {
+	return provider.update(`DELETE FROM members WHERE username = $1`, id)
+}
+
+// update executes statement, that must affect exactly one member.
+func (provider *MemberProviderCtx) update(query string, args ...any) error {
+	res, err := provider.db.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return types.ErrMemberDoesNotExist
+	}
+
+	return nil
+}
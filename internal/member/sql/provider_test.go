@@ -0,0 +1,233 @@
+package sql
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/demodesk/neko/pkg/types"
+	"github.com/demodesk/neko/pkg/utils"
+)
+
+func newTestProvider(t *testing.T) *MemberProviderCtx {
+	provider := &MemberProviderCtx{
+		config: Config{
+			Driver: "sqlite3",
+			DSN:    filepath.Join(t.TempDir(), "members.db"),
+			Hash:   true,
+		},
+	}
+
+	if err := provider.Connect(); err != nil {
+		t.Fatalf("provider.Connect() returned error: %s", err)
+	}
+
+	t.Cleanup(func() { provider.Disconnect() })
+	return provider
+}
+
+func TestMemberProviderCtx_migrate(t *testing.T) {
+	provider := newTestProvider(t)
+
+	// migrations must be skipped when already applied
+	if err := migrate(provider.db); err != nil {
+		t.Fatalf("migrate() returned error: %s", err)
+	}
+
+	var version int
+	if err := provider.db.QueryRow(`SELECT MAX(version) FROM members_migrations`).Scan(&version); err != nil {
+		t.Fatalf("unable to get schema version: %s", err)
+	}
+
+	if version != len(migrations) {
+		t.Errorf("schema version = %d, want %d", version, len(migrations))
+	}
+}
+
+func TestMemberProviderCtx_members(t *testing.T) {
+	provider := newTestProvider(t)
+
+	profile := types.MemberProfile{
+		Name:     "Alice",
+		CanLogin: true,
+		Plugins: map[string]any{
+			"chat": map[string]any{"enabled": true},
+		},
+	}
+
+	id, err := provider.Insert("alice", "secret", profile)
+	if err != nil {
+		t.Fatalf("provider.Insert() returned error: %s", err)
+	}
+
+	if _, err := provider.Insert("alice", "other", profile); !errors.Is(err, types.ErrMemberAlreadyExists) {
+		t.Errorf("provider.Insert() error = %v, want %v", err, types.ErrMemberAlreadyExists)
+	}
+
+	authID, authProfile, err := provider.Authenticate("alice", "secret")
+	if err != nil {
+		t.Fatalf("provider.Authenticate() returned error: %s", err)
+	}
+	if authID != id || authProfile.Name != "Alice" {
+		t.Errorf("provider.Authenticate() = %s, %+v", authID, authProfile)
+	}
+
+	chat, ok := authProfile.Plugins["chat"].(map[string]any)
+	if !ok || chat["enabled"] != true {
+		t.Errorf("provider.Authenticate() plugins = %v, want chat enabled", authProfile.Plugins)
+	}
+
+	if _, _, err := provider.Authenticate("alice", "wrong"); !errors.Is(err, types.ErrMemberInvalidPassword) {
+		t.Errorf("provider.Authenticate() error = %v, want %v", err, types.ErrMemberInvalidPassword)
+	}
+
+	if _, _, err := provider.Authenticate("bob", "secret"); !errors.Is(err, types.ErrMemberDoesNotExist) {
+		t.Errorf("provider.Authenticate() error = %v, want %v", err, types.ErrMemberDoesNotExist)
+	}
+
+	profile.IsAdmin = true
+	if err := provider.UpdateProfile(id, profile); err != nil {
+		t.Fatalf("provider.UpdateProfile() returned error: %s", err)
+	}
+
+	selected, err := provider.Select(id)
+	if err != nil {
+		t.Fatalf("provider.Select() returned error: %s", err)
+	}
+	if !selected.IsAdmin {
+		t.Errorf("provider.Select() expected updated profile")
+	}
+
+	if err := provider.UpdatePassword(id, "new"); err != nil {
+		t.Fatalf("provider.UpdatePassword() returned error: %s", err)
+	}
+	if _, _, err := provider.Authenticate("alice", "new"); err != nil {
+		t.Errorf("provider.Authenticate() returned error after password update: %s", err)
+	}
+
+	if err := provider.Delete(id); err != nil {
+		t.Fatalf("provider.Delete() returned error: %s", err)
+	}
+
+	if err := provider.Delete(id); !errors.Is(err, types.ErrMemberDoesNotExist) {
+		t.Errorf("provider.Delete() error = %v, want %v", err, types.ErrMemberDoesNotExist)
+	}
+
+	if err := provider.UpdateProfile(id, profile); !errors.Is(err, types.ErrMemberDoesNotExist) {
+		t.Errorf("provider.UpdateProfile() error = %v, want %v", err, types.ErrMemberDoesNotExist)
+	}
+}
+
+// Ensure that legacy sha256 hashes are accepted and replaced on successful login
+func TestMemberProviderCtx_rehash(t *testing.T) {
+	provider := newTestProvider(t)
+
+	_, err := provider.db.Exec(
+		`INSERT INTO members (username, password, profile) VALUES ($1, $2, $3), ($4, $5, $6)`,
+		"alice", utils.LegacyPasswordHash("secret"), "{}",
+		"bob", "secret", "{}",
+	)
+	if err != nil {
+		t.Fatalf("unable to insert legacy members: %s", err)
+	}
+
+	if _, _, err := provider.Authenticate("alice", "wrong"); !errors.Is(err, types.ErrMemberInvalidPassword) {
+		t.Errorf("provider.Authenticate() error = %v, want %v", err, types.ErrMemberInvalidPassword)
+	}
+
+	if _, _, err := provider.Authenticate("alice", "secret"); err != nil {
+		t.Fatalf("provider.Authenticate() returned error: %s", err)
+	}
+
+	var stored string
+	if err := provider.db.QueryRow(`SELECT password FROM members WHERE username = $1`, "alice").Scan(&stored); err != nil {
+		t.Fatalf("unable to select password: %s", err)
+	}
+
+	if !strings.HasPrefix(stored, "$argon2id$") {
+		t.Errorf("password was not rehashed: %s", stored)
+	}
+
+	if _, _, err := provider.Authenticate("alice", "secret"); err != nil {
+		t.Errorf("provider.Authenticate() returned error after rehash: %s", err)
+	}
+
+	// plain text is not compared, when hash is enabled
+	if _, _, err := provider.Authenticate("bob", "secret"); !errors.Is(err, types.ErrMemberInvalidPassword) {
+		t.Errorf("provider.Authenticate() error = %v, want %v", err, types.ErrMemberInvalidPassword)
+	}
+}
+
+func TestMemberProviderCtx_SelectAll(t *testing.T) {
+	provider := newTestProvider(t)
+
+	for i := 0; i < 10; i++ {
+		if _, err := provider.Insert(fmt.Sprintf("member%d", i), "secret", types.MemberProfile{}); err != nil {
+			t.Fatalf("provider.Insert() returned error: %s", err)
+		}
+	}
+
+	profiles, err := provider.SelectAll(0, 0)
+	if err != nil {
+		t.Fatalf("provider.SelectAll() returned error: %s", err)
+	}
+	if len(profiles) != 10 {
+		t.Errorf("provider.SelectAll() returned %d members, want 10", len(profiles))
+	}
+
+	profiles, err = provider.SelectAll(3, 4)
+	if err != nil {
+		t.Fatalf("provider.SelectAll() returned error: %s", err)
+	}
+	if len(profiles) != 3 {
+		t.Errorf("provider.SelectAll() returned %d members, want 3", len(profiles))
+	}
+	for _, id := range []string{"member4", "member5", "member6"} {
+		if _, ok := profiles[id]; !ok {
+			t.Errorf("provider.SelectAll() is missing %s", id)
+		}
+	}
+
+	profiles, err = provider.SelectAll(0, 8)
+	if err != nil {
+		t.Fatalf("provider.SelectAll() returned error: %s", err)
+	}
+	if len(profiles) != 2 {
+		t.Errorf("provider.SelectAll() returned %d members, want 2", len(profiles))
+	}
+}
+
+func TestMemberProviderCtx_TOTP(t *testing.T) {
+	provider := newTestProvider(t)
+
+	if _, err := provider.Insert("alice", "secret", types.MemberProfile{}); err != nil {
+		t.Fatalf("provider.Insert() returned error: %s", err)
+	}
+
+	totp, err := provider.SelectTOTP("alice")
+	if err != nil {
+		t.Fatalf("provider.SelectTOTP() returned error: %s", err)
+	}
+	if totp.Secret != "" || totp.Enabled {
+		t.Errorf("provider.SelectTOTP() = %+v, want empty", totp)
+	}
+
+	totp = types.MemberTOTP{Secret: "ABC", Enabled: true, RecoveryCodes: []string{"x"}, LastCounter: 42}
+	if err := provider.UpdateTOTP("alice", totp); err != nil {
+		t.Fatalf("provider.UpdateTOTP() returned error: %s", err)
+	}
+
+	selected, err := provider.SelectTOTP("alice")
+	if err != nil {
+		t.Fatalf("provider.SelectTOTP() returned error: %s", err)
+	}
+	if selected.Secret != "ABC" || !selected.Enabled || selected.LastCounter != 42 || len(selected.RecoveryCodes) != 1 {
+		t.Errorf("provider.SelectTOTP() = %+v, want %+v", selected, totp)
+	}
+
+	if err := provider.UpdateTOTP("bob", totp); !errors.Is(err, types.ErrMemberDoesNotExist) {
+		t.Errorf("provider.UpdateTOTP() error = %v, want %v", err, types.ErrMemberDoesNotExist)
+	}
+}
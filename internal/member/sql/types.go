@@ -0,0 +1,9 @@
+package sql
+
+type Config struct {
+	// database/sql driver name, sqlite3 or postgres
+	Driver string
+	// data source name, file path for sqlite3 or connection string for postgres
+	DSN  string
+	Hash bool
+}
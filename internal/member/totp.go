@@ -0,0 +1,348 @@
+package member
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/demodesk/neko/pkg/types"
+	"github.com/demodesk/neko/pkg/utils"
+)
+
+const (
+	totpChallengeTimeout  = 5 * time.Minute
+	totpChallengeAttempts = 5
+	totpRecoveryCodes     = 10
+)
+
+type totpChallenge struct {
+	id        string
+	username  string
+	profile   types.MemberProfile
+	enroll    bool
+	expiresAt time.Time
+	attempts  int
+}
+
+func (manager *MemberManagerCtx) totpProvider() (types.MemberTOTPProvider, bool) {
+	provider, ok := manager.provider.(types.MemberTOTPProvider)
+	return provider, ok
+}
+
+// totpRequired returns challenge error, if member must provide second factor to finish login.
+func (manager *MemberManagerCtx) totpRequired(id string, username string, profile types.MemberProfile) error {
+	required := manager.config.TOTPRequireAdmin && profile.IsAdmin
+
+	provider, ok := manager.totpProvider()
+	if !ok {
+		if required {
+			return types.ErrMemberTOTPUnsupported
+		}
+		return nil
+	}
+
+	manager.providerMu.Lock()
+	totp, err := provider.SelectTOTP(id)
+	manager.providerMu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if !totp.Enabled && !required {
+		return nil
+	}
+
+	token, err := utils.NewUID(32)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	manager.totpMu.Lock()
+	defer manager.totpMu.Unlock()
+
+	// remove stale challenges
+	for key, challenge := range manager.totpChallenges {
+		if now.After(challenge.expiresAt) {
+			delete(manager.totpChallenges, key)
+		}
+	}
+
+	manager.totpChallenges[token] = &totpChallenge{
+		id:        id,
+		username:  username,
+		profile:   profile,
+		enroll:    !totp.Enabled,
+		expiresAt: now.Add(totpChallengeTimeout),
+	}
+
+	return &types.MemberTOTPChallenge{
+		Token:  token,
+		Enroll: !totp.Enabled,
+	}
+}
+
+func (manager *MemberManagerCtx) totpChallenge(token string) (*totpChallenge, bool) {
+	manager.totpMu.Lock()
+	defer manager.totpMu.Unlock()
+
+	challenge, ok := manager.totpChallenges[token]
+	if !ok {
+		return nil, false
+	}
+
+	if time.Now().After(challenge.expiresAt) || challenge.attempts >= totpChallengeAttempts {
+		delete(manager.totpChallenges, token)
+		return nil, false
+	}
+
+	return challenge, true
+}
+
+func (manager *MemberManagerCtx) LoginTOTP(token string, code string, ip string) (types.Session, string, []string, error) {
+	manager.loginMu.Lock()
+	defer manager.loginMu.Unlock()
+
+	challenge, ok := manager.totpChallenge(token)
+	if !ok {
+		return nil, "", nil, types.ErrMemberTOTPChallengeInvalid
+	}
+
+	if manager.lockout.locked(challenge.username, ip) {
+		loginFailures.WithLabelValues("locked_out").Inc()
+		manager.logger.Warn().
+			Str("username", challenge.username).
+			Str("ip", ip).
+			Msg("second factor attempt while locked out")
+		return nil, "", nil, types.ErrMemberLockedOut
+	}
+
+	var recoveryCodes []string
+	var err error
+	if challenge.enroll {
+		recoveryCodes, err = manager.TOTPConfirm(challenge.id, code)
+	} else {
+		err = manager.totpVerify(challenge.id, code)
+	}
+
+	manager.totpMu.Lock()
+	if err != nil {
+		challenge.attempts++
+	} else {
+		delete(manager.totpChallenges, token)
+	}
+	manager.totpMu.Unlock()
+
+	// challenge allows only few attempts, but new one can be requested by another login
+	if errors.Is(err, types.ErrMemberTOTPInvalidCode) {
+		failures := manager.lockout.fail(challenge.username, ip)
+
+		loginFailures.WithLabelValues("invalid_totp").Inc()
+		manager.logger.Warn().Err(err).
+			Str("username", challenge.username).
+			Str("ip", ip).
+			Int("failures", failures).
+			Msg("failed second factor attempt")
+	}
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	session, sessionToken, err := manager.login(challenge.id, challenge.profile)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	manager.lockout.success(challenge.username)
+	return session, sessionToken, recoveryCodes, nil
+}
+
+func (manager *MemberManagerCtx) LoginTOTPEnroll(token string) (string, string, error) {
+	challenge, ok := manager.totpChallenge(token)
+	if !ok || !challenge.enroll {
+		return "", "", types.ErrMemberTOTPChallengeInvalid
+	}
+
+	return manager.TOTPEnroll(challenge.id)
+}
+
+// TOTPEnroll generates new secret, that becomes active after it is confirmed with a valid code.
+func (manager *MemberManagerCtx) TOTPEnroll(id string) (string, string, error) {
+	provider, ok := manager.totpProvider()
+	if !ok {
+		return "", "", types.ErrMemberTOTPUnsupported
+	}
+
+	manager.providerMu.Lock()
+	defer manager.providerMu.Unlock()
+
+	totp, err := provider.SelectTOTP(id)
+	if err != nil {
+		return "", "", err
+	}
+
+	// must be disabled first, so that stolen session cannot replace second factor
+	if totp.Enabled {
+		return "", "", types.ErrMemberTOTPEnabled
+	}
+
+	secret, err := utils.NewTOTPSecret()
+	if err != nil {
+		return "", "", err
+	}
+
+	err = provider.UpdateTOTP(id, types.MemberTOTP{
+		Secret: secret,
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	return secret, utils.TOTPURL(manager.config.TOTPIssuer, id, secret), nil
+}
+
+// TOTPConfirm enables enrolled secret and returns recovery codes, that are shown only once.
+func (manager *MemberManagerCtx) TOTPConfirm(id string, code string) ([]string, error) {
+	provider, ok := manager.totpProvider()
+	if !ok {
+		return nil, types.ErrMemberTOTPUnsupported
+	}
+
+	manager.providerMu.Lock()
+	defer manager.providerMu.Unlock()
+
+	totp, err := provider.SelectTOTP(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if totp.Enabled {
+		return nil, types.ErrMemberTOTPEnabled
+	}
+
+	if totp.Secret == "" {
+		return nil, types.ErrMemberTOTPNotEnrolled
+	}
+
+	counter, ok := utils.VerifyTOTP(totp.Secret, code, time.Now())
+	if !ok {
+		return nil, types.ErrMemberTOTPInvalidCode
+	}
+
+	recoveryCodes := make([]string, totpRecoveryCodes)
+	totp.RecoveryCodes = make([]string, totpRecoveryCodes)
+	for i := range recoveryCodes {
+		recoveryCodes[i], err = newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		totp.RecoveryCodes[i] = hashRecoveryCode(recoveryCodes[i])
+	}
+
+	totp.Enabled = true
+	totp.LastCounter = counter
+	if err := provider.UpdateTOTP(id, totp); err != nil {
+		return nil, err
+	}
+
+	return recoveryCodes, nil
+}
+
+// TOTPDisable removes second factor, current code or recovery code is required.
+func (manager *MemberManagerCtx) TOTPDisable(id string, code string) error {
+	if manager.config.TOTPRequireAdmin {
+		profile, err := manager.Select(id)
+		if err != nil {
+			return err
+		}
+		profile = manager.resolve(profile)
+
+		// admins cannot opt out, when it is required
+		if profile.IsAdmin {
+			return types.ErrMemberTOTPRequired
+		}
+	}
+
+	if err := manager.totpVerify(id, code); err != nil {
+		return err
+	}
+
+	return manager.TOTPReset(id)
+}
+
+// TOTPReset removes second factor without verification, e.g. when member lost the device.
+func (manager *MemberManagerCtx) TOTPReset(id string) error {
+	provider, ok := manager.totpProvider()
+	if !ok {
+		return types.ErrMemberTOTPUnsupported
+	}
+
+	manager.providerMu.Lock()
+	defer manager.providerMu.Unlock()
+
+	return provider.UpdateTOTP(id, types.MemberTOTP{})
+}
+
+// totpVerify checks TOTP code or recovery code, used recovery code is removed.
+func (manager *MemberManagerCtx) totpVerify(id string, code string) error {
+	provider, ok := manager.totpProvider()
+	if !ok {
+		return types.ErrMemberTOTPUnsupported
+	}
+
+	manager.providerMu.Lock()
+	defer manager.providerMu.Unlock()
+
+	totp, err := provider.SelectTOTP(id)
+	if err != nil {
+		return err
+	}
+
+	if !totp.Enabled {
+		return types.ErrMemberTOTPNotEnrolled
+	}
+
+	if counter, ok := utils.VerifyTOTP(totp.Secret, code, time.Now()); ok {
+		// code must not be used twice
+		if counter <= totp.LastCounter {
+			return types.ErrMemberTOTPInvalidCode
+		}
+
+		totp.LastCounter = counter
+		return provider.UpdateTOTP(id, totp)
+	}
+
+	hash := hashRecoveryCode(code)
+	for i, recoveryCode := range totp.RecoveryCodes {
+		if subtle.ConstantTimeCompare([]byte(recoveryCode), []byte(hash)) == 1 {
+			totp.RecoveryCodes = append(totp.RecoveryCodes[:i], totp.RecoveryCodes[i+1:]...)
+			return provider.UpdateTOTP(id, totp)
+		}
+	}
+
+	return types.ErrMemberTOTPInvalidCode
+}
+
+// newRecoveryCode returns random code in xxxxx-xxxxx format.
+func newRecoveryCode() (string, error) {
+	buf := make([]byte, 10)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	code := strings.ToLower(base32.StdEncoding.EncodeToString(buf))
+	return code[:5] + "-" + code[5:10], nil
+}
+
+// recovery codes have enough entropy, so that unsalted hash is sufficient.
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
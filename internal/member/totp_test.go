@@ -0,0 +1,164 @@
+package member
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/demodesk/neko/internal/config"
+	"github.com/demodesk/neko/internal/member/file"
+	"github.com/demodesk/neko/internal/session"
+	"github.com/demodesk/neko/pkg/types"
+	"github.com/demodesk/neko/pkg/utils"
+)
+
+func newTOTPTestManager(t *testing.T, requireAdmin bool) *MemberManagerCtx {
+	manager := New(session.New(&config.Session{}), &config.Member{
+		Provider:         "file",
+		TOTPIssuer:       "neko",
+		TOTPRequireAdmin: requireAdmin,
+		File: file.Config{
+			Path: filepath.Join(t.TempDir(), "members.json"),
+			Hash: true,
+		},
+	})
+
+	if _, err := manager.Insert("admin", "secret", types.MemberProfile{IsAdmin: true, CanLogin: true}); err != nil {
+		t.Fatalf("manager.Insert() returned error: %s", err)
+	}
+
+	return manager
+}
+
+func TestMemberManagerCtx_TOTP(t *testing.T) {
+	manager := newTOTPTestManager(t, false)
+
+	secret, _, err := manager.TOTPEnroll("admin")
+	if err != nil {
+		t.Fatalf("manager.TOTPEnroll() returned error: %s", err)
+	}
+
+	// not confirmed yet, login does not require second factor
+	if _, _, err := manager.Login("admin", "secret", "127.0.0.1"); err != nil {
+		t.Fatalf("manager.Login() returned error: %s", err)
+	}
+	if err := manager.Logout("admin"); err != nil {
+		t.Fatalf("manager.Logout() returned error: %s", err)
+	}
+
+	code, _ := utils.TOTPCode(secret, time.Now())
+	recoveryCodes, err := manager.TOTPConfirm("admin", code)
+	if err != nil {
+		t.Fatalf("manager.TOTPConfirm() returned error: %s", err)
+	}
+	if len(recoveryCodes) != totpRecoveryCodes {
+		t.Errorf("manager.TOTPConfirm() returned %d recovery codes", len(recoveryCodes))
+	}
+
+	_, _, err = manager.Login("admin", "secret", "127.0.0.1")
+	var challenge *types.MemberTOTPChallenge
+	if !errors.As(err, &challenge) || challenge.Enroll {
+		t.Fatalf("manager.Login() error = %v, want challenge", err)
+	}
+
+	// code used for confirmation must not be accepted again
+	if _, _, _, err := manager.LoginTOTP(challenge.Token, code, "127.0.0.1"); !errors.Is(err, types.ErrMemberTOTPInvalidCode) {
+		t.Errorf("manager.LoginTOTP() error = %v, want %v", err, types.ErrMemberTOTPInvalidCode)
+	}
+
+	session, _, _, err := manager.LoginTOTP(challenge.Token, recoveryCodes[0], "127.0.0.1")
+	if err != nil {
+		t.Fatalf("manager.LoginTOTP() returned error: %s", err)
+	}
+	if session.ID() != "admin" {
+		t.Errorf("manager.LoginTOTP() session = %s, want admin", session.ID())
+	}
+
+	// challenge can be used only once
+	if _, _, _, err := manager.LoginTOTP(challenge.Token, recoveryCodes[1], "127.0.0.1"); !errors.Is(err, types.ErrMemberTOTPChallengeInvalid) {
+		t.Errorf("manager.LoginTOTP() error = %v, want %v", err, types.ErrMemberTOTPChallengeInvalid)
+	}
+
+	// recovery code can be used only once
+	if err := manager.TOTPDisable("admin", recoveryCodes[0]); !errors.Is(err, types.ErrMemberTOTPInvalidCode) {
+		t.Errorf("manager.TOTPDisable() error = %v, want %v", err, types.ErrMemberTOTPInvalidCode)
+	}
+
+	if err := manager.TOTPDisable("admin", recoveryCodes[1]); err != nil {
+		t.Fatalf("manager.TOTPDisable() returned error: %s", err)
+	}
+}
+
+func TestMemberManagerCtx_TOTPRequireAdmin(t *testing.T) {
+	manager := newTOTPTestManager(t, true)
+
+	_, _, err := manager.Login("admin", "secret", "127.0.0.1")
+	var challenge *types.MemberTOTPChallenge
+	if !errors.As(err, &challenge) || !challenge.Enroll {
+		t.Fatalf("manager.Login() error = %v, want enroll challenge", err)
+	}
+
+	secret, _, err := manager.LoginTOTPEnroll(challenge.Token)
+	if err != nil {
+		t.Fatalf("manager.LoginTOTPEnroll() returned error: %s", err)
+	}
+
+	code, _ := utils.TOTPCode(secret, time.Now())
+	session, _, recoveryCodes, err := manager.LoginTOTP(challenge.Token, code, "127.0.0.1")
+	if err != nil {
+		t.Fatalf("manager.LoginTOTP() returned error: %s", err)
+	}
+	if session.ID() != "admin" || len(recoveryCodes) != totpRecoveryCodes {
+		t.Errorf("manager.LoginTOTP() = %s, %d recovery codes", session.ID(), len(recoveryCodes))
+	}
+
+	if err := manager.TOTPDisable("admin", recoveryCodes[0]); !errors.Is(err, types.ErrMemberTOTPRequired) {
+		t.Errorf("manager.TOTPDisable() error = %v, want %v", err, types.ErrMemberTOTPRequired)
+	}
+}
+
+func TestMemberManagerCtx_Connect_TOTPRequireAdmin(t *testing.T) {
+	manager := New(session.New(&config.Session{}), &config.Member{
+		Provider:         "multiuser",
+		TOTPRequireAdmin: true,
+	})
+
+	if err := manager.Connect(); !errors.Is(err, types.ErrMemberTOTPUnsupported) {
+		t.Errorf("manager.Connect() error = %v, want %v", err, types.ErrMemberTOTPUnsupported)
+	}
+}
+
+func TestMemberManagerCtx_LoginTOTP_lockout(t *testing.T) {
+	manager := newTOTPTestManager(t, false)
+	manager.config.LockoutAttempts = 3
+	manager.config.LockoutDuration = time.Minute
+	manager.config.LockoutMaxDuration = time.Hour
+
+	secret, _, err := manager.TOTPEnroll("admin")
+	if err != nil {
+		t.Fatalf("manager.TOTPEnroll() returned error: %s", err)
+	}
+
+	code, _ := utils.TOTPCode(secret, time.Now())
+	if _, err := manager.TOTPConfirm("admin", code); err != nil {
+		t.Fatalf("manager.TOTPConfirm() returned error: %s", err)
+	}
+
+	// every login gets new challenge, but failed codes add up
+	for i := 0; i < 3; i++ {
+		_, _, err := manager.Login("admin", "secret", "10.0.0.1")
+		var challenge *types.MemberTOTPChallenge
+		if !errors.As(err, &challenge) {
+			t.Fatalf("manager.Login() error = %v, want challenge", err)
+		}
+
+		if _, _, _, err := manager.LoginTOTP(challenge.Token, "000000", "10.0.0.1"); !errors.Is(err, types.ErrMemberTOTPInvalidCode) {
+			t.Errorf("manager.LoginTOTP() error = %v, want %v", err, types.ErrMemberTOTPInvalidCode)
+		}
+	}
+
+	if _, _, err := manager.Login("admin", "secret", "10.0.0.2"); !errors.Is(err, types.ErrMemberLockedOut) {
+		t.Errorf("manager.Login() error = %v, want %v", err, types.ErrMemberLockedOut)
+	}
+}
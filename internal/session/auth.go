@@ -44,6 +44,22 @@ func (manager *SessionManagerCtx) Authenticate(r *http.Request) (types.Session,
 
 	session, ok := manager.GetByToken(token)
 	if !ok {
+		if !manager.jwtEnabled() || !isJWT(token) {
+			return nil, types.ErrSessionNotFound
+		}
+
+		var err error
+		session, err = manager.authenticateJWT(token)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// do not wait for reaper to delete expired session
+	if manager.isExpired(session) {
+		if err := manager.Delete(session.ID()); err != nil && !errors.Is(err, types.ErrSessionNotFound) {
+			return nil, err
+		}
 		return nil, types.ErrSessionNotFound
 	}
 
@@ -0,0 +1,96 @@
+package session
+
+import (
+	"errors"
+	"time"
+
+	"github.com/demodesk/neko/pkg/types"
+)
+
+// Start runs reaper even without lifetime and idle timeout, because sessions can have their own expiration.
+func (manager *SessionManagerCtx) Start() {
+	manager.wg.Add(1)
+
+	go func() {
+		defer manager.wg.Done()
+
+		ticker := time.NewTicker(manager.config.ReaperInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-manager.shutdown:
+				return
+			case <-ticker.C:
+				manager.reap()
+			}
+		}
+	}()
+}
+
+func (manager *SessionManagerCtx) Shutdown() error {
+	manager.logger.Info().Msgf("shutdown")
+
+	close(manager.shutdown)
+	manager.wg.Wait()
+
+	return nil
+}
+
+// expired checks if session reached its expiration, exceeded its lifetime or was not connected for longer than idle timeout.
+func (manager *SessionManagerCtx) expired(session *SessionCtx, now time.Time) bool {
+	if !session.expiresAt.IsZero() && !now.Before(session.expiresAt) {
+		return true
+	}
+
+	if manager.config.Lifetime > 0 && now.Sub(session.createdAt) > manager.config.Lifetime {
+		return true
+	}
+
+	if manager.config.IdleTimeout > 0 {
+		state := session.State()
+		if state.IsConnected {
+			return false
+		}
+
+		// session that never connected is idle since it was created
+		idleSince := session.createdAt
+		if state.NotConnectedSince != nil {
+			idleSince = *state.NotConnectedSince
+		}
+
+		return now.Sub(idleSince) > manager.config.IdleTimeout
+	}
+
+	return false
+}
+
+// isExpired checks if session should have been already deleted by reaper, API session never expires.
+func (manager *SessionManagerCtx) isExpired(session types.Session) bool {
+	s, ok := session.(*SessionCtx)
+	return ok && s != manager.apiSession && manager.expired(s, time.Now())
+}
+
+// reap deletes all expired sessions.
+func (manager *SessionManagerCtx) reap() {
+	now := time.Now()
+
+	manager.sessionsMu.Lock()
+	var expired []string
+	for id, session := range manager.sessions {
+		if manager.expired(session, now) {
+			expired = append(expired, id)
+		}
+	}
+	manager.sessionsMu.Unlock()
+
+	for _, id := range expired {
+		err := manager.Delete(id)
+		if err != nil && !errors.Is(err, types.ErrSessionNotFound) {
+			manager.logger.Err(err).Str("session_id", id).Msg("unable to delete expired session")
+			continue
+		}
+
+		manager.logger.Info().Str("session_id", id).Msg("expired session deleted")
+	}
+}
@@ -0,0 +1,123 @@
+package session
+
+import (
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/demodesk/neko/internal/config"
+	"github.com/demodesk/neko/pkg/types"
+)
+
+func TestSessionManagerCtx_reap(t *testing.T) {
+	manager := New(&config.Session{
+		Lifetime:    time.Hour,
+		IdleTimeout: time.Minute,
+	})
+
+	profile := types.MemberProfile{CanLogin: true}
+
+	for _, id := range []string{"fresh", "idle", "old", "connected"} {
+		if _, _, err := manager.Create(id, profile); err != nil {
+			t.Fatalf("manager.Create() returned error: %s", err)
+		}
+	}
+
+	deleted := map[string]bool{}
+	manager.OnDeleted(func(session types.Session) {
+		deleted[session.ID()] = true
+	})
+
+	now := time.Now()
+	notConnectedSince := now.Add(-2 * time.Minute)
+	connectedSince := now.Add(-30 * time.Minute)
+
+	manager.sessions["idle"].state.NotConnectedSince = &notConnectedSince
+	manager.sessions["old"].createdAt = now.Add(-2 * time.Hour)
+	manager.sessions["connected"].createdAt = now.Add(-30 * time.Minute)
+	manager.sessions["connected"].state.IsConnected = true
+	manager.sessions["connected"].state.ConnectedSince = &connectedSince
+
+	manager.reap()
+
+	for id, want := range map[string]bool{"fresh": false, "idle": true, "old": true, "connected": false} {
+		if _, ok := manager.Get(id); ok == want {
+			t.Errorf("session %s exists = %v, want %v", id, ok, !want)
+		}
+		if deleted[id] != want {
+			t.Errorf("session %s deleted event = %v, want %v", id, deleted[id], want)
+		}
+	}
+}
+
+func TestSessionManagerCtx_Authenticate_expired(t *testing.T) {
+	manager := New(&config.Session{
+		Lifetime: time.Hour,
+	})
+
+	_, token, err := manager.Create("alice", types.MemberProfile{CanLogin: true})
+	if err != nil {
+		t.Fatalf("manager.Create() returned error: %s", err)
+	}
+
+	r := httptest.NewRequest("GET", "/api/whoami?token="+token, nil)
+	if _, err := manager.Authenticate(r); err != nil {
+		t.Fatalf("manager.Authenticate() returned error: %s", err)
+	}
+
+	manager.sessions["alice"].createdAt = time.Now().Add(-2 * time.Hour)
+
+	if _, err := manager.Authenticate(r); err != types.ErrSessionNotFound {
+		t.Errorf("manager.Authenticate() error = %v, want %v", err, types.ErrSessionNotFound)
+	}
+
+	if _, ok := manager.Get("alice"); ok {
+		t.Errorf("expired session expected to be deleted")
+	}
+}
+
+func TestSessionManagerCtx_reap_expiresAt(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "sessions.json")
+	manager := New(&config.Session{File: file})
+
+	profile := types.MemberProfile{CanLogin: true}
+
+	if _, _, err := manager.CreateExpiring("invite", profile, time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("manager.CreateExpiring() returned error: %s", err)
+	}
+	if _, _, err := manager.Create("member", profile); err != nil {
+		t.Fatalf("manager.Create() returned error: %s", err)
+	}
+
+	// expiration survives restart
+	manager = New(&config.Session{File: file})
+	manager.reap()
+
+	if _, ok := manager.Get("invite"); ok {
+		t.Errorf("expired session expected to be deleted")
+	}
+	if _, ok := manager.Get("member"); !ok {
+		t.Errorf("session without expiration expected to exist")
+	}
+}
+
+func TestSessionManagerCtx_reap_idleAfterRestart(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "sessions.json")
+	manager := New(&config.Session{File: file, IdleTimeout: time.Minute})
+
+	if _, _, err := manager.Create("member", types.MemberProfile{CanLogin: true}); err != nil {
+		t.Fatalf("manager.Create() returned error: %s", err)
+	}
+
+	manager.sessions["member"].createdAt = time.Now().Add(-time.Hour)
+	manager.save()
+
+	// idle time before restart is unknown, session is not reaped right away
+	manager = New(&config.Session{File: file, IdleTimeout: time.Minute})
+	manager.reap()
+
+	if _, ok := manager.Get("member"); !ok {
+		t.Errorf("session expected to exist after restart")
+	}
+}
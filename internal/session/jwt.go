@@ -0,0 +1,210 @@
+package session
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+
+	"github.com/demodesk/neko/pkg/types"
+)
+
+var (
+	errJWTInvalid      = errors.New("invalid JWT login token")
+	errJWTReplayed     = errors.New("JWT login token has already been used")
+	errJWTSubjectTaken = errors.New("JWT login token subject belongs to another session")
+	errJWTLoggedOut    = errors.New("JWT login token subject has been logged out")
+)
+
+type jwtClaims struct {
+	jwt.Claims
+
+	Name    string              `json:"name,omitempty"`
+	Profile types.MemberProfile `json:"profile"`
+}
+
+// jwtDeletion is deleted JWT session, that must not be recreated by its token.
+type jwtDeletion struct {
+	deletedAt time.Time
+	expiresAt time.Time
+}
+
+func (manager *SessionManagerCtx) jwtEnabled() bool {
+	return manager.config.JWTSecret != "" || manager.config.JWTPublicKey != nil
+}
+
+// jwtVerify validates signed JWT login token and returns its claims.
+func (manager *SessionManagerCtx) jwtVerify(token string) (*jwtClaims, error) {
+	tok, err := jwt.ParseSigned(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tok.Headers) != 1 {
+		return nil, errJWTInvalid
+	}
+
+	// pick key by algorithm, so that tokens cannot choose how they are verified
+	var key any
+	switch jose.SignatureAlgorithm(tok.Headers[0].Algorithm) {
+	case jose.HS256, jose.HS384, jose.HS512:
+		if manager.config.JWTSecret != "" {
+			key = []byte(manager.config.JWTSecret)
+		}
+	case jose.RS256, jose.RS384, jose.RS512, jose.PS256, jose.PS384, jose.PS512:
+		if manager.config.JWTPublicKey != nil {
+			key = manager.config.JWTPublicKey
+		}
+	}
+
+	if key == nil {
+		return nil, errJWTInvalid
+	}
+
+	claims := &jwtClaims{}
+	if err := tok.Claims(key, claims); err != nil {
+		return nil, err
+	}
+
+	// login links must not be valid forever
+	if claims.Subject == "" || claims.Expiry == nil {
+		return nil, errJWTInvalid
+	}
+
+	err = claims.Validate(jwt.Expected{
+		Issuer: manager.config.JWTIssuer,
+		Time:   time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.Name != "" {
+		claims.Profile.Name = claims.Name
+	}
+
+	if claims.Profile.Name == "" {
+		claims.Profile.Name = claims.Subject
+	}
+
+	return claims, nil
+}
+
+// authenticateJWT returns session for JWT login token, session is created when seen for the first time.
+// Tokens carrying jti can create their session only once, so that it cannot be recreated after logout.
+// Tokens without jti cannot recreate deleted session either, unless they were issued after the deletion.
+func (manager *SessionManagerCtx) authenticateJWT(token string) (types.Session, error) {
+	claims, err := manager.jwtVerify(token)
+	if err != nil {
+		return nil, err
+	}
+
+	// roles are resolved by member providers, tokens must carry final permissions
+	if claims.Profile.Role != "" || claims.Profile.Overrides != nil {
+		return nil, errJWTInvalid
+	}
+
+	manager.jwtMu.Lock()
+	defer manager.jwtMu.Unlock()
+
+	manager.sessionsMu.Lock()
+	session, ok := manager.sessions[claims.Subject]
+	manager.sessionsMu.Unlock()
+
+	// tokens must not take over sessions of members
+	if ok && !session.jwt {
+		return nil, errJWTSubjectTaken
+	}
+
+	if !ok {
+		// do not create sessions, that would be rejected anyway
+		if !claims.Profile.CanLogin {
+			return nil, types.ErrSessionLoginDisabled
+		}
+
+		if claims.ID != "" && !manager.jwtUseID(claims.ID, claims.Expiry.Time()) {
+			return nil, errJWTReplayed
+		}
+
+		if !manager.jwtSubjectAllowed(claims) {
+			return nil, errJWTLoggedOut
+		}
+
+		// session must not outlive its login token
+		created, _, err := manager.create(claims.Subject, claims.Profile, claims.Expiry.Time(), true)
+		if err != nil {
+			return nil, err
+		}
+
+		return created, nil
+	}
+
+	// token might carry updated permissions
+	if !reflect.DeepEqual(session.Profile(), claims.Profile) {
+		if err := manager.Update(claims.Subject, claims.Profile); err != nil {
+			return nil, err
+		}
+	}
+
+	return session, nil
+}
+
+// jwtUseID marks JWT ID as used until its expiry, returns false if it has been used already.
+func (manager *SessionManagerCtx) jwtUseID(id string, expiry time.Time) bool {
+	now := time.Now()
+	for usedID, usedExpiry := range manager.jwtIDs {
+		if now.After(usedExpiry) {
+			delete(manager.jwtIDs, usedID)
+		}
+	}
+
+	if _, ok := manager.jwtIDs[id]; ok {
+		return false
+	}
+
+	manager.jwtIDs[id] = expiry
+	return true
+}
+
+// jwtSubjectDeleted remembers deleted JWT session until its token expires, so that the token cannot recreate it.
+func (manager *SessionManagerCtx) jwtSubjectDeleted(id string, expiresAt time.Time) {
+	now := time.Now()
+	if !now.Before(expiresAt) {
+		return
+	}
+
+	manager.jwtMu.Lock()
+	defer manager.jwtMu.Unlock()
+
+	manager.jwtDeleted[id] = jwtDeletion{
+		deletedAt: now,
+		expiresAt: expiresAt,
+	}
+}
+
+// jwtSubjectAllowed checks if token can create session for its subject, only tokens issued
+// after the session was deleted are allowed. It must be called with jwtMu held.
+func (manager *SessionManagerCtx) jwtSubjectAllowed(claims *jwtClaims) bool {
+	now := time.Now()
+	for id, deletion := range manager.jwtDeleted {
+		if !now.Before(deletion.expiresAt) {
+			delete(manager.jwtDeleted, id)
+		}
+	}
+
+	deletion, ok := manager.jwtDeleted[claims.Subject]
+	if !ok {
+		return true
+	}
+
+	// issued at has seconds precision, tokens issued in the same second are rejected as well
+	return claims.IssuedAt != nil && claims.IssuedAt.Time().After(deletion.deletedAt)
+}
+
+// isJWT checks if token has three base64url encoded parts, random session tokens do not contain dots.
+func isJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
@@ -0,0 +1,213 @@
+package session
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+
+	"github.com/demodesk/neko/internal/config"
+	"github.com/demodesk/neko/pkg/types"
+)
+
+func signJWT(t *testing.T, alg jose.SignatureAlgorithm, key any, claims any) string {
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: alg, Key: key}, nil)
+	if err != nil {
+		t.Fatalf("jose.NewSigner() returned error: %s", err)
+	}
+
+	token, err := jwt.Signed(signer).Claims(claims).CompactSerialize()
+	if err != nil {
+		t.Fatalf("jwt.Signed() returned error: %s", err)
+	}
+
+	return token
+}
+
+func TestSessionManagerCtx_authenticateJWT(t *testing.T) {
+	manager := New(&config.Session{
+		JWTSecret: "secret",
+		JWTIssuer: "backend",
+	})
+
+	authenticate := func(token string) (types.Session, error) {
+		r := httptest.NewRequest("GET", "/api/whoami?token="+token, nil)
+		return manager.Authenticate(r)
+	}
+
+	claims := jwtClaims{
+		Claims: jwt.Claims{
+			Subject: "guest-1",
+			Issuer:  "backend",
+			Expiry:  jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+		Name: "Guest",
+		Profile: types.MemberProfile{
+			CanLogin:   true,
+			CanConnect: true,
+			CanWatch:   true,
+		},
+	}
+
+	session, err := authenticate(signJWT(t, jose.HS256, []byte("secret"), claims))
+	if err != nil {
+		t.Fatalf("manager.Authenticate() returned error: %s", err)
+	}
+	if session.ID() != "guest-1" || session.Profile().Name != "Guest" || !session.Profile().CanWatch {
+		t.Errorf("manager.Authenticate() = %s, %+v", session.ID(), session.Profile())
+	}
+
+	// second token for the same subject updates permissions
+	claims.Profile.CanHost = true
+	session, err = authenticate(signJWT(t, jose.HS256, []byte("secret"), claims))
+	if err != nil {
+		t.Fatalf("manager.Authenticate() returned error: %s", err)
+	}
+	if !session.Profile().CanHost {
+		t.Errorf("manager.Authenticate() expected updated profile")
+	}
+	if len(manager.List()) != 1 {
+		t.Errorf("manager.List() returned %d sessions, want 1", len(manager.List()))
+	}
+
+	invalid := map[string]string{
+		"wrong secret": signJWT(t, jose.HS256, []byte("other"), claims),
+	}
+
+	expired := claims
+	expired.Expiry = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+	invalid["expired"] = signJWT(t, jose.HS256, []byte("secret"), expired)
+
+	noExpiry := claims
+	noExpiry.Expiry = nil
+	invalid["no expiry"] = signJWT(t, jose.HS256, []byte("secret"), noExpiry)
+
+	wrongIssuer := claims
+	wrongIssuer.Issuer = "other"
+	invalid["wrong issuer"] = signJWT(t, jose.HS256, []byte("secret"), wrongIssuer)
+
+	for name, token := range invalid {
+		if _, err := authenticate(token); err == nil {
+			t.Errorf("%s: manager.Authenticate() expected error", name)
+		}
+	}
+
+	loginDisabled := claims
+	loginDisabled.Subject = "guest-2"
+	loginDisabled.Profile.CanLogin = false
+	if _, err := authenticate(signJWT(t, jose.HS256, []byte("secret"), loginDisabled)); !errors.Is(err, types.ErrSessionLoginDisabled) {
+		t.Errorf("manager.Authenticate() error = %v, want %v", err, types.ErrSessionLoginDisabled)
+	}
+
+	// roles cannot be resolved without member provider
+	withRole := claims
+	withRole.Subject = "guest-3"
+	withRole.Profile.Role = "admin"
+	if _, err := authenticate(signJWT(t, jose.HS256, []byte("secret"), withRole)); !errors.Is(err, errJWTInvalid) {
+		t.Errorf("manager.Authenticate() error = %v, want %v", err, errJWTInvalid)
+	}
+
+	// tokens cannot take over sessions of members
+	if _, _, err := manager.Create("member", types.MemberProfile{CanLogin: true}); err != nil {
+		t.Fatalf("manager.Create() returned error: %s", err)
+	}
+	member := claims
+	member.Subject = "member"
+	member.Profile.IsAdmin = true
+	if _, err := authenticate(signJWT(t, jose.HS256, []byte("secret"), member)); !errors.Is(err, errJWTSubjectTaken) {
+		t.Errorf("manager.Authenticate() error = %v, want %v", err, errJWTSubjectTaken)
+	}
+	if session, _ := manager.Get("member"); session.Profile().IsAdmin {
+		t.Errorf("manager.Authenticate() must not update member profile")
+	}
+}
+
+func TestSessionManagerCtx_authenticateJWT_ID(t *testing.T) {
+	manager := New(&config.Session{
+		JWTSecret: "secret",
+	})
+
+	authenticate := func(token string) (types.Session, error) {
+		r := httptest.NewRequest("GET", "/api/whoami?token="+token, nil)
+		return manager.Authenticate(r)
+	}
+
+	token := signJWT(t, jose.HS256, []byte("secret"), jwtClaims{
+		Claims: jwt.Claims{
+			ID:      "link-1",
+			Subject: "guest-1",
+			Expiry:  jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+		Profile: types.MemberProfile{
+			CanLogin: true,
+		},
+	})
+
+	// token can be used repeatedly while its session exists
+	for i := 0; i < 2; i++ {
+		if _, err := authenticate(token); err != nil {
+			t.Fatalf("manager.Authenticate() returned error: %s", err)
+		}
+	}
+
+	if err := manager.Delete("guest-1"); err != nil {
+		t.Fatalf("manager.Delete() returned error: %s", err)
+	}
+
+	// deleted session cannot be recreated by the same token
+	if _, err := authenticate(token); !errors.Is(err, errJWTReplayed) {
+		t.Errorf("manager.Authenticate() error = %v, want %v", err, errJWTReplayed)
+	}
+}
+
+func TestSessionManagerCtx_authenticateJWT_deleted(t *testing.T) {
+	manager := New(&config.Session{
+		JWTSecret: "secret",
+	})
+
+	authenticate := func(token string) (types.Session, error) {
+		r := httptest.NewRequest("GET", "/api/whoami?token="+token, nil)
+		return manager.Authenticate(r)
+	}
+
+	expiry := time.Now().Add(time.Hour).Truncate(time.Second)
+	claims := jwtClaims{
+		Claims: jwt.Claims{
+			Subject:  "guest-1",
+			IssuedAt: jwt.NewNumericDate(time.Now().Add(-time.Minute)),
+			Expiry:   jwt.NewNumericDate(expiry),
+		},
+		Profile: types.MemberProfile{
+			CanLogin: true,
+		},
+	}
+	token := signJWT(t, jose.HS256, []byte("secret"), claims)
+
+	session, err := authenticate(token)
+	if err != nil {
+		t.Fatalf("manager.Authenticate() returned error: %s", err)
+	}
+
+	// session expires together with its token
+	if expiresAt := session.(*SessionCtx).expiresAt; !expiresAt.Equal(expiry) {
+		t.Errorf("session expires at %s, want %s", expiresAt, expiry)
+	}
+
+	if err := manager.Delete("guest-1"); err != nil {
+		t.Fatalf("manager.Delete() returned error: %s", err)
+	}
+
+	// deleted session cannot be recreated by token without jti
+	if _, err := authenticate(token); !errors.Is(err, errJWTLoggedOut) {
+		t.Errorf("manager.Authenticate() error = %v, want %v", err, errJWTLoggedOut)
+	}
+
+	// token issued after deletion is accepted
+	claims.IssuedAt = jwt.NewNumericDate(time.Now().Add(time.Second))
+	if _, err := authenticate(signJWT(t, jose.HS256, []byte("secret"), claims)); err != nil {
+		t.Errorf("manager.Authenticate() returned error: %s", err)
+	}
+}
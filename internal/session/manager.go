@@ -4,6 +4,7 @@ import (
 	"errors"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/kataras/go-events"
 	"github.com/rs/zerolog"
@@ -25,10 +26,13 @@ func New(config *config.Session) *SessionManagerCtx {
 			InactiveCursors:   config.InactiveCursors,
 			MercifulReconnect: config.MercifulReconnect,
 		},
-		tokens:   make(map[string]string),
-		sessions: make(map[string]*SessionCtx),
-		cursors:  make(map[types.Session][]types.Cursor),
-		emmiter:  events.New(),
+		tokens:     make(map[string]string),
+		sessions:   make(map[string]*SessionCtx),
+		jwtIDs:     make(map[string]time.Time),
+		jwtDeleted: make(map[string]jwtDeletion),
+		cursors:    make(map[types.Session][]types.Cursor),
+		emmiter:    events.New(),
+		shutdown:   make(chan struct{}),
 	}
 
 	// create API session
@@ -67,6 +71,11 @@ type SessionManagerCtx struct {
 	sessions   map[string]*SessionCtx
 	sessionsMu sync.Mutex
 
+	// used JWT IDs and deleted JWT sessions until their expiry, serializes creation of JWT sessions
+	jwtIDs     map[string]time.Time
+	jwtDeleted map[string]jwtDeletion
+	jwtMu      sync.Mutex
+
 	hostId atomic.Value
 
 	cursors   map[types.Session][]types.Cursor
@@ -74,9 +83,21 @@ type SessionManagerCtx struct {
 
 	emmiter    events.EventEmmiter
 	apiSession *SessionCtx
+
+	shutdown chan struct{}
+	wg       sync.WaitGroup
 }
 
 func (manager *SessionManagerCtx) Create(id string, profile types.MemberProfile) (types.Session, string, error) {
+	return manager.create(id, profile, time.Time{}, false)
+}
+
+// CreateExpiring creates session, that is deleted when it reaches expiration time.
+func (manager *SessionManagerCtx) CreateExpiring(id string, profile types.MemberProfile, expiresAt time.Time) (types.Session, string, error) {
+	return manager.create(id, profile, expiresAt, false)
+}
+
+func (manager *SessionManagerCtx) create(id string, profile types.MemberProfile, expiresAt time.Time, jwt bool) (types.Session, string, error) {
 	token, err := utils.NewUID(64)
 	if err != nil {
 		return nil, "", err
@@ -94,11 +115,14 @@ func (manager *SessionManagerCtx) Create(id string, profile types.MemberProfile)
 	}
 
 	session := &SessionCtx{
-		id:      id,
-		token:   token,
-		manager: manager,
-		logger:  manager.logger.With().Str("session_id", id).Logger(),
-		profile: profile,
+		id:        id,
+		token:     token,
+		manager:   manager,
+		logger:    manager.logger.With().Str("session_id", id).Logger(),
+		profile:   profile,
+		createdAt: time.Now(),
+		expiresAt: expiresAt,
+		jwt:       jwt,
 	}
 
 	manager.tokens[token] = id
@@ -142,6 +166,10 @@ func (manager *SessionManagerCtx) Delete(id string) error {
 	delete(manager.sessions, id)
 	manager.sessionsMu.Unlock()
 
+	if session.jwt {
+		manager.jwtSubjectDeleted(id, session.expiresAt)
+	}
+
 	if session.State().IsConnected {
 		session.DestroyWebSocketPeer("session deleted")
 	}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"os"
+	"time"
 
 	"github.com/demodesk/neko/pkg/types"
 )
@@ -17,9 +18,12 @@ func (manager *SessionManagerCtx) save() {
 	sessions := make([]types.SessionProfile, 0, len(manager.sessions))
 	for _, session := range manager.sessions {
 		sessions = append(sessions, types.SessionProfile{
-			Id:      session.id,
-			Token:   session.token,
-			Profile: session.profile,
+			Id:        session.id,
+			Token:     session.token,
+			Profile:   session.profile,
+			CreatedAt: session.createdAt,
+			ExpiresAt: session.expiresAt,
+			JWT:       session.jwt,
 		})
 	}
 
@@ -77,15 +81,29 @@ func (manager *SessionManagerCtx) load() {
 	}
 
 	// create sessions
+	now := time.Now()
 	manager.sessionsMu.Lock()
 	for _, session := range sessions {
+		// sessions stored without creation time start their lifetime now
+		createdAt := session.CreatedAt
+		if createdAt.IsZero() {
+			createdAt = now
+		}
+
 		manager.tokens[session.Token] = session.Id
 		manager.sessions[session.Id] = &SessionCtx{
-			id:      session.Id,
-			token:   session.Token,
-			manager: manager,
-			logger:  manager.logger.With().Str("session_id", session.Id).Logger(),
-			profile: session.Profile,
+			id:        session.Id,
+			token:     session.Token,
+			manager:   manager,
+			logger:    manager.logger.With().Str("session_id", session.Id).Logger(),
+			profile:   session.Profile,
+			createdAt: createdAt,
+			expiresAt: session.ExpiresAt,
+			jwt:       session.JWT,
+			// idle time before restart is unknown, idle timeout starts now
+			state: types.SessionState{
+				NotConnectedSince: &now,
+			},
 		}
 	}
 	manager.sessionsMu.Unlock()
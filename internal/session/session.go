@@ -22,6 +22,12 @@ type SessionCtx struct {
 	profile types.MemberProfile
 	state   types.SessionState
 
+	createdAt time.Time
+	// session is deleted by reaper after this time, if set
+	expiresAt time.Time
+	// session was created by JWT login token
+	jwt bool
+
 	websocketPeer types.WebSocketPeer
 	websocketMu   sync.Mutex
 
@@ -0,0 +1,226 @@
+package webrtc
+
+import (
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/demodesk/neko/internal/config"
+	"github.com/demodesk/neko/pkg/types"
+	"github.com/demodesk/neko/pkg/utils"
+)
+
+// name of the built-in bitrate controller
+const defaultBitrateController = "default"
+
+func (manager *WebRTCManagerCtx) RegisterBitrateController(name string, factory types.BitrateControllerFactory) error {
+	manager.bitrateControllersMu.Lock()
+	defer manager.bitrateControllersMu.Unlock()
+
+	if _, ok := manager.bitrateControllers[name]; ok || name == defaultBitrateController {
+		return types.ErrWebRTCBitrateControllerExists
+	}
+
+	manager.bitrateControllers[name] = factory
+	manager.logger.Info().Str("name", name).Msg("registered bitrate controller")
+	return nil
+}
+
+func (manager *WebRTCManagerCtx) newBitrateController(logger zerolog.Logger, session types.Session) types.BitrateController {
+	name := manager.config.Estimator.Controller
+
+	if name != "" && name != defaultBitrateController {
+		manager.bitrateControllersMu.RLock()
+		factory, ok := manager.bitrateControllers[name]
+		manager.bitrateControllersMu.RUnlock()
+
+		if ok {
+			return factory(session)
+		}
+
+		logger.Warn().Str("name", name).Msg("bitrate controller not found, using default")
+	}
+
+	return newEstimatorController(logger, manager.config.Estimator)
+}
+
+// estimatorController is the default bitrate controller, it follows trend of target
+// bitrate and waits for stable or unstable connection before switching streams.
+type estimatorController struct {
+	logger zerolog.Logger
+	conf   config.WebRTCEstimator
+	trend  *utils.TrendDetector
+
+	// since when is the estimate stable/unstable
+	stableSince   time.Time
+	unstableSince time.Time
+	// since when are we neutral but cannot accomodate current bitrate
+	// we migt be stalled or estimator just reached zer (very bad connection)
+	stalledSince time.Time
+	// when was the last upgrade/downgrade
+	lastUpgradeTime   time.Time
+	lastDowngradeTime time.Time
+}
+
+func newEstimatorController(logger zerolog.Logger, conf config.WebRTCEstimator) *estimatorController {
+	// if estimator is not in debug mode, use a nop logger
+	var debugLogger zerolog.Logger
+	if conf.Debug {
+		debugLogger = logger.With().Str("component", "estimator").Logger().Level(zerolog.DebugLevel)
+	} else {
+		debugLogger = zerolog.Nop()
+	}
+
+	return &estimatorController{
+		logger: debugLogger,
+		conf:   conf,
+		trend: utils.NewTrendDetector(
+			utils.TrendDetectorParams{
+				// Probing
+				//RequiredSamples:        3,
+				//DownwardTrendThreshold: 0.0,
+				//CollapseValues:         false,
+				// Non-Probing
+				RequiredSamples:        8,
+				DownwardTrendThreshold: -0.5,
+				CollapseValues:         true,
+			}),
+		stableSince: time.Now(), // we asume stable at start
+	}
+}
+
+func (c *estimatorController) Next(sample types.BitrateSample, current types.StreamSinkManager, available types.StreamSelectorManager) *types.StreamSelector {
+	conf := c.conf
+	now := sample.Time
+
+	// get trend direction to decide if we should upgrade or downgrade
+	c.trend.AddValue(int64(sample.TargetBitrate))
+	direction := c.trend.GetDirection()
+
+	// if stream bitrate is 0, we need to wait for some time until we get a valid value
+	streamId, streamBitrate := current.ID(), current.Bitrate()
+	if streamBitrate == 0 {
+		c.logger.Warn().Msg("looks like stream bitrate is 0, we need to wait for some time")
+		return nil
+	}
+
+	// check whats the difference between target and stream bitrate
+	diff := float64(sample.TargetBitrate) / float64(streamBitrate)
+
+	c.logger.Info().
+		Float64("diff", diff).
+		Int("target_bitrate", sample.TargetBitrate).
+		Uint64("stream_bitrate", streamBitrate).
+		Str("direction", direction.String()).
+		Msg("got bitrate from estimator")
+
+	// if we can accomodate current stream or we are not netural anymore,
+	// we are not stalled so we reset the stalled time
+	if direction != utils.TrendDirectionNeutral || diff > 1+conf.DiffThreshold {
+		c.stalledSince = now
+	}
+
+	// if we are neutral and stalled for too long, we might be congesting
+	stalled := direction == utils.TrendDirectionNeutral && now.Sub(c.stalledSince) > conf.StalledDuration
+	if stalled {
+		c.logger.Warn().
+			Time("stalled_since", c.stalledSince).
+			Msgf("it looks like we are stalled")
+	}
+
+	// if we have an downward trend or are stalled, we might be congesting
+	if direction == utils.TrendDirectionDownward || stalled {
+		// we reset the stable time because we are congesting
+		c.stableSince = now
+
+		// if we downgraded recently, we wait for some more time
+		if now.Sub(c.lastDowngradeTime) < conf.DowngradeBackoff {
+			c.logger.Debug().
+				Time("last_downgrade", c.lastDowngradeTime).
+				Msgf("downgraded recently, waiting for at least %v", conf.DowngradeBackoff)
+			return nil
+		}
+
+		// if we are not unstable but we fluctuate we should wait for some more time
+		if now.Sub(c.unstableSince) < conf.UnstableDuration {
+			c.logger.Debug().
+				Time("unstable_since", c.unstableSince).
+				Msgf("we are not unstable long enough, waiting for at least %v", conf.UnstableDuration)
+			return nil
+		}
+
+		// if we still have a big difference between target and stream bitrate, we wait for some more time
+		if conf.DiffThreshold >= 0 && diff > 1+conf.DiffThreshold {
+			c.logger.Debug().
+				Float64("diff", diff).
+				Float64("threshold", conf.DiffThreshold).
+				Msgf("we still have a big difference between target and stream bitrate, " +
+					"therefore we still should be able to accomodate current stream")
+			return nil
+		}
+
+		c.lastDowngradeTime = now
+
+		selector := types.StreamSelector{
+			ID:   streamId,
+			Type: types.StreamSelectorTypeLower,
+		}
+
+		if _, ok := available.GetStream(selector); !ok {
+			c.logger.Info().Msg("looks like we are already on the lowest stream")
+			return nil
+		}
+
+		c.logger.Info().Msg("downgrading video stream")
+		return &selector
+	}
+
+	// we reset the unstable time because we are not congesting
+	c.unstableSince = now
+
+	// if we have a neutral or upward trend, that means our estimate is stable
+	// if we are on the highest stream, we don't need to do anything
+	// but if there is a higher stream, we should try to upgrade and see if it works
+
+	// if we upgraded recently, we wait for some more time
+	if now.Sub(c.lastUpgradeTime) < conf.UpgradeBackoff {
+		c.logger.Debug().
+			Time("last_upgrade", c.lastUpgradeTime).
+			Msgf("upgraded recently, waiting for at least %v", conf.UpgradeBackoff)
+		return nil
+	}
+
+	// if we are not stable for long enough, we wait for some more time
+	// because bandwidth estimation might fluctuate
+	if now.Sub(c.stableSince) < conf.StableDuration {
+		c.logger.Debug().
+			Time("stable_since", c.stableSince).
+			Msgf("we are not stable long enough, waiting for at least %v", conf.StableDuration)
+		return nil
+	}
+
+	// upgrade only if estimated bitrate passed the threshold
+	if conf.DiffThreshold >= 0 && diff < 1+conf.DiffThreshold {
+		c.logger.Debug().
+			Float64("diff", diff).
+			Float64("threshold", conf.DiffThreshold).
+			Msgf("looks like we don't have enough bitrate to accomodate higher stream, " +
+				"therefore we should wait for some more time")
+		return nil
+	}
+
+	c.lastUpgradeTime = now
+
+	selector := types.StreamSelector{
+		ID:   streamId,
+		Type: types.StreamSelectorTypeHigher,
+	}
+
+	if _, ok := available.GetStream(selector); !ok {
+		c.logger.Info().Msg("looks like we are already on the highest stream")
+		return nil
+	}
+
+	c.logger.Info().Msg("upgrading video stream")
+	return &selector
+}
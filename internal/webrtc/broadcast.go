@@ -0,0 +1,481 @@
+package webrtc
+
+import (
+	"errors"
+	"io"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/rtp/codecs"
+	"github.com/pion/webrtc/v3"
+	"github.com/rs/zerolog"
+
+	"github.com/demodesk/neko/pkg/types"
+	"github.com/demodesk/neko/pkg/types/codec"
+)
+
+const (
+	// maximum size of RTP packet payload, same as pion uses for sample tracks
+	rtpOutboundMTU = 1200
+
+	// how often can a new subscriber request a keyframe from running stream
+	keyframeRequestInterval = time.Second
+)
+
+func payloaderForCodec(c codec.RTPCodec) (rtp.Payloader, bool) {
+	switch c.Name {
+	case codec.VP8().Name:
+		return &codecs.VP8Payloader{EnablePictureID: true}, true
+	case codec.VP9().Name:
+		return &codecs.VP9Payloader{}, true
+	case codec.H264().Name:
+		return &codecs.H264Payloader{}, true
+	case codec.Opus().Name:
+		return &codecs.OpusPayloader{}, true
+	case codec.G722().Name:
+		return &codecs.G722Payloader{}, true
+	case codec.PCMU().Name, codec.PCMA().Name:
+		return &codecs.G711Payloader{}, true
+	default:
+		return nil, false
+	}
+}
+
+// rtpBroadcaster packetizes samples of a single stream once and fans
+// out resulting RTP packets to all subscribed watch tracks.
+type rtpBroadcaster struct {
+	logger zerolog.Logger
+	stream types.StreamSinkManager
+
+	packetizer rtp.Packetizer
+	clockRate  uint32
+	isVideo    bool
+
+	// serializes subscription changes, held while adding or removing stream listener
+	subMu sync.Mutex
+
+	keyframeMu          sync.Mutex
+	keyframeTimer       *time.Timer
+	lastKeyframeRequest time.Time
+
+	mu          sync.Mutex
+	subscribers map[*watchTrack]struct{}
+}
+
+func newRTPBroadcaster(logger zerolog.Logger, stream types.StreamSinkManager) (*rtpBroadcaster, error) {
+	c := stream.Codec()
+
+	payloader, ok := payloaderForCodec(c)
+	if !ok {
+		return nil, webrtc.ErrNoPayloaderForCodec
+	}
+
+	return &rtpBroadcaster{
+		logger:      logger.With().Str("submodule", "broadcast").Str("stream", stream.ID()).Logger(),
+		stream:      stream,
+		packetizer:  rtp.NewPacketizer(rtpOutboundMTU, 0, 0, payloader, rtp.NewRandomSequencer(), c.Capability.ClockRate),
+		clockRate:   c.Capability.ClockRate,
+		isVideo:     c.IsVideo(),
+		subscribers: map[*watchTrack]struct{}{},
+	}, nil
+}
+
+func (b *rtpBroadcaster) WriteSample(sample types.Sample) {
+	samples := uint32(sample.Duration.Seconds() * float64(b.clockRate))
+	packets := b.packetizer.Packetize(sample.Data, samples)
+	if len(packets) == 0 {
+		return
+	}
+
+	// audio samples can always be decoded independently
+	keyframe := !b.isVideo || !sample.DeltaUnit
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for t := range b.subscribers {
+		t.writeRTP(b, packets, samples, keyframe)
+	}
+}
+
+func (b *rtpBroadcaster) subscribe(t *watchTrack) error {
+	b.subMu.Lock()
+	defer b.subMu.Unlock()
+
+	b.mu.Lock()
+	_, exists := b.subscribers[t]
+	count := len(b.subscribers)
+	b.subscribers[t] = struct{}{}
+	b.mu.Unlock()
+
+	if exists {
+		return nil
+	}
+
+	// first subscriber starts listening to the stream
+	if count == 0 {
+		if err := b.stream.AddListener(b); err != nil {
+			b.mu.Lock()
+			delete(b.subscribers, t)
+			b.mu.Unlock()
+			return err
+		}
+
+		b.logger.Debug().Msg("first subscriber, listening to stream")
+		return nil
+	}
+
+	// stream is already running, new subscriber needs a keyframe
+	b.keyframeNeeded()
+	return nil
+}
+
+func (b *rtpBroadcaster) unsubscribe(t *watchTrack) {
+	b.subMu.Lock()
+	defer b.subMu.Unlock()
+
+	b.mu.Lock()
+	_, exists := b.subscribers[t]
+	delete(b.subscribers, t)
+	count := len(b.subscribers)
+	b.mu.Unlock()
+
+	// last subscriber stops listening to the stream
+	if exists && count == 0 {
+		if err := b.stream.RemoveListener(b); err != nil {
+			b.logger.Warn().Err(err).Msg("failed to remove listener from stream")
+		}
+
+		b.logger.Debug().Msg("last subscriber, stopped listening to stream")
+	}
+}
+
+// keyframeNeeded requests a keyframe for new subscribers. Requests are throttled, so that
+// many subscribers joining at once do not flood everyone with keyframes.
+func (b *rtpBroadcaster) keyframeNeeded() {
+	if !b.isVideo {
+		return
+	}
+
+	b.keyframeMu.Lock()
+	defer b.keyframeMu.Unlock()
+
+	// request is already scheduled
+	if b.keyframeTimer != nil {
+		return
+	}
+
+	wait := keyframeRequestInterval - time.Since(b.lastKeyframeRequest)
+	if wait <= 0 {
+		b.lastKeyframeRequest = time.Now()
+		b.requestKeyframe()
+		return
+	}
+
+	b.keyframeTimer = time.AfterFunc(wait, func() {
+		b.keyframeMu.Lock()
+		b.keyframeTimer = nil
+		b.lastKeyframeRequest = time.Now()
+		b.keyframeMu.Unlock()
+
+		b.mu.Lock()
+		count := len(b.subscribers)
+		b.mu.Unlock()
+
+		// do not start the stream again, if everyone left meanwhile
+		if count > 0 {
+			b.requestKeyframe()
+		}
+	})
+}
+
+// requestKeyframe adds temporary listener to the stream, that waits in the keyframe
+// lobby and therefore makes the stream emit a keyframe, without interrupting others.
+func (b *rtpBroadcaster) requestKeyframe() {
+	probe := &keyframeProbe{stream: b.stream}
+	if err := b.stream.AddListener(probe); err != nil {
+		b.logger.Warn().Err(err).Msg("failed to request keyframe")
+	}
+}
+
+type keyframeProbe struct {
+	stream types.StreamSinkManager
+	once   sync.Once
+}
+
+func (p *keyframeProbe) WriteSample(sample types.Sample) {
+	p.once.Do(func() {
+		// stream is locked while writing samples
+		go p.stream.RemoveListener(p)
+	})
+}
+
+// watchTrack is a local track of a single watch-only peer, that forwards packets from
+// shared broadcaster and rewrites them, so that switching streams is seamless for the peer.
+type watchTrack struct {
+	logger zerolog.Logger
+	codec  codec.RTPCodec
+
+	broadcaster func(stream types.StreamSinkManager) (*rtpBroadcaster, error)
+
+	paused   bool
+	stream   types.StreamSinkManager
+	source   *rtpBroadcaster
+	streamMu sync.Mutex
+
+	mu          sync.Mutex
+	active      *rtpBroadcaster
+	writer      webrtc.TrackLocalWriter
+	ssrc        webrtc.SSRC
+	payloadType webrtc.PayloadType
+	// rewriting of packets from current source
+	waitForKf  bool
+	resync     bool
+	sequence   uint16
+	timestamp  uint32
+	tsOffset   uint32
+	hasWritten bool
+}
+
+func newWatchTrack(logger zerolog.Logger, codec codec.RTPCodec, broadcaster func(types.StreamSinkManager) (*rtpBroadcaster, error)) *watchTrack {
+	return &watchTrack{
+		logger:      logger.With().Str("id", codec.Type.String()).Logger(),
+		codec:       codec,
+		broadcaster: broadcaster,
+		sequence:    uint16(rand.Uint32()),
+	}
+}
+
+//
+// webrtc.TrackLocal
+//
+
+func (t *watchTrack) Bind(ctx webrtc.TrackLocalContext) (webrtc.RTPCodecParameters, error) {
+	for _, params := range ctx.CodecParameters() {
+		if !strings.EqualFold(params.MimeType, t.codec.Capability.MimeType) {
+			continue
+		}
+
+		t.mu.Lock()
+		t.writer = ctx.WriteStream()
+		t.ssrc = ctx.SSRC()
+		t.payloadType = params.PayloadType
+		source := t.active
+		t.mu.Unlock()
+
+		// keyframes sent before binding were dropped
+		if source != nil {
+			source.keyframeNeeded()
+		}
+
+		return params, nil
+	}
+
+	return webrtc.RTPCodecParameters{}, webrtc.ErrUnsupportedCodec
+}
+
+func (t *watchTrack) Unbind(ctx webrtc.TrackLocalContext) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.writer = nil
+	return nil
+}
+
+func (t *watchTrack) ID() string {
+	return t.codec.Type.String()
+}
+
+func (t *watchTrack) RID() string {
+	return ""
+}
+
+func (t *watchTrack) StreamID() string {
+	return "stream"
+}
+
+func (t *watchTrack) Kind() webrtc.RTPCodecType {
+	return t.codec.Type
+}
+
+//
+// packets
+//
+
+func (t *watchTrack) writeRTP(source *rtpBroadcaster, packets []*rtp.Packet, samples uint32, keyframe bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// ignore packets from previous source while switching streams
+	if t.writer == nil || source != t.active {
+		return
+	}
+
+	// after switching streams, wait for the first keyframe
+	if t.waitForKf {
+		if !keyframe {
+			return
+		}
+		t.waitForKf = false
+	}
+
+	// continue timestamps of previous source, so that the peer sees a single stream
+	if t.resync {
+		if t.hasWritten {
+			t.tsOffset = t.timestamp + samples - packets[0].Timestamp
+		}
+		t.resync = false
+	}
+
+	for _, packet := range packets {
+		header := packet.Header
+		header.SSRC = uint32(t.ssrc)
+		header.PayloadType = uint8(t.payloadType)
+		header.SequenceNumber = t.sequence
+		header.Timestamp = packet.Timestamp + t.tsOffset
+
+		t.sequence++
+		t.timestamp = header.Timestamp
+		t.hasWritten = true
+
+		if _, err := t.writer.WriteRTP(&header, packet.Payload); err != nil {
+			if !errors.Is(err, io.ErrClosedPipe) {
+				t.logger.Warn().Err(err).Msg("failed to write rtp packet to track")
+			}
+			return
+		}
+	}
+}
+
+// watchSourceState is forwarding state of a source, that is restored when switching streams fails.
+type watchSourceState struct {
+	active    *rtpBroadcaster
+	waitForKf bool
+	resync    bool
+}
+
+// setSource switches packets source, packets from new source are forwarded after next keyframe.
+// It returns previous state, so that switching can be reverted.
+func (t *watchTrack) setSource(source *rtpBroadcaster) watchSourceState {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	prev := watchSourceState{
+		active:    t.active,
+		waitForKf: t.waitForKf,
+		resync:    t.resync,
+	}
+
+	t.active = source
+	t.waitForKf = t.codec.IsVideo()
+	t.resync = true
+	return prev
+}
+
+// restoreSource reverts source switch, current source keeps forwarding packets without waiting for a keyframe.
+func (t *watchTrack) restoreSource(state watchSourceState) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.active = state.active
+	t.waitForKf = state.waitForKf
+	t.resync = state.resync
+}
+
+//
+// stream
+//
+
+func (t *watchTrack) subscribe(stream types.StreamSinkManager) (*rtpBroadcaster, error) {
+	source, err := t.broadcaster(stream)
+	if err != nil {
+		return nil, err
+	}
+
+	prev := t.setSource(source)
+	if err := source.subscribe(t); err != nil {
+		// keep forwarding packets from current source
+		t.restoreSource(prev)
+		return nil, err
+	}
+
+	return source, nil
+}
+
+func (t *watchTrack) SetStream(stream types.StreamSinkManager) (bool, error) {
+	t.streamMu.Lock()
+	defer t.streamMu.Unlock()
+
+	// if we already listen to the stream, do nothing
+	if t.stream == stream {
+		return false, nil
+	}
+
+	// if paused, we switch the stream but don't subscribe
+	if t.paused {
+		t.stream = stream
+		return true, nil
+	}
+
+	source, err := t.subscribe(stream)
+	if err != nil {
+		return false, err
+	}
+
+	if t.source != nil {
+		t.source.unsubscribe(t)
+	}
+
+	t.stream = stream
+	t.source = source
+	return true, nil
+}
+
+func (t *watchTrack) RemoveStream() {
+	t.streamMu.Lock()
+	defer t.streamMu.Unlock()
+
+	if t.source != nil {
+		t.source.unsubscribe(t)
+	}
+
+	t.setSource(nil)
+	t.stream = nil
+	t.source = nil
+}
+
+func (t *watchTrack) Stream() (types.StreamSinkManager, bool) {
+	t.streamMu.Lock()
+	defer t.streamMu.Unlock()
+
+	return t.stream, t.stream != nil
+}
+
+func (t *watchTrack) SetPaused(paused bool) {
+	t.streamMu.Lock()
+	defer t.streamMu.Unlock()
+
+	// if there is no state change or no stream, do nothing
+	if t.paused == paused || t.stream == nil {
+		t.paused = paused
+		return
+	}
+
+	if paused {
+		t.source.unsubscribe(t)
+		t.setSource(nil)
+		t.source = nil
+	} else {
+		source, err := t.subscribe(t.stream)
+		if err != nil {
+			t.logger.Warn().Err(err).Msg("failed to change subscription state")
+			return
+		}
+		t.source = source
+	}
+
+	t.paused = paused
+}
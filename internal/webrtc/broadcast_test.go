@@ -0,0 +1,85 @@
+package webrtc
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/rs/zerolog"
+
+	"github.com/demodesk/neko/pkg/types"
+	"github.com/demodesk/neko/pkg/types/codec"
+)
+
+type testTrackWriter struct {
+	packets int
+}
+
+func (w *testTrackWriter) WriteRTP(header *rtp.Header, payload []byte) (int, error) {
+	w.packets++
+	return len(payload), nil
+}
+
+func (w *testTrackWriter) Write(b []byte) (int, error) {
+	w.packets++
+	return len(b), nil
+}
+
+// testFailingStream refuses new listeners.
+type testFailingStream struct {
+	testStream
+}
+
+func (s *testFailingStream) AddListener(listener types.SampleListener) error {
+	return errors.New("unable to add listener")
+}
+
+func TestWatchTrack_SetStreamFailure(t *testing.T) {
+	logger := zerolog.Nop()
+
+	current := &testStream{id: "current", codec: codec.VP8(), listeners: map[types.SampleListener]struct{}{}}
+	failing := &testFailingStream{testStream{id: "failing", codec: codec.VP8(), listeners: map[types.SampleListener]struct{}{}}}
+
+	broadcasters := map[types.StreamSinkManager]*rtpBroadcaster{}
+	track := newWatchTrack(logger, codec.VP8(), func(stream types.StreamSinkManager) (*rtpBroadcaster, error) {
+		if b, ok := broadcasters[stream]; ok {
+			return b, nil
+		}
+		b, err := newRTPBroadcaster(logger, stream)
+		broadcasters[stream] = b
+		return b, err
+	})
+
+	writer := &testTrackWriter{}
+	track.writer = writer
+
+	if _, err := track.SetStream(current); err != nil {
+		t.Fatalf("track.SetStream() returned error: %s", err)
+	}
+
+	sample := func(deltaUnit bool) types.Sample {
+		return types.Sample{Data: make([]byte, 100), Duration: time.Second / 30, DeltaUnit: deltaUnit}
+	}
+
+	source := broadcasters[current]
+	source.WriteSample(sample(false))
+	if writer.packets == 0 {
+		t.Fatalf("keyframe from current stream was not forwarded")
+	}
+
+	if _, err := track.SetStream(failing); err == nil {
+		t.Fatalf("track.SetStream() expected error for failing stream")
+	}
+
+	// current stream keeps flowing, without waiting for another keyframe
+	written := writer.packets
+	source.WriteSample(sample(true))
+	if writer.packets == written {
+		t.Errorf("packets from current stream are not forwarded after failed switch")
+	}
+
+	if stream, ok := track.Stream(); !ok || stream != current {
+		t.Errorf("track.Stream() = %v, want %s", stream, current.id)
+	}
+}
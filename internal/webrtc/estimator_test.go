@@ -0,0 +1,794 @@
+package webrtc
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pion/ice/v2"
+	"github.com/pion/interceptor"
+	"github.com/pion/interceptor/pkg/cc"
+	"github.com/pion/interceptor/pkg/gcc"
+	"github.com/pion/interceptor/pkg/nack"
+	"github.com/pion/rtcp"
+	"github.com/pion/transport/v2"
+	"github.com/pion/transport/v2/vnet"
+	"github.com/pion/webrtc/v3"
+	"github.com/rs/zerolog"
+
+	"github.com/demodesk/neko/internal/config"
+	"github.com/demodesk/neko/internal/webrtc/pionlog"
+	"github.com/demodesk/neko/pkg/types"
+	"github.com/demodesk/neko/pkg/types/codec"
+	"github.com/demodesk/neko/pkg/utils"
+)
+
+//
+// network conditions
+//
+
+type networkConditions struct {
+	// bandwidth from sender to receiver in bits per second, 0 means unlimited
+	Bandwidth int
+	// percentage of packets lost from sender to receiver
+	Loss int
+	// one way latency in both directions
+	Latency time.Duration
+}
+
+type networkStep struct {
+	// time since start of the test when conditions are applied
+	At time.Duration
+	networkConditions
+}
+
+type videoExpectation struct {
+	// time since start of the test when video ID is checked
+	At time.Duration
+	// any of these video IDs is accepted
+	VideoIDs []string
+}
+
+type estimatorScenario struct {
+	name     string
+	duration time.Duration
+	initial  string
+	network  []networkStep
+	expect   []videoExpectation
+}
+
+// tuned down estimator config, so that scenarios finish in reasonable time
+var testEstimatorConfig = config.WebRTCEstimator{
+	Enabled:          true,
+	InitialBitrate:   1_000_000,
+	ReadInterval:     250 * time.Millisecond,
+	StableDuration:   3 * time.Second,
+	UnstableDuration: 1500 * time.Millisecond,
+	StalledDuration:  4 * time.Second,
+	DowngradeBackoff: 2 * time.Second,
+	UpgradeBackoff:   2 * time.Second,
+	DiffThreshold:    0.15,
+}
+
+// ordered from the highest to the lowest quality, as in capture config
+var testStreams = []testStreamConfig{
+	{id: "high", bitrate: 1_500_000},
+	{id: "medium", bitrate: 600_000},
+	{id: "low", bitrate: 250_000},
+}
+
+func TestEstimatorNetworkConditions(t *testing.T) {
+	// scenarios run in real time for more than a minute, so they are opt-in
+	if testing.Short() || os.Getenv("NEKO_ESTIMATOR_TESTS") != "1" {
+		t.Skip("skipping estimator simulation, set NEKO_ESTIMATOR_TESTS=1 to run it")
+	}
+
+	scenarios := []estimatorScenario{
+		{
+			name:     "upgrade on good network",
+			duration: 15 * time.Second,
+			initial:  "medium",
+			network: []networkStep{
+				{At: 0, networkConditions: networkConditions{Bandwidth: 10_000_000, Latency: 20 * time.Millisecond}},
+			},
+			expect: []videoExpectation{
+				{At: 1 * time.Second, VideoIDs: []string{"medium"}},
+				{At: 15 * time.Second, VideoIDs: []string{"high"}},
+			},
+		},
+		{
+			name:     "high latency is not congestion",
+			duration: 15 * time.Second,
+			initial:  "medium",
+			network: []networkStep{
+				{At: 0, networkConditions: networkConditions{Bandwidth: 10_000_000, Latency: 150 * time.Millisecond}},
+			},
+			expect: []videoExpectation{
+				{At: 15 * time.Second, VideoIDs: []string{"high"}},
+			},
+		},
+		{
+			name:     "downgrade on bandwidth drop",
+			duration: 30 * time.Second,
+			initial:  "high",
+			network: []networkStep{
+				{At: 0, networkConditions: networkConditions{Bandwidth: 10_000_000, Latency: 20 * time.Millisecond}},
+				{At: 5 * time.Second, networkConditions: networkConditions{Bandwidth: 400_000, Latency: 20 * time.Millisecond}},
+			},
+			expect: []videoExpectation{
+				{At: 4 * time.Second, VideoIDs: []string{"high"}},
+				// estimator probes for higher stream from time to time
+				{At: 20 * time.Second, VideoIDs: []string{"low", "medium"}},
+				{At: 30 * time.Second, VideoIDs: []string{"low", "medium"}},
+			},
+		},
+		{
+			name:     "downgrade on heavy loss",
+			duration: 25 * time.Second,
+			initial:  "high",
+			network: []networkStep{
+				{At: 0, networkConditions: networkConditions{Bandwidth: 10_000_000, Latency: 20 * time.Millisecond}},
+				{At: 5 * time.Second, networkConditions: networkConditions{Bandwidth: 10_000_000, Loss: 25, Latency: 20 * time.Millisecond}},
+			},
+			expect: []videoExpectation{
+				{At: 4 * time.Second, VideoIDs: []string{"high"}},
+				{At: 25 * time.Second, VideoIDs: []string{"low"}},
+			},
+		},
+	}
+
+	for i, scenario := range scenarios {
+		i, scenario := i, scenario
+		t.Run(scenario.name, func(t *testing.T) {
+			t.Parallel()
+
+			// loss pattern is the same in every run of the scenario
+			h := newEstimatorHarness(t, scenario.initial, int64(i))
+			defer h.Close()
+
+			history := h.Run(scenario.network, scenario.duration)
+			for _, expect := range scenario.expect {
+				videoID := history.At(expect.At)
+				if ok, _ := utils.ArrayIn(videoID, expect.VideoIDs); !ok {
+					t.Errorf("at %v got video %q, want one of %q (history: %s)", expect.At, videoID, expect.VideoIDs, history)
+				}
+			}
+		})
+	}
+}
+
+//
+// harness
+//
+
+const (
+	testSenderIP   = "1.2.3.4"
+	testReceiverIP = "1.2.3.5"
+)
+
+// metrics are registered globally per session ID, so that every harness needs unique one
+var testSessionSeq atomic.Int32
+
+type estimatorHarness struct {
+	t *testing.T
+
+	router *vnet.Router
+	tbf    *vnet.TokenBucketFilter
+	// packet loss percentage from sender to receiver
+	loss atomic.Int32
+	// seeded random source for packet loss
+	lossMu   sync.Mutex
+	lossRand *rand.Rand
+	// one way latency in nanoseconds
+	latency atomic.Int64
+
+	sender   *webrtc.PeerConnection
+	receiver *webrtc.PeerConnection
+	streams  *testStreamSelector
+	peer     *WebRTCPeerCtx
+}
+
+func newEstimatorHarness(t *testing.T, initial string, seed int64) *estimatorHarness {
+	h := &estimatorHarness{
+		t:        t,
+		lossRand: rand.New(rand.NewSource(seed)),
+	}
+
+	var err error
+	h.router, err = vnet.NewRouter(&vnet.RouterConfig{
+		CIDR:          "1.2.3.0/24",
+		LoggerFactory: pionlog.New(zerolog.Nop()),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// loss is applied only on media path, feedback is not affected
+	h.router.AddChunkFilter(func(c vnet.Chunk) bool {
+		if c.SourceAddr().(*net.UDPAddr).IP.String() != testSenderIP {
+			return true
+		}
+		h.lossMu.Lock()
+		defer h.lossMu.Unlock()
+		return h.lossRand.Intn(100) >= int(h.loss.Load())
+	})
+
+	senderNet, err := vnet.NewNet(&vnet.NetConfig{StaticIPs: []string{testSenderIP}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.router.AddNet(senderNet); err != nil {
+		t.Fatal(err)
+	}
+
+	receiverNet, err := vnet.NewNet(&vnet.NetConfig{StaticIPs: []string{testReceiverIP}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// bandwidth is limited on packets arriving to the receiver, queue is kept
+	// small because estimator remembers only a few hundred sent packets and
+	// feedback for packets queued longer than that would be ignored
+	h.tbf, err = vnet.NewTokenBucketFilter(receiverNet, vnet.TBFQueueSizeInBytes(10_000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.setBandwidth(0)
+	if err := h.router.AddNet(h.tbf); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := h.router.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	videoCodec := codec.VP8()
+	logger := zerolog.Nop()
+
+	// sender
+	var estimator cc.BandwidthEstimator
+	h.sender, estimator = h.newSender(&delayNet{Net: senderNet, latency: &h.latency}, videoCodec)
+
+	// receiver
+	h.receiver = h.newReceiver(&delayNet{Net: receiverNet, latency: &h.latency}, videoCodec)
+
+	session := &testSession{id: fmt.Sprintf("estimator-test-%d", testSessionSeq.Add(1))}
+	metrics := newMetricsManager().getBySession(session)
+
+	videoRtcp := make(chan []rtcp.Packet, 1)
+	videoTrack, err := NewTrack(logger, videoCodec, h.sender, WithRtcpChan(videoRtcp))
+	if err != nil {
+		t.Fatal(err)
+	}
+	go metrics.rtcpReceiver(videoRtcp)
+
+	h.streams = newTestStreamSelector(videoCodec, testStreams)
+	h.peer = &WebRTCPeerCtx{
+		logger:            logger,
+		session:           session,
+		metrics:           metrics,
+		connection:        h.sender,
+		estimator:         estimator,
+		bitrateController: newEstimatorController(logger, testEstimatorConfig),
+		video:             h.streams,
+		videoTrack:        videoTrack,
+		rtcpChannel:       videoRtcp,
+		estimatorConfig:   testEstimatorConfig,
+		audioDisabled:     true,
+	}
+
+	auto := true
+	err = h.peer.SetVideo(types.PeerVideoRequest{
+		Selector: &types.StreamSelector{ID: initial, Type: types.StreamSelectorTypeExact},
+		Auto:     &auto,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h.connect()
+	return h
+}
+
+func (h *estimatorHarness) newSender(net transport.Net, videoCodec codec.RTPCodec) (*webrtc.PeerConnection, cc.BandwidthEstimator) {
+	engine := &webrtc.MediaEngine{}
+	if err := videoCodec.Register(engine); err != nil {
+		h.t.Fatal(err)
+	}
+
+	registry := &interceptor.Registry{}
+
+	// same estimator as used in production
+	estimatorChan := make(chan cc.BandwidthEstimator, 1)
+	congestionController, err := cc.NewInterceptor(func() (cc.BandwidthEstimator, error) {
+		return gcc.NewSendSideBWE(
+			gcc.SendSideBWEInitialBitrate(testEstimatorConfig.InitialBitrate),
+			gcc.SendSideBWEPacer(gcc.NewNoOpPacer()),
+		)
+	})
+	if err != nil {
+		h.t.Fatal(err)
+	}
+
+	congestionController.OnNewPeerConnection(func(id string, estimator cc.BandwidthEstimator) {
+		estimatorChan <- estimator
+	})
+
+	registry.Add(congestionController)
+	if err := webrtc.ConfigureTWCCHeaderExtensionSender(engine, registry); err != nil {
+		h.t.Fatal(err)
+	}
+
+	if err := webrtc.RegisterDefaultInterceptors(engine, registry); err != nil {
+		h.t.Fatal(err)
+	}
+
+	connection := h.newPeerConnection(net, engine, registry)
+	return connection, <-estimatorChan
+}
+
+func (h *estimatorHarness) newReceiver(net transport.Net, videoCodec codec.RTPCodec) *webrtc.PeerConnection {
+	engine := &webrtc.MediaEngine{}
+	if err := videoCodec.Register(engine); err != nil {
+		h.t.Fatal(err)
+	}
+
+	registry := &interceptor.Registry{}
+
+	// limit retransmission requests like browsers do, otherwise
+	// on congested link nack storm consumes all available bandwidth
+	generator, err := nack.NewGeneratorInterceptor(nack.GeneratorMaxNacksPerPacket(2))
+	if err != nil {
+		h.t.Fatal(err)
+	}
+
+	engine.RegisterFeedback(webrtc.RTCPFeedback{Type: "nack"}, webrtc.RTPCodecTypeVideo)
+	engine.RegisterFeedback(webrtc.RTCPFeedback{Type: "nack", Parameter: "pli"}, webrtc.RTPCodecTypeVideo)
+	registry.Add(generator)
+
+	if err := webrtc.ConfigureRTCPReports(registry); err != nil {
+		h.t.Fatal(err)
+	}
+
+	if err := webrtc.ConfigureTWCCSender(engine, registry); err != nil {
+		h.t.Fatal(err)
+	}
+
+	connection := h.newPeerConnection(net, engine, registry)
+
+	// receiver must read incoming packets in order to send feedback
+	connection.OnTrack(func(track *webrtc.TrackRemote, receiver *webrtc.RTPReceiver) {
+		buf := make([]byte, 1500)
+		for {
+			if _, _, err := track.Read(buf); err != nil {
+				return
+			}
+		}
+	})
+
+	if _, err := connection.AddTransceiverFromKind(webrtc.RTPCodecTypeVideo, webrtc.RTPTransceiverInit{
+		Direction: webrtc.RTPTransceiverDirectionRecvonly,
+	}); err != nil {
+		h.t.Fatal(err)
+	}
+
+	return connection
+}
+
+func (h *estimatorHarness) newPeerConnection(net transport.Net, engine *webrtc.MediaEngine, registry *interceptor.Registry) *webrtc.PeerConnection {
+	settings := webrtc.SettingEngine{
+		LoggerFactory: pionlog.New(zerolog.Nop()),
+	}
+	settings.SetNet(net)
+	settings.SetICEMulticastDNSMode(ice.MulticastDNSModeDisabled)
+	settings.SetNetworkTypes([]webrtc.NetworkType{webrtc.NetworkTypeUDP4})
+
+	api := webrtc.NewAPI(
+		webrtc.WithMediaEngine(engine),
+		webrtc.WithSettingEngine(settings),
+		webrtc.WithInterceptorRegistry(registry),
+	)
+
+	connection, err := api.NewPeerConnection(webrtc.Configuration{})
+	if err != nil {
+		h.t.Fatal(err)
+	}
+
+	return connection
+}
+
+func (h *estimatorHarness) connect() {
+	connected := make(chan struct{})
+	var once sync.Once
+	h.sender.OnConnectionStateChange(func(state webrtc.PeerConnectionState) {
+		if state == webrtc.PeerConnectionStateConnected {
+			once.Do(func() { close(connected) })
+		}
+	})
+
+	offer, err := h.sender.CreateOffer(nil)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+
+	gathered := webrtc.GatheringCompletePromise(h.sender)
+	if err := h.sender.SetLocalDescription(offer); err != nil {
+		h.t.Fatal(err)
+	}
+	<-gathered
+
+	if err := h.receiver.SetRemoteDescription(*h.sender.LocalDescription()); err != nil {
+		h.t.Fatal(err)
+	}
+
+	answer, err := h.receiver.CreateAnswer(nil)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+
+	gathered = webrtc.GatheringCompletePromise(h.receiver)
+	if err := h.receiver.SetLocalDescription(answer); err != nil {
+		h.t.Fatal(err)
+	}
+	<-gathered
+
+	if err := h.sender.SetRemoteDescription(*h.receiver.LocalDescription()); err != nil {
+		h.t.Fatal(err)
+	}
+
+	select {
+	case <-connected:
+	case <-time.After(10 * time.Second):
+		h.t.Fatal("peers did not connect")
+	}
+}
+
+func (h *estimatorHarness) setBandwidth(bandwidth int) {
+	if bandwidth <= 0 {
+		bandwidth = 1000 * vnet.MBit
+	}
+
+	// tokens are refilled at most every 100ms, burst must cover at least that
+	h.tbf.Set(
+		vnet.TBFRate(bandwidth),
+		vnet.TBFMaxBurst(bandwidth/8/5),
+	)
+}
+
+func (h *estimatorHarness) apply(conditions networkConditions) {
+	h.setBandwidth(conditions.Bandwidth)
+	h.loss.Store(int32(conditions.Loss))
+	h.latency.Store(int64(conditions.Latency))
+}
+
+// Run applies network schedule and records selected video over time.
+func (h *estimatorHarness) Run(schedule []networkStep, duration time.Duration) videoHistory {
+	start := time.Now()
+	go h.peer.estimatorReader()
+
+	history := videoHistory{}
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	step := 0
+	for {
+		elapsed := time.Since(start)
+		for step < len(schedule) && schedule[step].At <= elapsed {
+			h.apply(schedule[step].networkConditions)
+			step++
+		}
+
+		history.add(elapsed, h.peer.Video().ID)
+		if elapsed >= duration {
+			return history
+		}
+
+		<-ticker.C
+	}
+}
+
+func (h *estimatorHarness) Close() {
+	h.peer.videoTrack.Shutdown()
+	h.streams.Close()
+
+	if err := h.sender.Close(); err != nil {
+		h.t.Error(err)
+	}
+	if err := h.receiver.Close(); err != nil {
+		h.t.Error(err)
+	}
+
+	_ = h.tbf.Close()
+	_ = h.router.Stop()
+}
+
+//
+// video history
+//
+
+type videoChange struct {
+	At      time.Duration
+	VideoID string
+}
+
+type videoHistory []videoChange
+
+func (v *videoHistory) add(at time.Duration, videoID string) {
+	if len(*v) > 0 && (*v)[len(*v)-1].VideoID == videoID {
+		return
+	}
+	*v = append(*v, videoChange{At: at, VideoID: videoID})
+}
+
+// At returns video ID selected at given time.
+func (v videoHistory) At(at time.Duration) string {
+	videoID := ""
+	for _, change := range v {
+		if change.At > at {
+			break
+		}
+		videoID = change.VideoID
+	}
+	return videoID
+}
+
+func (v videoHistory) String() string {
+	str := ""
+	for i, change := range v {
+		if i > 0 {
+			str += " -> "
+		}
+		str += fmt.Sprintf("%s@%v", change.VideoID, change.At.Round(100*time.Millisecond))
+	}
+	return str
+}
+
+//
+// virtual network with latency
+//
+
+// delayNet delays all outgoing UDP packets by configured latency.
+type delayNet struct {
+	*vnet.Net
+	latency *atomic.Int64
+}
+
+func (n *delayNet) ListenUDP(network string, locAddr *net.UDPAddr) (transport.UDPConn, error) {
+	conn, err := n.Net.ListenUDP(network, locAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	return newDelayConn(conn, n.latency), nil
+}
+
+type delayedPacket struct {
+	data     []byte
+	addr     net.Addr
+	deadline time.Time
+}
+
+type delayConn struct {
+	transport.UDPConn
+	latency *atomic.Int64
+	queue   chan delayedPacket
+	done    chan struct{}
+	once    sync.Once
+}
+
+func newDelayConn(conn transport.UDPConn, latency *atomic.Int64) *delayConn {
+	c := &delayConn{
+		UDPConn: conn,
+		latency: latency,
+		queue:   make(chan delayedPacket, 1024),
+		done:    make(chan struct{}),
+	}
+
+	go c.run()
+	return c
+}
+
+func (c *delayConn) WriteTo(p []byte, addr net.Addr) (int, error) {
+	packet := delayedPacket{
+		data:     append([]byte(nil), p...),
+		addr:     addr,
+		deadline: time.Now().Add(time.Duration(c.latency.Load())),
+	}
+
+	select {
+	case c.queue <- packet:
+	case <-c.done:
+		return 0, net.ErrClosed
+	default:
+		// queue is full, packet is dropped
+	}
+
+	return len(p), nil
+}
+
+func (c *delayConn) run() {
+	for {
+		select {
+		case <-c.done:
+			return
+		case packet := <-c.queue:
+			// packets are sent in order, as on a real link
+			if wait := time.Until(packet.deadline); wait > 0 {
+				time.Sleep(wait)
+			}
+			_, _ = c.UDPConn.WriteTo(packet.data, packet.addr)
+		}
+	}
+}
+
+func (c *delayConn) Close() error {
+	c.once.Do(func() { close(c.done) })
+	return c.UDPConn.Close()
+}
+
+//
+// video streams
+//
+
+type testStreamConfig struct {
+	id      string
+	bitrate uint64
+}
+
+// testStream produces random samples with constant bitrate.
+type testStream struct {
+	id      string
+	codec   codec.RTPCodec
+	bitrate uint64
+
+	mu        sync.Mutex
+	listeners map[types.SampleListener]struct{}
+	stop      chan struct{}
+}
+
+func (s *testStream) ID() string            { return s.id }
+func (s *testStream) Codec() codec.RTPCodec { return s.codec }
+func (s *testStream) Bitrate() uint64       { return s.bitrate }
+func (s *testStream) Started() bool         { return true }
+func (s *testStream) CreatePipeline() error { return nil }
+func (s *testStream) DestroyPipeline()      {}
+
+func (s *testStream) ListenersCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.listeners)
+}
+
+func (s *testStream) AddListener(listener types.SampleListener) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.listeners[listener] = struct{}{}
+	return nil
+}
+
+func (s *testStream) RemoveListener(listener types.SampleListener) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.listeners, listener)
+	return nil
+}
+
+func (s *testStream) MoveListenerTo(listener types.SampleListener, targetStream types.StreamSinkManager) error {
+	if err := s.RemoveListener(listener); err != nil {
+		return err
+	}
+	return targetStream.AddListener(listener)
+}
+
+// samples per second produced by test streams
+const testStreamFramerate = 30
+
+func (s *testStream) run() {
+	interval := time.Second / testStreamFramerate
+	size := int(s.bitrate / 8 / testStreamFramerate)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case now := <-ticker.C:
+			data := make([]byte, size)
+			_, _ = rand.Read(data)
+
+			s.mu.Lock()
+			for listener := range s.listeners {
+				listener.WriteSample(types.Sample{
+					Data:      data,
+					Length:    size,
+					Timestamp: now,
+					Duration:  interval,
+				})
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+type testStreamSelector struct {
+	codec   codec.RTPCodec
+	ids     []string
+	streams map[string]*testStream
+}
+
+// newTestStreamSelector creates streams ordered from the highest to the lowest bitrate.
+func newTestStreamSelector(codec codec.RTPCodec, configs []testStreamConfig) *testStreamSelector {
+	selector := &testStreamSelector{
+		codec:   codec,
+		streams: map[string]*testStream{},
+	}
+
+	for _, config := range configs {
+		stream := &testStream{
+			id:        config.id,
+			codec:     codec,
+			bitrate:   config.bitrate,
+			listeners: map[types.SampleListener]struct{}{},
+			stop:      make(chan struct{}),
+		}
+		go stream.run()
+
+		selector.ids = append(selector.ids, config.id)
+		selector.streams[config.id] = stream
+	}
+
+	return selector
+}
+
+func (s *testStreamSelector) IDs() []string         { return s.ids }
+func (s *testStreamSelector) Codec() codec.RTPCodec { return s.codec }
+
+func (s *testStreamSelector) GetStream(selector types.StreamSelector) (types.StreamSinkManager, bool) {
+	for i, id := range s.ids {
+		if id != selector.ID {
+			continue
+		}
+
+		switch selector.Type {
+		case types.StreamSelectorTypeLower:
+			i++
+		case types.StreamSelectorTypeHigher:
+			i--
+		}
+
+		if i < 0 || i >= len(s.ids) {
+			return nil, false
+		}
+		return s.streams[s.ids[i]], true
+	}
+
+	return nil, false
+}
+
+func (s *testStreamSelector) Close() {
+	for _, stream := range s.streams {
+		close(stream.stop)
+	}
+}
+
+//
+// session
+//
+
+type testSession struct {
+	types.Session
+	id string
+}
+
+func (s *testSession) ID() string                     { return s.id }
+func (s *testSession) Send(event string, payload any) {}
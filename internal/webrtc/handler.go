@@ -3,8 +3,10 @@ package webrtc
 import (
 	"bytes"
 	"encoding/binary"
+	"fmt"
 	"math"
 	"time"
+	"unicode/utf8"
 
 	"github.com/demodesk/neko/internal/webrtc/payload"
 	"github.com/demodesk/neko/pkg/types"
@@ -16,6 +18,7 @@ func (manager *WebRTCManagerCtx) handle(
 	logger zerolog.Logger, data []byte,
 	dataChannel *webrtc.DataChannel,
 	session types.Session,
+	peer *WebRTCPeerCtx,
 ) error {
 	isHost := session.IsHost()
 
@@ -43,9 +46,17 @@ func (manager *WebRTCManagerCtx) handle(
 
 		x, y := int(payload.X), int(payload.Y)
 		if isHost {
+			// excess movement is coalesced by the limiter
+			if !peer.limiter.Move(x, y) {
+				return nil
+			}
+
 			// handle active cursor movement
-			manager.desktop.Move(x, y)
+			if err := manager.desktop.ApplyInput(types.InputEvent{Type: "move", X: x, Y: y}); err != nil {
+				return err
+			}
 			manager.curPosition.Set(x, y)
+			peer.videoTrack.MarkInput()
 		} else {
 			// handle inactive cursor movement
 			session.SetCursor(types.Cursor{
@@ -88,6 +99,14 @@ func (manager *WebRTCManagerCtx) handle(
 		}
 
 		return dataChannel.Send(buffer.Bytes())
+	} else if header.Event == payload.OP_SERVER_PONG {
+		pong := &payload.ServerPong{}
+		if err := binary.Read(buffer, binary.BigEndian, pong); err != nil {
+			return err
+		}
+
+		peer.handleServerPong(pong)
+		return nil
 	}
 
 	// continue only if session is host
@@ -95,8 +114,14 @@ func (manager *WebRTCManagerCtx) handle(
 		return nil
 	}
 
+	// latency to the following video sample is measured only for applied input
 	switch header.Event {
 	case payload.OP_SCROLL:
+		if !peer.limiter.Scroll() {
+			logger.Debug().Msg("scroll rate limited")
+			return nil
+		}
+
 		// TODO: remove this once the client is fixed
 		if header.Length == 4 {
 			payload := &payload.Scroll_Old{}
@@ -104,23 +129,31 @@ func (manager *WebRTCManagerCtx) handle(
 				return err
 			}
 
-			manager.desktop.Scroll(int(payload.X), int(payload.Y), false)
-			logger.Trace().
-				Int16("x", payload.X).
-				Int16("y", payload.Y).
-				Msg("scroll")
+			if err := manager.desktop.ApplyInput(types.InputEvent{Type: "scroll", X: int(payload.X), Y: int(payload.Y)}); err != nil {
+				logger.Warn().Err(err).Msg("scroll failed")
+			} else {
+				peer.videoTrack.MarkInput()
+				logger.Trace().
+					Int16("x", payload.X).
+					Int16("y", payload.Y).
+					Msg("scroll")
+			}
 		} else {
 			payload := &payload.Scroll{}
 			if err := binary.Read(buffer, binary.BigEndian, payload); err != nil {
 				return err
 			}
 
-			manager.desktop.Scroll(int(payload.DeltaX), int(payload.DeltaY), payload.ControlKey)
-			logger.Trace().
-				Int16("deltaX", payload.DeltaX).
-				Int16("deltaY", payload.DeltaY).
-				Bool("controlKey", payload.ControlKey).
-				Msg("scroll")
+			if err := manager.desktop.ApplyInput(types.InputEvent{Type: "scroll", X: int(payload.DeltaX), Y: int(payload.DeltaY), ControlKey: payload.ControlKey}); err != nil {
+				logger.Warn().Err(err).Msg("scroll failed")
+			} else {
+				peer.videoTrack.MarkInput()
+				logger.Trace().
+					Int16("deltaX", payload.DeltaX).
+					Int16("deltaY", payload.DeltaY).
+					Bool("controlKey", payload.ControlKey).
+					Msg("scroll")
+			}
 		}
 	case payload.OP_KEY_DOWN:
 		payload := &payload.Key{}
@@ -128,9 +161,15 @@ func (manager *WebRTCManagerCtx) handle(
 			return err
 		}
 
-		if err := manager.desktop.KeyDown(payload.Key); err != nil {
+		if !peer.limiter.Key() {
+			logger.Debug().Uint32("key", payload.Key).Msg("key down rate limited")
+			return nil
+		}
+
+		if err := manager.desktop.ApplyInput(types.InputEvent{Type: "key_down", Code: payload.Key}); err != nil {
 			logger.Warn().Err(err).Uint32("key", payload.Key).Msg("key down failed")
 		} else {
+			peer.videoTrack.MarkInput()
 			logger.Trace().Uint32("key", payload.Key).Msg("key down")
 		}
 	case payload.OP_KEY_UP:
@@ -139,9 +178,10 @@ func (manager *WebRTCManagerCtx) handle(
 			return err
 		}
 
-		if err := manager.desktop.KeyUp(payload.Key); err != nil {
+		if err := manager.desktop.ApplyInput(types.InputEvent{Type: "key_up", Code: payload.Key}); err != nil {
 			logger.Warn().Err(err).Uint32("key", payload.Key).Msg("key up failed")
 		} else {
+			peer.videoTrack.MarkInput()
 			logger.Trace().Uint32("key", payload.Key).Msg("key up")
 		}
 	case payload.OP_BTN_DOWN:
@@ -150,9 +190,15 @@ func (manager *WebRTCManagerCtx) handle(
 			return err
 		}
 
-		if err := manager.desktop.ButtonDown(payload.Key); err != nil {
+		if !peer.limiter.Button() {
+			logger.Debug().Uint32("key", payload.Key).Msg("button down rate limited")
+			return nil
+		}
+
+		if err := manager.desktop.ApplyInput(types.InputEvent{Type: "button_down", Code: payload.Key}); err != nil {
 			logger.Warn().Err(err).Uint32("key", payload.Key).Msg("button down failed")
 		} else {
+			peer.videoTrack.MarkInput()
 			logger.Trace().Uint32("key", payload.Key).Msg("button down")
 		}
 	case payload.OP_BTN_UP:
@@ -161,9 +207,10 @@ func (manager *WebRTCManagerCtx) handle(
 			return err
 		}
 
-		if err := manager.desktop.ButtonUp(payload.Key); err != nil {
+		if err := manager.desktop.ApplyInput(types.InputEvent{Type: "button_up", Code: payload.Key}); err != nil {
 			logger.Warn().Err(err).Uint32("key", payload.Key).Msg("button up failed")
 		} else {
+			peer.videoTrack.MarkInput()
 			logger.Trace().Uint32("key", payload.Key).Msg("button up")
 		}
 	case payload.OP_TOUCH_BEGIN:
@@ -172,9 +219,10 @@ func (manager *WebRTCManagerCtx) handle(
 			return err
 		}
 
-		if err := manager.desktop.TouchBegin(payload.TouchId, int(payload.X), int(payload.Y), payload.Pressure); err != nil {
+		if err := manager.desktop.ApplyInput(types.InputEvent{Type: "touch_begin", TouchId: payload.TouchId, X: int(payload.X), Y: int(payload.Y), Pressure: payload.Pressure}); err != nil {
 			logger.Warn().Err(err).Uint32("touchId", payload.TouchId).Msg("touch begin failed")
 		} else {
+			peer.videoTrack.MarkInput()
 			logger.Trace().Uint32("touchId", payload.TouchId).Msg("touch begin")
 		}
 	case payload.OP_TOUCH_UPDATE:
@@ -183,9 +231,10 @@ func (manager *WebRTCManagerCtx) handle(
 			return err
 		}
 
-		if err := manager.desktop.TouchUpdate(payload.TouchId, int(payload.X), int(payload.Y), payload.Pressure); err != nil {
+		if err := manager.desktop.ApplyInput(types.InputEvent{Type: "touch_update", TouchId: payload.TouchId, X: int(payload.X), Y: int(payload.Y), Pressure: payload.Pressure}); err != nil {
 			logger.Warn().Err(err).Uint32("touchId", payload.TouchId).Msg("touch update failed")
 		} else {
+			peer.videoTrack.MarkInput()
 			logger.Trace().Uint32("touchId", payload.TouchId).Msg("touch update")
 		}
 	case payload.OP_TOUCH_END:
@@ -194,11 +243,30 @@ func (manager *WebRTCManagerCtx) handle(
 			return err
 		}
 
-		if err := manager.desktop.TouchEnd(payload.TouchId, int(payload.X), int(payload.Y), payload.Pressure); err != nil {
+		if err := manager.desktop.ApplyInput(types.InputEvent{Type: "touch_end", TouchId: payload.TouchId, X: int(payload.X), Y: int(payload.Y), Pressure: payload.Pressure}); err != nil {
 			logger.Warn().Err(err).Uint32("touchId", payload.TouchId).Msg("touch end failed")
 		} else {
+			peer.videoTrack.MarkInput()
 			logger.Trace().Uint32("touchId", payload.TouchId).Msg("touch end")
 		}
+	case payload.OP_TEXT:
+		text := buffer.Next(int(header.Length))
+		if len(text) != int(header.Length) || !utf8.Valid(text) {
+			return fmt.Errorf("invalid text payload")
+		}
+
+		// whole text counts as a single key event, its length is capped by desktop
+		if !peer.limiter.Key() {
+			logger.Debug().Int("length", len(text)).Msg("text input rate limited")
+			return nil
+		}
+
+		if err := manager.desktop.ApplyInput(types.InputEvent{Type: "text", Text: string(text)}); err != nil {
+			logger.Warn().Err(err).Int("length", len(text)).Msg("text input failed")
+		} else {
+			peer.videoTrack.MarkInput()
+			logger.Trace().Int("length", len(text)).Msg("text input")
+		}
 	}
 
 	return nil
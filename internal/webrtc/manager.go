@@ -24,7 +24,6 @@ import (
 	"github.com/demodesk/neko/pkg/types/codec"
 	"github.com/demodesk/neko/pkg/types/event"
 	"github.com/demodesk/neko/pkg/types/message"
-	"github.com/demodesk/neko/pkg/utils"
 )
 
 const (
@@ -47,7 +46,7 @@ const (
 	rtcpPLIInterval = 3 * time.Second
 )
 
-func New(desktop types.DesktopManager, capture types.CaptureManager, config *config.WebRTC) *WebRTCManagerCtx {
+func New(sessions types.SessionManager, desktop types.DesktopManager, capture types.CaptureManager, config *config.WebRTC) *WebRTCManagerCtx {
 	logger := log.With().Str("module", "webrtc").Logger()
 
 	configuration := webrtc.Configuration{
@@ -74,6 +73,15 @@ func New(desktop types.DesktopManager, capture types.CaptureManager, config *con
 		configuration.ICEServers = ICEServers
 	}
 
+	var turnServer *turnServer
+	if config.TURNServer.Enabled {
+		var err error
+		turnServer, err = newTURNServer(logger, config.TURNServer, sessions, config.TURNSecret, config.TURNCredentialsTTL)
+		if err != nil {
+			logger.Panic().Err(err).Msg("unable to create turn server")
+		}
+	}
+
 	return &WebRTCManagerCtx{
 		logger:  logger,
 		config:  config,
@@ -81,6 +89,14 @@ func New(desktop types.DesktopManager, capture types.CaptureManager, config *con
 
 		webrtcConfiguration: configuration,
 
+		turnServer: turnServer,
+
+		bitrateControllers: map[string]types.BitrateControllerFactory{},
+		broadcasters:       map[types.StreamSinkManager]*rtpBroadcaster{},
+
+		nat1to1IPs: config.NAT1To1IPs,
+		shutdown:   make(chan struct{}),
+
 		desktop:     desktop,
 		capture:     capture,
 		curImage:    cursor.NewImage(logger, desktop),
@@ -103,18 +119,41 @@ type WebRTCManagerCtx struct {
 
 	tcpMux ice.TCPMux
 	udpMux ice.UDPMux
+	// optional listener for ICE-TCP, replaces TCP mux port
+	tcpMuxListener net.Listener
+
+	turnServer *turnServer
+
+	bitrateControllers   map[string]types.BitrateControllerFactory
+	bitrateControllersMu sync.RWMutex
+
+	// shared packetization for watch-only peers
+	broadcasters   map[types.StreamSinkManager]*rtpBroadcaster
+	broadcastersMu sync.Mutex
+
+	nat1to1IPs   []string
+	nat1to1IPsMu sync.RWMutex
+	shutdown     chan struct{}
 
 	camStop, micStop *func()
 }
 
+// SetTCPMuxListener makes ICE-TCP accept connections from given listener
+// instead of TCP mux port, it must be called before Start.
+func (manager *WebRTCManagerCtx) SetTCPMuxListener(listener net.Listener) {
+	manager.tcpMuxListener = listener
+}
+
 func (manager *WebRTCManagerCtx) Start() {
 	manager.curImage.Start()
 
 	logger := pionlog.New(manager.logger)
 
 	// add TCP Mux listener
-	if manager.config.TCPMux > 0 {
-		tcpListener, err := net.ListenTCP("tcp", &net.TCPAddr{
+	tcpListener := manager.tcpMuxListener
+	if tcpListener == nil && manager.config.TCPMux > 0 {
+		var err error
+		tcpListener, err = net.ListenTCP("tcp", &net.TCPAddr{
 			IP:   net.IP{0, 0, 0, 0},
 			Port: manager.config.TCPMux,
 		})
@@ -122,7 +161,9 @@ func (manager *WebRTCManagerCtx) Start() {
 		if err != nil {
 			manager.logger.Fatal().Err(err).Msg("unable to setup ice TCP mux")
 		}
+	}
 
+	if tcpListener != nil {
 		manager.tcpMux = ice.NewTCPMuxDefault(ice.TCPMuxParams{
 			Listener:        tcpListener,
 			Logger:          logger.NewLogger("ice-tcp"),
@@ -143,14 +184,27 @@ func (manager *WebRTCManagerCtx) Start() {
 		}
 	}
 
+	// start embedded TURN server
+	if manager.turnServer != nil {
+		if err := manager.turnServer.Start(logger); err != nil {
+			manager.logger.Fatal().Err(err).Msg("unable to start turn server")
+		}
+	}
+
+	// periodically refresh retrieved external IP address
+	if manager.config.NAT1To1Retrieved && manager.config.IpRetrievalInterval > 0 {
+		go manager.nat1to1Refresher(manager.config.IpRetrievalInterval)
+	}
+
 	manager.logger.Info().
 		Bool("icelite", manager.config.ICELite).
 		Bool("icetrickle", manager.config.ICETrickle).
 		Interface("iceservers-frontend", manager.config.ICEServersFrontend).
 		Interface("iceservers-backend", manager.config.ICEServersBackend).
-		Str("nat1to1", strings.Join(manager.config.NAT1To1IPs, ",")).
+		Str("nat1to1", strings.Join(manager.currentNAT1To1IPs(), ",")).
 		Str("epr", fmt.Sprintf("%d-%d", manager.config.EphemeralMin, manager.config.EphemeralMax)).
 		Int("tcpmux", manager.config.TCPMux).
+		Bool("singleport", manager.tcpMuxListener != nil).
 		Int("udpmux", manager.config.UDPMux).
 		Msg("webrtc starting")
 }
@@ -158,17 +212,98 @@ func (manager *WebRTCManagerCtx) Start() {
 func (manager *WebRTCManagerCtx) Shutdown() error {
 	manager.logger.Info().Msg("shutdown")
 
+	close(manager.shutdown)
+
 	manager.curImage.Shutdown()
 	manager.curPosition.Shutdown()
 
+	if manager.turnServer != nil {
+		if err := manager.turnServer.Shutdown(); err != nil {
+			manager.logger.Err(err).Msg("unable to shutdown turn server")
+		}
+	}
+
 	return nil
 }
 
+func (manager *WebRTCManagerCtx) currentNAT1To1IPs() []string {
+	manager.nat1to1IPsMu.RLock()
+	defer manager.nat1to1IPsMu.RUnlock()
+
+	return manager.nat1to1IPs
+}
+
+func (manager *WebRTCManagerCtx) nat1to1Refresher(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-manager.shutdown:
+			return
+		case <-ticker.C:
+		}
+
+		ip, err := manager.config.RetrieveIP()
+		if err != nil {
+			manager.logger.Warn().Err(err).Msg("external IP refresh failed")
+			continue
+		}
+
+		old := manager.currentNAT1To1IPs()
+		if len(old) == 1 && old[0] == ip {
+			continue
+		}
+
+		manager.nat1to1IPsMu.Lock()
+		manager.nat1to1IPs = []string{ip}
+		manager.nat1to1IPsMu.Unlock()
+
+		// relay IP of embedded TURN server follows external IP, if it was taken from it
+		if manager.turnServer != nil && len(old) > 0 && manager.turnServer.RelayIP().String() == old[0] {
+			manager.turnServer.SetRelayIP(net.ParseIP(ip))
+		}
+
+		// only new peer connections will use the new IP address
+		manager.logger.Info().
+			Str("old", strings.Join(old, ",")).
+			Str("new", ip).
+			Msg("external IP address changed")
+	}
+}
+
 func (manager *WebRTCManagerCtx) ICEServers() []types.ICEServer {
 	return manager.config.ICEServersFrontend
 }
 
-func (manager *WebRTCManagerCtx) newPeerConnection(logger zerolog.Logger, codecs []codec.RTPCodec) (*webrtc.PeerConnection, cc.BandwidthEstimator, error) {
+// SessionICEServers returns ICE servers for frontend with credentials generated for the session.
+func (manager *WebRTCManagerCtx) SessionICEServers(session types.Session) []types.ICEServer {
+	servers := manager.config.ICEServersFrontend
+
+	// generate ephemeral credentials for TURN servers
+	if manager.config.TURNSecret != "" {
+		username, credential := turnCredentials(manager.config.TURNSecret, session.ID(), manager.config.TURNCredentialsTTL)
+
+		servers = make([]types.ICEServer, 0, len(manager.config.ICEServersFrontend)+1)
+		for _, server := range manager.config.ICEServersFrontend {
+			if isTURNServer(server) {
+				server.Username = username
+				server.Credential = credential
+			}
+
+			servers = append(servers, server)
+		}
+	}
+
+	// advertise embedded TURN server
+	if manager.turnServer != nil {
+		servers = append(servers[:len(servers):len(servers)], manager.turnServer.ICEServer(session))
+	}
+
+	return servers
+}
+
+func (manager *WebRTCManagerCtx) newPeerConnection(logger zerolog.Logger, codecs []codec.RTPCodec, withEstimator bool) (*webrtc.PeerConnection, cc.BandwidthEstimator, error) {
 	// create media engine
 	engine := &webrtc.MediaEngine{}
 	for _, codec := range codecs {
@@ -184,7 +319,7 @@ func (manager *WebRTCManagerCtx) newPeerConnection(logger zerolog.Logger, codecs
 
 	settings.DisableMediaEngineCopy(true)
 	settings.SetICETimeouts(disconnectedTimeout, failedTimeout, keepAliveInterval)
-	settings.SetNAT1To1IPs(manager.config.NAT1To1IPs, webrtc.ICECandidateTypeHost)
+	settings.SetNAT1To1IPs(manager.currentNAT1To1IPs(), webrtc.ICECandidateTypeHost)
 	settings.SetLite(manager.config.ICELite)
 	// make sure server answer sdp setup as passive, to not force DTLS renegotiation
 	// otherwise iOS renegotiation fails with: Failed to set SSL role for the transport.
@@ -224,7 +359,7 @@ func (manager *WebRTCManagerCtx) newPeerConnection(logger zerolog.Logger, codecs
 
 	// create bandwidth estimator
 	estimatorChan := make(chan cc.BandwidthEstimator, 1)
-	if manager.config.Estimator.Enabled {
+	if withEstimator && manager.config.Estimator.Enabled {
 		congestionController, err := cc.NewInterceptor(func() (cc.BandwidthEstimator, error) {
 			return gcc.NewSendSideBWE(
 				gcc.SendSideBWEInitialBitrate(manager.config.Estimator.InitialBitrate),
@@ -266,6 +401,11 @@ func (manager *WebRTCManagerCtx) newPeerConnection(logger zerolog.Logger, codecs
 }
 
 func (manager *WebRTCManagerCtx) CreatePeer(session types.Session) (*webrtc.SessionDescription, types.WebRTCPeer, error) {
+	// sessions that cannot host, do not need data channel
+	if manager.config.WatchOnly && !session.Profile().CanHost {
+		return manager.createWatchPeer(session)
+	}
+
 	id := atomic.AddInt32(&manager.peerId, 1)
 
 	// get metrics for session
@@ -285,7 +425,7 @@ func (manager *WebRTCManagerCtx) CreatePeer(session types.Session) (*webrtc.Sess
 	videoCodec := video.Codec()
 
 	connection, estimator, err := manager.newPeerConnection(
-		logger, []codec.RTPCodec{audioCodec, videoCodec})
+		logger, []codec.RTPCodec{audioCodec, videoCodec}, true)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -321,9 +461,17 @@ func (manager *WebRTCManagerCtx) CreatePeer(session types.Session) (*webrtc.Sess
 		return nil, nil, err
 	}
 
+	// peer is referenced in track callbacks
+	var peer *WebRTCPeerCtx
+
 	// video track
 	videoRtcp := make(chan []rtcp.Packet, 1)
-	videoTrack, err := NewTrack(logger, videoCodec, connection, WithRtcpChan(videoRtcp))
+	videoTrack, err := NewTrack(logger, videoCodec, connection,
+		WithRtcpChan(videoRtcp),
+		WithInputLatency(func(latency time.Duration) {
+			peer.setInputToSample(latency)
+		}),
+	)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -339,26 +487,16 @@ func (manager *WebRTCManagerCtx) CreatePeer(session types.Session) (*webrtc.Sess
 		return nil, nil, err
 	}
 
-	peer := &WebRTCPeerCtx{
+	peer = &WebRTCPeerCtx{
 		logger:     logger,
 		session:    session,
 		metrics:    metrics,
 		connection: connection,
 		// bandwidth estimator
-		estimator: estimator,
-		estimateTrend: utils.NewTrendDetector(
-			utils.TrendDetectorParams{
-				// Probing
-				//RequiredSamples:        3,
-				//DownwardTrendThreshold: 0.0,
-				//CollapseValues:         false,
-				// Non-Probing
-				RequiredSamples:        8,
-				DownwardTrendThreshold: -0.5,
-				CollapseValues:         true,
-			}),
+		estimator:         estimator,
+		bitrateController: manager.newBitrateController(logger, session),
 		// stream selectors
-		video: video,
+		video: newLimitedStreamSelector(session, video),
 		audio: audio,
 		// tracks & channels
 		audioTrack:  audioTrack,
@@ -472,14 +610,46 @@ func (manager *WebRTCManagerCtx) CreatePeer(session types.Session) (*webrtc.Sess
 		logger.Info().Interface("data_channel", dc).Msg("got remote data channel")
 	})
 
+	if manager.config.RateLimit.Enabled {
+		peer.limiter = newInputLimiter(manager.config.RateLimit,
+			// apply coalesced cursor move
+			func(x, y int) {
+				if !session.IsHost() {
+					return
+				}
+
+				if err := manager.desktop.ApplyInput(types.InputEvent{Type: "move", X: x, Y: y}); err != nil {
+					logger.Warn().Err(err).Msg("coalesced move failed")
+					return
+				}
+				manager.curPosition.Set(x, y)
+				peer.videoTrack.MarkInput()
+			},
+			metrics.InputExcess,
+			// disconnect abusing session
+			func() {
+				logger.Warn().Msg("input rate limit exceeded repeatedly, disconnecting session")
+				metrics.InputAbuse()
+				session.DestroyWebSocketPeer("input rate limit exceeded")
+			},
+		)
+	}
+
+	recovery := manager.newConnectionRecovery(logger, session, peer, metrics)
+
 	var once sync.Once
 	connection.OnConnectionStateChange(func(state webrtc.PeerConnectionState) {
 		switch state {
 		case webrtc.PeerConnectionStateConnected:
 			session.SetWebRTCConnected(peer, true)
+			recovery.Connected()
 		case webrtc.PeerConnectionStateDisconnected,
 			webrtc.PeerConnectionStateFailed:
-			peer.Destroy()
+			if recovery != nil {
+				recovery.Start()
+			} else {
+				peer.Destroy()
+			}
 		case webrtc.PeerConnectionStateClosed:
 			// ensure we only run this once
 			once.Do(func() {
@@ -490,6 +660,8 @@ func (manager *WebRTCManagerCtx) CreatePeer(session types.Session) (*webrtc.Sess
 				audioTrack.Shutdown()
 				videoTrack.Shutdown()
 				close(videoRtcp)
+				peer.limiter.Close()
+				recovery.Close()
 			})
 		}
 
@@ -517,6 +689,11 @@ func (manager *WebRTCManagerCtx) CreatePeer(session types.Session) (*webrtc.Sess
 		if err != nil {
 			logger.Err(err).Msg("failed to set cursor position")
 		}
+
+		// measure latency using periodic pings
+		if manager.config.PingInterval > 0 {
+			go peer.pingSender(manager.config.PingInterval)
+		}
 	})
 
 	dataChannel.OnClose(func() {
@@ -525,7 +702,7 @@ func (manager *WebRTCManagerCtx) CreatePeer(session types.Session) (*webrtc.Sess
 	})
 
 	dataChannel.OnMessage(func(message webrtc.DataChannelMessage) {
-		if err := manager.handle(logger, message.Data, dataChannel, session); err != nil {
+		if err := manager.handle(logger, message.Data, dataChannel, session, peer); err != nil {
 			logger.Err(err).Msg("data handle failed")
 		}
 	})
@@ -567,6 +744,7 @@ func (manager *WebRTCManagerCtx) CreatePeer(session types.Session) (*webrtc.Sess
 
 	// start estimator reader
 	go peer.estimatorReader()
+	go videoLimitReader(logger, connection, session, peer, videoTrack.Stream)
 
 	return offer, peer, nil
 }
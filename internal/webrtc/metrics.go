@@ -20,6 +20,39 @@ type metricsManager struct {
 	mu sync.Mutex
 
 	sessions map[string]*metrics
+	// watch-only peers share metrics, they are not per session
+	watch *watchMetrics
+}
+
+type watchMetrics struct {
+	peers       prometheus.Gauge
+	connections prometheus.Counter
+}
+
+func (m *metricsManager) getWatch() *watchMetrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.watch != nil {
+		return m.watch
+	}
+
+	m.watch = &watchMetrics{
+		peers: promauto.NewGauge(prometheus.GaugeOpts{
+			Name:      "watch_peers",
+			Namespace: "neko",
+			Subsystem: "webrtc",
+			Help:      "Current number of watch-only peers.",
+		}),
+		connections: promauto.NewCounter(prometheus.CounterOpts{
+			Name:      "watch_connections",
+			Namespace: "neko",
+			Subsystem: "webrtc",
+			Help:      "Count of watch-only peer connections.",
+		}),
+	}
+
+	return m.watch
 }
 
 func newMetricsManager() *metricsManager {
@@ -215,15 +248,120 @@ func (m *metricsManager) getBySession(session types.Session) *metrics {
 				"transport":  "sctp",
 			},
 		}),
+
+		dataChannelRtt: promauto.NewHistogram(prometheus.HistogramOpts{
+			Name:      "data_channel_rtt",
+			Namespace: "neko",
+			Subsystem: "webrtc",
+			Help:      "Round trip time of data channel ping in seconds.",
+			Buckets:   prometheus.ExponentialBuckets(0.005, 2, 10),
+			ConstLabels: map[string]string{
+				"session_id": sessionId,
+			},
+		}),
+		clockOffset: promauto.NewHistogram(prometheus.HistogramOpts{
+			Name:      "clock_offset",
+			Namespace: "neko",
+			Subsystem: "webrtc",
+			Help:      "Difference between client and server clock in seconds.",
+			Buckets:   []float64{-10, -1, -0.5, -0.1, -0.05, -0.01, 0, 0.01, 0.05, 0.1, 0.5, 1, 10},
+			ConstLabels: map[string]string{
+				"session_id": sessionId,
+			},
+		}),
+		inputToSample: promauto.NewHistogram(prometheus.HistogramOpts{
+			Name:      "input_to_sample",
+			Namespace: "neko",
+			Subsystem: "webrtc",
+			Help:      "Time between host input and the next video sample written to the track in seconds.",
+			Buckets:   prometheus.ExponentialBuckets(0.005, 2, 10),
+			ConstLabels: map[string]string{
+				"session_id": sessionId,
+			},
+		}),
+
+		inputAbuseCount: promauto.NewCounter(prometheus.CounterOpts{
+			Name:      "input_abuse_count",
+			Namespace: "neko",
+			Subsystem: "webrtc",
+			Help:      "Count of disconnects caused by repeatedly exceeding input rate limit.",
+			ConstLabels: map[string]string{
+				"session_id": sessionId,
+			},
+		}),
+
+		recoveryAttempts: promauto.NewCounter(prometheus.CounterOpts{
+			Name:      "recovery_attempts",
+			Namespace: "neko",
+			Subsystem: "webrtc",
+			Help:      "Count of server initiated ICE restarts.",
+			ConstLabels: map[string]string{
+				"session_id": sessionId,
+			},
+		}),
+		recoverySuccess: promauto.NewCounter(prometheus.CounterOpts{
+			Name:      "recovery_success",
+			Namespace: "neko",
+			Subsystem: "webrtc",
+			Help:      "Count of connections recovered using ICE restart.",
+			ConstLabels: map[string]string{
+				"session_id": sessionId,
+			},
+		}),
+		recoveryFailed: promauto.NewCounter(prometheus.CounterOpts{
+			Name:      "recovery_failed",
+			Namespace: "neko",
+			Subsystem: "webrtc",
+			Help:      "Count of connections that could not be recovered using ICE restart.",
+			ConstLabels: map[string]string{
+				"session_id": sessionId,
+			},
+		}),
+		recoveryDuration: promauto.NewHistogram(prometheus.HistogramOpts{
+			Name:      "recovery_duration",
+			Namespace: "neko",
+			Subsystem: "webrtc",
+			Help:      "Time from connection loss until it was recovered using ICE restart in seconds.",
+			Buckets:   prometheus.ExponentialBuckets(0.5, 2, 8),
+			ConstLabels: map[string]string{
+				"session_id": sessionId,
+			},
+		}),
+	}
+
+	met.inputExcess = map[inputEventType]prometheus.Counter{}
+	for _, eventType := range []inputEventType{inputEventMove, inputEventKey, inputEventButton, inputEventScroll} {
+		met.inputExcess[eventType] = promauto.NewCounter(prometheus.CounterOpts{
+			Name:      "input_events_excess",
+			Namespace: "neko",
+			Subsystem: "webrtc",
+			Help:      "Input events over the rate limit, that were coalesced or dropped.",
+			ConstLabels: map[string]string{
+				"session_id": sessionId,
+				"type":       eventType.String(),
+			},
+		})
 	}
 
 	m.sessions[sessionId] = met
 	return met
 }
 
+// rtcpStats holds last values received in RTCP packets.
+type rtcpStats struct {
+	jitter       uint32
+	packetsLost  uint32
+	fractionLost uint8
+	nacks        uint64
+	remb         float32
+}
+
 type metrics struct {
 	sessionId string
 
+	rtcpStats   rtcpStats
+	rtcpStatsMu sync.Mutex
+
 	connectionState      prometheus.Gauge
 	connectionStateCount prometheus.Counter
 	connectionCount      prometheus.Counter
@@ -252,6 +390,18 @@ type metrics struct {
 	iceBytesReceived  prometheus.Gauge
 	sctpBytesSent     prometheus.Gauge
 	sctpBytesReceived prometheus.Gauge
+
+	dataChannelRtt prometheus.Histogram
+	clockOffset    prometheus.Histogram
+	inputToSample  prometheus.Histogram
+
+	inputExcess     map[inputEventType]prometheus.Counter
+	inputAbuseCount prometheus.Counter
+
+	recoveryAttempts prometheus.Counter
+	recoverySuccess  prometheus.Counter
+	recoveryFailed   prometheus.Counter
+	recoveryDuration prometheus.Histogram
 }
 
 func (met *metrics) reset() {
@@ -268,6 +418,17 @@ func (met *metrics) reset() {
 
 	met.receiverReportDelay.Set(0)
 	met.receiverReportJitter.Set(0)
+
+	met.rtcpStatsMu.Lock()
+	met.rtcpStats = rtcpStats{}
+	met.rtcpStatsMu.Unlock()
+}
+
+func (met *metrics) RTCPStats() rtcpStats {
+	met.rtcpStatsMu.Lock()
+	defer met.rtcpStatsMu.Unlock()
+
+	return met.rtcpStats
 }
 
 func (met *metrics) NewConnection() {
@@ -354,6 +515,10 @@ func (met *metrics) SetVideoID(videoId string) {
 
 func (met *metrics) SetReceiverEstimatedMaximumBitrate(bitrate float32) {
 	met.receiverEstimatedMaximumBitrate.Set(float64(bitrate))
+
+	met.rtcpStatsMu.Lock()
+	met.rtcpStats.remb = bitrate
+	met.rtcpStatsMu.Unlock()
 }
 
 func (met *metrics) SetReceiverEstimatedTargetBitrate(bitrate float64) {
@@ -364,6 +529,20 @@ func (met *metrics) SetReceiverReport(report rtcp.ReceptionReport) {
 	met.receiverReportDelay.Set(float64(report.Delay))
 	met.receiverReportJitter.Set(float64(report.Jitter))
 	met.receiverReportTotalLost.Set(float64(report.TotalLost))
+
+	met.rtcpStatsMu.Lock()
+	met.rtcpStats.jitter = report.Jitter
+	met.rtcpStats.packetsLost = report.TotalLost
+	met.rtcpStats.fractionLost = report.FractionLost
+	met.rtcpStatsMu.Unlock()
+}
+
+func (met *metrics) AddTransportLayerNacks(count int) {
+	met.transportLayerNacks.Add(float64(count))
+
+	met.rtcpStatsMu.Lock()
+	met.rtcpStats.nacks += uint64(count)
+	met.rtcpStatsMu.Unlock()
 }
 
 func (met *metrics) SetIceTransportStats(data webrtc.TransportStats) {
@@ -376,6 +555,38 @@ func (met *metrics) SetSctpTransportStats(data webrtc.TransportStats) {
 	met.sctpBytesReceived.Set(float64(data.BytesReceived))
 }
 
+func (met *metrics) ObserveLatency(rtt, clockOffset time.Duration) {
+	met.dataChannelRtt.Observe(rtt.Seconds())
+	met.clockOffset.Observe(clockOffset.Seconds())
+}
+
+func (met *metrics) ObserveInputToSample(latency time.Duration) {
+	met.inputToSample.Observe(latency.Seconds())
+}
+
+func (met *metrics) InputExcess(eventType inputEventType) {
+	if counter, ok := met.inputExcess[eventType]; ok {
+		counter.Add(1)
+	}
+}
+
+func (met *metrics) InputAbuse() {
+	met.inputAbuseCount.Add(1)
+}
+
+func (met *metrics) RecoveryAttempt() {
+	met.recoveryAttempts.Add(1)
+}
+
+func (met *metrics) RecoverySuccess(duration time.Duration) {
+	met.recoverySuccess.Add(1)
+	met.recoveryDuration.Observe(duration.Seconds())
+}
+
+func (met *metrics) RecoveryFailed() {
+	met.recoveryFailed.Add(1)
+}
+
 //
 // collectors
 //
@@ -401,7 +612,7 @@ func (met *metrics) rtcpReceiver(rtcpCh chan []rtcp.Packet) {
 			case *rtcp.TransportLayerNack:
 				for _, pair := range rtcpPacket.Nacks {
 					packetList := pair.PacketList()
-					met.transportLayerNacks.Add(float64(len(packetList)))
+					met.AddTransportLayerNacks(len(packetList))
 				}
 			}
 		}
@@ -14,6 +14,10 @@ const (
 	OP_TOUCH_BEGIN  = 0x08
 	OP_TOUCH_UPDATE = 0x09
 	OP_TOUCH_END    = 0x0a
+	// text input, followed by utf-8 encoded string of header length
+	OP_TEXT = 0x0b
+	// response to server ping
+	OP_SERVER_PONG = 0x0c
 )
 
 type Move struct {
@@ -53,3 +57,15 @@ type Touch struct {
 	Y        int32
 	Pressure uint8
 }
+
+type ServerPong struct {
+	ServerPing
+
+	// client's timestamp split into two uint32
+	ClientTs1 uint32
+	ClientTs2 uint32
+}
+
+func (p ServerPong) ClientTs() uint64 {
+	return (uint64(p.ClientTs1) * uint64(math.MaxUint32)) + uint64(p.ClientTs2)
+}
@@ -6,6 +6,7 @@ const (
 	OP_CURSOR_POSITION = 0x01
 	OP_CURSOR_IMAGE    = 0x02
 	OP_PONG            = 0x03
+	OP_SERVER_PING     = 0x04
 )
 
 type CursorPosition struct {
@@ -31,3 +32,13 @@ type Pong struct {
 func (p Pong) ServerTs() uint64 {
 	return (uint64(p.ServerTs1) * uint64(math.MaxUint32)) + uint64(p.ServerTs2)
 }
+
+type ServerPing struct {
+	// server's timestamp split into two uint32
+	ServerTs1 uint32
+	ServerTs2 uint32
+}
+
+func (p ServerPing) ServerTs() uint64 {
+	return (uint64(p.ServerTs1) * uint64(math.MaxUint32)) + uint64(p.ServerTs2)
+}
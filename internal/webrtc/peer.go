@@ -3,6 +3,7 @@ package webrtc
 import (
 	"bytes"
 	"encoding/binary"
+	"math"
 	"sync"
 	"time"
 
@@ -15,7 +16,7 @@ import (
 	"github.com/demodesk/neko/internal/webrtc/payload"
 	"github.com/demodesk/neko/pkg/types"
 	"github.com/demodesk/neko/pkg/types/event"
-	"github.com/demodesk/neko/pkg/utils"
+	"github.com/demodesk/neko/pkg/types/message"
 )
 
 type WebRTCPeerCtx struct {
@@ -25,8 +26,8 @@ type WebRTCPeerCtx struct {
 	metrics    *metrics
 	connection *webrtc.PeerConnection
 	// bandwidth estimator
-	estimator     cc.BandwidthEstimator
-	estimateTrend *utils.TrendDetector
+	estimator         cc.BandwidthEstimator
+	bitrateController types.BitrateController
 	// stream selectors
 	video types.StreamSelectorManager
 	audio types.StreamSinkManager
@@ -42,8 +43,17 @@ type WebRTCPeerCtx struct {
 	videoAuto       bool
 	videoDisabled   bool
 	audioDisabled   bool
+	// input
+	limiter *inputLimiter
+	// latency
+	stats     types.WebRTCPeerStats
+	statsStop chan struct{}
+	statsMu   sync.Mutex
 }
 
+// minimal interval of stats push to the client
+const minStatsInterval = 500 * time.Millisecond
+
 //
 // connection
 //
@@ -75,22 +85,26 @@ func (peer *WebRTCPeerCtx) CreateAnswer() (*webrtc.SessionDescription, error) {
 }
 
 func (peer *WebRTCPeerCtx) setLocalDescription(description webrtc.SessionDescription) (*webrtc.SessionDescription, error) {
-	if !peer.iceTrickle {
+	return setLocalDescription(peer.connection, peer.iceTrickle, description)
+}
+
+func setLocalDescription(connection *webrtc.PeerConnection, iceTrickle bool, description webrtc.SessionDescription) (*webrtc.SessionDescription, error) {
+	if !iceTrickle {
 		// Create channel that is blocked until ICE Gathering is complete
-		gatherComplete := webrtc.GatheringCompletePromise(peer.connection)
+		gatherComplete := webrtc.GatheringCompletePromise(connection)
 
-		if err := peer.connection.SetLocalDescription(description); err != nil {
+		if err := connection.SetLocalDescription(description); err != nil {
 			return nil, err
 		}
 
 		<-gatherComplete
 	} else {
-		if err := peer.connection.SetLocalDescription(description); err != nil {
+		if err := connection.SetLocalDescription(description); err != nil {
 			return nil, err
 		}
 	}
 
-	return peer.connection.LocalDescription(), nil
+	return connection.LocalDescription(), nil
 }
 
 func (peer *WebRTCPeerCtx) SetRemoteDescription(desc webrtc.SessionDescription) error {
@@ -108,6 +122,10 @@ func (peer *WebRTCPeerCtx) SetCandidate(candidate webrtc.ICECandidateInit) error
 }
 
 // TODO: Add shutdown function?
+func (peer *WebRTCPeerCtx) WatchOnly() bool {
+	return false
+}
+
 func (peer *WebRTCPeerCtx) Destroy() {
 	peer.mu.Lock()
 	defer peer.mu.Unlock()
@@ -123,35 +141,15 @@ func (peer *WebRTCPeerCtx) Destroy() {
 }
 
 func (peer *WebRTCPeerCtx) estimatorReader() {
-	conf := peer.estimatorConfig
-
-	// if estimator is not in debug mode, use a nop logger
-	var debugLogger zerolog.Logger
-	if conf.Debug {
-		debugLogger = peer.logger.With().Str("component", "estimator").Logger().Level(zerolog.DebugLevel)
-	} else {
-		debugLogger = zerolog.Nop()
-	}
-
 	// if estimator is disabled, do nothing
 	if peer.estimator == nil {
 		return
 	}
 
 	// use a ticker to get current client target bitrate
-	ticker := time.NewTicker(conf.ReadInterval)
+	ticker := time.NewTicker(peer.estimatorConfig.ReadInterval)
 	defer ticker.Stop()
 
-	// since when is the estimate stable/unstable
-	stableSince := time.Now() // we asume stable at start
-	unstableSince := time.Time{}
-	// since when are we neutral but cannot accomodate current bitrate
-	// we migt be stalled or estimator just reached zer (very bad connection)
-	stalledSince := time.Time{}
-	// when was the last upgrade/downgrade
-	lastUpgradeTime := time.Time{}
-	lastDowngradeTime := time.Time{}
-
 	for range ticker.C {
 		targetBitrate := peer.estimator.GetTargetBitrate()
 		peer.metrics.SetReceiverEstimatedTargetBitrate(float64(targetBitrate))
@@ -162,151 +160,39 @@ func (peer *WebRTCPeerCtx) estimatorReader() {
 		}
 
 		// if estimation or video is disabled, do nothing
-		if !peer.videoAuto || peer.videoDisabled || peer.paused || conf.Passive {
+		if !peer.videoAuto || peer.videoDisabled || peer.paused || peer.estimatorConfig.Passive {
 			continue
 		}
 
-		// get trend direction to decide if we should upgrade or downgrade
-		peer.estimateTrend.AddValue(int64(targetBitrate))
-		direction := peer.estimateTrend.GetDirection()
-
-		// get current stream bitrate
+		// get current stream
 		stream, ok := peer.videoTrack.Stream()
 		if !ok {
-			debugLogger.Warn().Msg("looks like we don't have a stream yet, skipping bitrate estimation")
-			continue
-		}
-
-		// if stream bitrate is 0, we need to wait for some time until we get a valid value
-		streamId, streamBitrate := stream.ID(), stream.Bitrate()
-		if streamBitrate == 0 {
-			debugLogger.Warn().Msg("looks like stream bitrate is 0, we need to wait for some time")
+			peer.logger.Debug().Msg("looks like we don't have a stream yet, skipping bitrate estimation")
 			continue
 		}
 
-		// check whats the difference between target and stream bitrate
-		diff := float64(targetBitrate) / float64(streamBitrate)
-
-		debugLogger.Info().
-			Float64("diff", diff).
-			Int("target_bitrate", targetBitrate).
-			Uint64("stream_bitrate", streamBitrate).
-			Str("direction", direction.String()).
-			Msg("got bitrate from estimator")
-
-		// if we can accomodate current stream or we are not netural anymore,
-		// we are not stalled so we reset the stalled time
-		if direction != utils.TrendDirectionNeutral || diff > 1+conf.DiffThreshold {
-			stalledSince = time.Now()
-		}
-
-		// if we are neutral and stalled for too long, we might be congesting
-		stalled := direction == utils.TrendDirectionNeutral && time.Since(stalledSince) > conf.StalledDuration
-		if stalled {
-			debugLogger.Warn().
-				Time("stalled_since", stalledSince).
-				Msgf("it looks like we are stalled")
-		}
-
-		// if we have an downward trend or are stalled, we might be congesting
-		if direction == utils.TrendDirectionDownward || stalled {
-			// we reset the stable time because we are congesting
-			stableSince = time.Now()
-
-			// if we downgraded recently, we wait for some more time
-			if time.Since(lastDowngradeTime) < conf.DowngradeBackoff {
-				debugLogger.Debug().
-					Time("last_downgrade", lastDowngradeTime).
-					Msgf("downgraded recently, waiting for at least %v", conf.DowngradeBackoff)
-				continue
-			}
-
-			// if we are not unstable but we fluctuate we should wait for some more time
-			if time.Since(unstableSince) < conf.UnstableDuration {
-				debugLogger.Debug().
-					Time("unstable_since", unstableSince).
-					Msgf("we are not unstable long enough, waiting for at least %v", conf.UnstableDuration)
-				continue
-			}
-
-			// if we still have a big difference between target and stream bitrate, we wait for some more time
-			if conf.DiffThreshold >= 0 && diff > 1+conf.DiffThreshold {
-				debugLogger.Debug().
-					Float64("diff", diff).
-					Float64("threshold", conf.DiffThreshold).
-					Msgf("we still have a big difference between target and stream bitrate, " +
-						"therefore we still should be able to accomodate current stream")
-				continue
-			}
-
-			err := peer.SetVideo(types.PeerVideoRequest{
-				Selector: &types.StreamSelector{
-					ID:   streamId,
-					Type: types.StreamSelectorTypeLower,
-				},
-			})
-			if err != nil && err != types.ErrWebRTCStreamNotFound {
-				peer.logger.Warn().Err(err).Msg("failed to downgrade video stream")
-			}
-			lastDowngradeTime = time.Now()
+		peer.statsMu.Lock()
+		rtt := time.Duration(peer.stats.RTT * float64(time.Millisecond))
+		peer.statsMu.Unlock()
 
-			if err == types.ErrWebRTCStreamNotFound {
-				debugLogger.Info().Msg("looks like we are already on the lowest stream")
-			} else {
-				debugLogger.Info().Msg("downgraded video stream")
-			}
-			continue
+		sample := types.BitrateSample{
+			TargetBitrate: targetBitrate,
+			FractionLost:  float64(peer.metrics.RTCPStats().fractionLost) / 256,
+			RTT:           rtt,
+			Time:          time.Now(),
 		}
 
-		// we reset the unstable time because we are not congesting
-		unstableSince = time.Now()
-
-		// if we have a neutral or upward trend, that means our estimate is stable
-		// if we are on the highest stream, we don't need to do anything
-		// but if there is a higher stream, we should try to upgrade and see if it works
-
-		// if we upgraded recently, we wait for some more time
-		if time.Since(lastUpgradeTime) < conf.UpgradeBackoff {
-			debugLogger.Debug().
-				Time("last_upgrade", lastUpgradeTime).
-				Msgf("upgraded recently, waiting for at least %v", conf.UpgradeBackoff)
-			continue
-		}
-
-		// if we are not stable for long enough, we wait for some more time
-		// because bandwidth estimation might fluctuate
-		if time.Since(stableSince) < conf.StableDuration {
-			debugLogger.Debug().
-				Time("stable_since", stableSince).
-				Msgf("we are not stable long enough, waiting for at least %v", conf.StableDuration)
-			continue
-		}
-
-		// upgrade only if estimated bitrate passed the threshold
-		if conf.DiffThreshold >= 0 && diff < 1+conf.DiffThreshold {
-			debugLogger.Debug().
-				Float64("diff", diff).
-				Float64("threshold", conf.DiffThreshold).
-				Msgf("looks like we don't have enough bitrate to accomodate higher stream, " +
-					"therefore we should wait for some more time")
+		// let bitrate controller decide if we should switch stream
+		selector := peer.bitrateController.Next(sample, stream, peer.video)
+		if selector == nil {
 			continue
 		}
 
 		err := peer.SetVideo(types.PeerVideoRequest{
-			Selector: &types.StreamSelector{
-				ID:   streamId,
-				Type: types.StreamSelectorTypeHigher,
-			},
+			Selector: selector,
 		})
 		if err != nil && err != types.ErrWebRTCStreamNotFound {
-			peer.logger.Warn().Err(err).Msg("failed to upgrade video stream")
-		}
-		lastUpgradeTime = time.Now()
-
-		if err == types.ErrWebRTCStreamNotFound {
-			debugLogger.Info().Msg("looks like we are already on the highest stream")
-		} else {
-			debugLogger.Info().Msg("upgraded video stream")
+			peer.logger.Warn().Err(err).Str("type", selector.Type.String()).Msg("failed to switch video stream")
 		}
 	}
 }
@@ -541,3 +427,182 @@ func (peer *WebRTCPeerCtx) SendCursorImage(cur *types.CursorImage, img []byte) e
 
 	return peer.dataChannel.Send(buffer.Bytes())
 }
+
+func (peer *WebRTCPeerCtx) sendServerPing() error {
+	peer.mu.Lock()
+	defer peer.mu.Unlock()
+
+	header := payload.Header{
+		Event:  payload.OP_SERVER_PING,
+		Length: 11,
+	}
+
+	// generate server timestamp
+	serverTs := uint64(time.Now().UnixMilli())
+
+	data := payload.ServerPing{
+		ServerTs1: uint32(serverTs / math.MaxUint32),
+		ServerTs2: uint32(serverTs % math.MaxUint32),
+	}
+
+	buffer := &bytes.Buffer{}
+
+	if err := binary.Write(buffer, binary.BigEndian, header); err != nil {
+		return err
+	}
+
+	if err := binary.Write(buffer, binary.BigEndian, data); err != nil {
+		return err
+	}
+
+	return peer.dataChannel.Send(buffer.Bytes())
+}
+
+//
+// latency
+//
+
+func (peer *WebRTCPeerCtx) pingSender(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if peer.connection.ConnectionState() == webrtc.PeerConnectionStateClosed {
+			break
+		}
+
+		if err := peer.sendServerPing(); err != nil {
+			peer.logger.Debug().Err(err).Msg("failed to send server ping")
+		}
+	}
+}
+
+func (peer *WebRTCPeerCtx) handleServerPong(pong *payload.ServerPong) {
+	now := time.Now().UnixMilli()
+	serverTs := int64(pong.ServerTs())
+	clientTs := int64(pong.ClientTs())
+
+	rtt := time.Duration(now-serverTs) * time.Millisecond
+	// client received ping approximately half of the round trip after it was sent
+	offset := time.Duration(clientTs-serverTs)*time.Millisecond - rtt/2
+
+	peer.statsMu.Lock()
+	peer.stats.RTT = float64(rtt) / float64(time.Millisecond)
+	peer.stats.ClockOffset = float64(offset) / float64(time.Millisecond)
+	peer.statsMu.Unlock()
+
+	peer.metrics.ObserveLatency(rtt, offset)
+}
+
+func (peer *WebRTCPeerCtx) setInputToSample(latency time.Duration) {
+	peer.statsMu.Lock()
+	peer.stats.InputToSample = float64(latency) / float64(time.Millisecond)
+	peer.statsMu.Unlock()
+
+	peer.metrics.ObserveInputToSample(latency)
+}
+
+//
+// stats
+//
+
+func (peer *WebRTCPeerCtx) Stats() types.WebRTCPeerStats {
+	peer.statsMu.Lock()
+	stats := peer.stats
+	peer.statsMu.Unlock()
+
+	rtcpStats := peer.metrics.RTCPStats()
+	stats.Jitter = rtcpStats.jitter
+	stats.PacketsLost = rtcpStats.packetsLost
+	stats.FractionLost = float64(rtcpStats.fractionLost) / 256
+	stats.NACKs = rtcpStats.nacks
+	stats.REMB = float64(rtcpStats.remb)
+
+	if peer.estimator != nil {
+		stats.TargetBitrate = float64(peer.estimator.GetTargetBitrate())
+	}
+
+	if stream, ok := peer.videoTrack.Stream(); ok {
+		stats.VideoID = stream.ID()
+	}
+
+	report := peer.connection.GetStats()
+
+	if transport, ok := report["iceTransport"].(webrtc.TransportStats); ok {
+		stats.BytesSent = transport.BytesSent
+	}
+
+	// find selected candidate pair
+	for _, entry := range report {
+		pair, ok := entry.(webrtc.ICECandidatePairStats)
+		if !ok || !pair.Nominated {
+			continue
+		}
+
+		stats.ICERTT = pair.CurrentRoundTripTime * 1000
+		stats.LocalCandidate = iceCandidateFromStats(report[pair.LocalCandidateID])
+		stats.RemoteCandidate = iceCandidateFromStats(report[pair.RemoteCandidateID])
+		break
+	}
+
+	return stats
+}
+
+func iceCandidateFromStats(entry webrtc.Stats) *types.WebRTCICECandidate {
+	candidate, ok := entry.(webrtc.ICECandidateStats)
+	if !ok {
+		return nil
+	}
+
+	return &types.WebRTCICECandidate{
+		Address:  candidate.IP,
+		Port:     int(candidate.Port),
+		Protocol: candidate.Protocol,
+		Type:     candidate.CandidateType.String(),
+	}
+}
+
+func (peer *WebRTCPeerCtx) SetStatsInterval(interval time.Duration) {
+	peer.statsMu.Lock()
+	defer peer.statsMu.Unlock()
+
+	// stop previous stats push
+	if peer.statsStop != nil {
+		close(peer.statsStop)
+		peer.statsStop = nil
+	}
+
+	if interval <= 0 {
+		return
+	}
+
+	if interval < minStatsInterval {
+		interval = minStatsInterval
+	}
+
+	stop := make(chan struct{})
+	peer.statsStop = stop
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+
+			if peer.connection.ConnectionState() == webrtc.PeerConnectionStateClosed {
+				return
+			}
+
+			peer.session.Send(
+				event.SIGNAL_STATS,
+				message.SignalStats{
+					WebRTCPeerStats: peer.Stats(),
+				})
+		}
+	}()
+}
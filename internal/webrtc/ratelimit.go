@@ -0,0 +1,250 @@
+package webrtc
+
+import (
+	"sync"
+	"time"
+
+	"github.com/demodesk/neko/internal/config"
+)
+
+type inputEventType int
+
+const (
+	inputEventMove inputEventType = iota
+	inputEventKey
+	inputEventButton
+	inputEventScroll
+)
+
+func (t inputEventType) String() string {
+	switch t {
+	case inputEventMove:
+		return "move"
+	case inputEventKey:
+		return "key"
+	case inputEventButton:
+		return "button"
+	case inputEventScroll:
+		return "scroll"
+	default:
+		return "unknown"
+	}
+}
+
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+}
+
+func (b *tokenBucket) allow(now time.Time) bool {
+	// zero rate means unlimited
+	if b.rate <= 0 {
+		return true
+	}
+
+	b.refill(now)
+	if b.tokens < 1 {
+		return false
+	}
+
+	b.tokens--
+	return true
+}
+
+// wait returns how long it takes until next token is available.
+func (b *tokenBucket) wait() time.Duration {
+	if b.rate <= 0 || b.tokens >= 1 {
+		return 0
+	}
+
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+// inputLimiter applies per-session rate limits on input events, nil limiter allows everything.
+type inputLimiter struct {
+	mu     sync.Mutex
+	config config.WebRTCRateLimit
+
+	move   tokenBucket
+	key    tokenBucket
+	button tokenBucket
+	scroll tokenBucket
+
+	// latest move that has been held back, applied when tokens are available
+	pendingMove  *struct{ x, y int }
+	pendingTimer *time.Timer
+	applyMove    func(x, y int)
+
+	violations      int
+	violationsStart time.Time
+
+	onExcess func(eventType inputEventType)
+	onAbuse  func()
+	closed   bool
+}
+
+func newInputLimiter(config config.WebRTCRateLimit, applyMove func(x, y int), onExcess func(inputEventType), onAbuse func()) *inputLimiter {
+	return &inputLimiter{
+		config:          config,
+		move:            newTokenBucket(config.MoveRate, config.MoveBurst),
+		key:             newTokenBucket(config.KeyRate, config.KeyBurst),
+		button:          newTokenBucket(config.ButtonRate, config.ButtonBurst),
+		scroll:          newTokenBucket(config.ScrollRate, config.ScrollBurst),
+		applyMove:       applyMove,
+		violationsStart: time.Now(),
+		onExcess:        onExcess,
+		onAbuse:         onAbuse,
+	}
+}
+
+// Move returns true if move can be applied immediately. Otherwise it is coalesced
+// with other held back moves and only the latest one is applied later.
+func (l *inputLimiter) Move(x, y int) bool {
+	if l == nil {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return false
+	}
+
+	now := time.Now()
+	if l.pendingMove == nil && l.move.allow(now) {
+		return true
+	}
+
+	l.pendingMove = &struct{ x, y int }{x, y}
+	if l.pendingTimer == nil {
+		l.move.refill(now)
+		l.pendingTimer = time.AfterFunc(l.move.wait(), l.flushMove)
+	}
+
+	// coalescing is expected handling of fast mice, so it is not a violation
+	l.onExcess(inputEventMove)
+	return false
+}
+
+func (l *inputLimiter) flushMove() {
+	l.mu.Lock()
+	move := l.pendingMove
+	l.pendingMove = nil
+	l.pendingTimer = nil
+	closed := l.closed
+
+	if move != nil {
+		// consume token for coalesced move
+		l.move.refill(time.Now())
+		l.move.tokens--
+	}
+	l.mu.Unlock()
+
+	if move != nil && !closed {
+		l.applyMove(move.x, move.y)
+	}
+}
+
+// Key returns true if key down event can be applied.
+func (l *inputLimiter) Key() bool {
+	if l == nil {
+		return true
+	}
+
+	return l.allow(&l.key, inputEventKey)
+}
+
+// Button returns true if button down event can be applied.
+func (l *inputLimiter) Button() bool {
+	if l == nil {
+		return true
+	}
+
+	return l.allow(&l.button, inputEventButton)
+}
+
+// Scroll returns true if scroll event can be applied.
+func (l *inputLimiter) Scroll() bool {
+	if l == nil {
+		return true
+	}
+
+	return l.allow(&l.scroll, inputEventScroll)
+}
+
+func (l *inputLimiter) allow(bucket *tokenBucket, eventType inputEventType) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return false
+	}
+
+	now := time.Now()
+	if bucket.allow(now) {
+		return true
+	}
+
+	l.excess(now, eventType)
+	return false
+}
+
+// excess reports dropped event and counts it as violation.
+func (l *inputLimiter) excess(now time.Time, eventType inputEventType) {
+	l.onExcess(eventType)
+
+	if l.config.MaxViolations <= 0 {
+		return
+	}
+
+	if now.Sub(l.violationsStart) > l.config.ViolationWindow {
+		l.violations = 0
+		l.violationsStart = now
+	}
+
+	l.violations++
+	if l.violations == l.config.MaxViolations {
+		l.closed = true
+		go l.onAbuse()
+	}
+}
+
+func (l *inputLimiter) Close() {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.closed = true
+	if l.pendingTimer != nil {
+		l.pendingTimer.Stop()
+		l.pendingTimer = nil
+	}
+	l.pendingMove = nil
+}
@@ -0,0 +1,66 @@
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/demodesk/neko/internal/config"
+)
+
+func TestInputLimiter_moveFlood(t *testing.T) {
+	excess := 0
+	limiter := newInputLimiter(config.WebRTCRateLimit{
+		MoveRate:        1,
+		MoveBurst:       1,
+		MaxViolations:   3,
+		ViolationWindow: time.Minute,
+	}, func(x, y int) {}, func(inputEventType) { excess++ }, func() { t.Errorf("limiter reported abuse for coalesced moves") })
+	defer limiter.Close()
+
+	if !limiter.Move(0, 0) {
+		t.Fatalf("limiter.Move() expected first move to be allowed")
+	}
+
+	// coalesced moves are reported, but do not count as violations
+	for i := 0; i < 10; i++ {
+		if limiter.Move(i, i) {
+			t.Errorf("limiter.Move() expected move %d to be coalesced", i)
+		}
+	}
+
+	if excess != 10 {
+		t.Errorf("limiter reported %d excess moves, want 10", excess)
+	}
+}
+
+func TestInputLimiter_scrollFlood(t *testing.T) {
+	abused := make(chan struct{})
+	limiter := newInputLimiter(config.WebRTCRateLimit{
+		ScrollRate:      1,
+		ScrollBurst:     1,
+		MaxViolations:   3,
+		ViolationWindow: time.Minute,
+	}, func(x, y int) {}, func(inputEventType) {}, func() { close(abused) })
+	defer limiter.Close()
+
+	if !limiter.Scroll() {
+		t.Fatalf("limiter.Scroll() expected first scroll to be allowed")
+	}
+
+	// dropped events count as violations
+	for i := 0; i < 3; i++ {
+		if limiter.Scroll() {
+			t.Errorf("limiter.Scroll() expected scroll %d to be dropped", i)
+		}
+	}
+
+	select {
+	case <-abused:
+	case <-time.After(time.Second):
+		t.Fatalf("limiter expected to report abuse after scroll flood")
+	}
+
+	if limiter.Key() {
+		t.Errorf("limiter.Key() expected closed limiter to drop events")
+	}
+}
@@ -0,0 +1,193 @@
+package webrtc
+
+import (
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/demodesk/neko/internal/config"
+	"github.com/demodesk/neko/pkg/types"
+	"github.com/demodesk/neko/pkg/types/event"
+	"github.com/demodesk/neko/pkg/types/message"
+)
+
+// newConnectionRecovery returns nil if recovery is disabled, metrics are optional.
+func (manager *WebRTCManagerCtx) newConnectionRecovery(logger zerolog.Logger, session types.Session, peer types.WebRTCPeer, metrics *metrics) *connectionRecovery {
+	if !manager.config.Recovery.Enabled {
+		return nil
+	}
+
+	return newConnectionRecovery(logger, manager.config.Recovery, metrics,
+		// send ICE restart offer
+		func() error {
+			if !session.State().IsConnected {
+				return types.ErrSessionNotConnected
+			}
+
+			offer, err := peer.CreateOffer(true)
+			if err != nil {
+				return err
+			}
+
+			session.Send(
+				event.SIGNAL_RESTART,
+				message.SignalDescription{
+					SDP: offer.SDP,
+				})
+
+			return nil
+		},
+		peer.Destroy,
+	)
+}
+
+// connectionRecovery tries to recover disconnected or failed peer connection using
+// server initiated ICE restarts, before the peer is destroyed. Nil recovery does nothing.
+type connectionRecovery struct {
+	mu      sync.Mutex
+	logger  zerolog.Logger
+	config  config.WebRTCRecovery
+	metrics *metrics
+
+	running   bool
+	closed    bool
+	connected chan struct{}
+	shutdown  chan struct{}
+
+	restart func() error
+	destroy func()
+}
+
+func newConnectionRecovery(logger zerolog.Logger, config config.WebRTCRecovery, metrics *metrics, restart func() error, destroy func()) *connectionRecovery {
+	return &connectionRecovery{
+		logger:   logger.With().Str("component", "recovery").Logger(),
+		config:   config,
+		metrics:  metrics,
+		shutdown: make(chan struct{}),
+		restart:  restart,
+		destroy:  destroy,
+	}
+}
+
+// Start begins recovery of the connection, if it is not already running.
+func (r *connectionRecovery) Start() {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.running || r.closed {
+		return
+	}
+
+	r.running = true
+	r.connected = make(chan struct{})
+	go r.recover(r.connected)
+}
+
+// Connected notifies running recovery that connection is established again.
+func (r *connectionRecovery) Connected() {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.running {
+		return
+	}
+
+	r.running = false
+	close(r.connected)
+}
+
+// Close stops running recovery, it cannot be started again.
+func (r *connectionRecovery) Close() {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return
+	}
+
+	r.closed = true
+	close(r.shutdown)
+}
+
+// wait returns true if connection has been established within timeout.
+func (r *connectionRecovery) wait(connected chan struct{}, timeout time.Duration) (ok bool, closed bool) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-connected:
+		return true, false
+	case <-r.shutdown:
+		return false, true
+	case <-timer.C:
+		return false, false
+	}
+}
+
+func (r *connectionRecovery) recover(connected chan struct{}) {
+	start := time.Now()
+
+	// connection might recover by itself, e.g. after short packet loss
+	if ok, closed := r.wait(connected, r.config.GracePeriod); ok || closed {
+		if ok {
+			r.logger.Debug().Msg("connection recovered without ICE restart")
+		}
+		return
+	}
+
+	for attempt := 1; attempt <= r.config.MaxAttempts; attempt++ {
+		logger := r.logger.With().Int("attempt", attempt).Logger()
+		logger.Info().Msg("sending ICE restart offer")
+		if r.metrics != nil {
+			r.metrics.RecoveryAttempt()
+		}
+
+		// if offer could not be sent, e.g. because websocket is reconnecting,
+		// we still wait for the timeout and try again
+		if err := r.restart(); err != nil {
+			logger.Warn().Err(err).Msg("unable to send ICE restart offer")
+		}
+
+		ok, closed := r.wait(connected, r.config.AttemptTimeout)
+		if closed {
+			return
+		}
+
+		if ok {
+			duration := time.Since(start)
+			logger.Info().Dur("duration", duration).Msg("connection recovered using ICE restart")
+			if r.metrics != nil {
+				r.metrics.RecoverySuccess(duration)
+			}
+			return
+		}
+	}
+
+	r.mu.Lock()
+	// connection might have been established just now
+	if !r.running {
+		r.mu.Unlock()
+		return
+	}
+	r.running = false
+	r.mu.Unlock()
+
+	r.logger.Warn().Msg("unable to recover connection, destroying peer")
+	if r.metrics != nil {
+		r.metrics.RecoveryFailed()
+	}
+	r.destroy()
+}
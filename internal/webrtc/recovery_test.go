@@ -0,0 +1,108 @@
+package webrtc
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/demodesk/neko/internal/config"
+)
+
+type testRecovery struct {
+	*connectionRecovery
+	restarts  atomic.Int32
+	destroyed chan struct{}
+}
+
+func newTestRecovery(onRestart func(r *testRecovery)) *testRecovery {
+	r := &testRecovery{destroyed: make(chan struct{})}
+	r.connectionRecovery = newConnectionRecovery(zerolog.Nop(), config.WebRTCRecovery{
+		Enabled:        true,
+		GracePeriod:    20 * time.Millisecond,
+		AttemptTimeout: 20 * time.Millisecond,
+		MaxAttempts:    3,
+	}, nil, func() error {
+		r.restarts.Add(1)
+		if onRestart != nil {
+			onRestart(r)
+		}
+		return nil
+	}, func() {
+		close(r.destroyed)
+	})
+	return r
+}
+
+func (r *testRecovery) waitDestroyed(t *testing.T, want bool) {
+	t.Helper()
+
+	select {
+	case <-r.destroyed:
+		if !want {
+			t.Errorf("peer destroyed, but connection recovered")
+		}
+	case <-time.After(200 * time.Millisecond):
+		if want {
+			t.Errorf("peer not destroyed after all attempts failed")
+		}
+	}
+}
+
+func TestConnectionRecovery_gracePeriod(t *testing.T) {
+	r := newTestRecovery(nil)
+	defer r.Close()
+
+	r.Start()
+	r.Connected()
+
+	r.waitDestroyed(t, false)
+	if n := r.restarts.Load(); n != 0 {
+		t.Errorf("restart called %d times, want 0", n)
+	}
+}
+
+func TestConnectionRecovery_restart(t *testing.T) {
+	// connection is established after the second ICE restart
+	r := newTestRecovery(func(r *testRecovery) {
+		if r.restarts.Load() == 2 {
+			r.Connected()
+		}
+	})
+	defer r.Close()
+
+	r.Start()
+
+	r.waitDestroyed(t, false)
+	if n := r.restarts.Load(); n != 2 {
+		t.Errorf("restart called %d times, want 2", n)
+	}
+}
+
+func TestConnectionRecovery_failed(t *testing.T) {
+	r := newTestRecovery(nil)
+	defer r.Close()
+
+	r.Start()
+
+	r.waitDestroyed(t, true)
+	if n := r.restarts.Load(); n != 3 {
+		t.Errorf("restart called %d times, want 3", n)
+	}
+}
+
+func TestConnectionRecovery_close(t *testing.T) {
+	r := newTestRecovery(nil)
+
+	r.Start()
+	r.Close()
+
+	r.waitDestroyed(t, false)
+
+	// nil recovery is no-op
+	var nilRecovery *connectionRecovery
+	nilRecovery.Start()
+	nilRecovery.Connected()
+	nilRecovery.Close()
+}
@@ -4,6 +4,8 @@ import (
 	"errors"
 	"io"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/pion/rtcp"
 	"github.com/pion/webrtc/v3"
@@ -24,10 +26,20 @@ type Track struct {
 	paused   bool
 	stream   types.StreamSinkManager
 	streamMu sync.Mutex
+
+	// time of the first host input not yet followed by a sample
+	inputTs        atomic.Int64
+	onInputLatency func(latency time.Duration)
 }
 
 type trackOption func(*Track)
 
+func WithInputLatency(listener func(latency time.Duration)) trackOption {
+	return func(t *Track) {
+		t.onInputLatency = listener
+	}
+}
+
 func WithRtcpChan(rtcp chan []rtcp.Packet) trackOption {
 	return func(t *Track) {
 		t.rtcpCh = rtcp
@@ -97,6 +109,11 @@ func (t *Track) sampleReader() {
 			return
 		}
 
+		// tag the sample that follows host input
+		if ts := t.inputTs.Swap(0); ts != 0 && t.onInputLatency != nil {
+			t.onInputLatency(time.Since(time.Unix(0, ts)))
+		}
+
 		err := t.track.WriteSample(media.Sample{
 			Data:      sample.Data,
 			Duration:  sample.Duration,
@@ -113,6 +130,11 @@ func (t *Track) WriteSample(sample types.Sample) {
 	t.sample <- sample
 }
 
+// MarkInput records host input, so that latency to the following sample can be measured.
+func (t *Track) MarkInput() {
+	t.inputTs.CompareAndSwap(0, time.Now().UnixNano())
+}
+
 // --- stream ---
 
 func (t *Track) SetStream(stream types.StreamSinkManager) (bool, error) {
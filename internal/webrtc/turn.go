@@ -0,0 +1,257 @@
+package webrtc
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pion/logging"
+	"github.com/pion/turn/v2"
+	"github.com/rs/zerolog"
+
+	"github.com/demodesk/neko/internal/config"
+	"github.com/demodesk/neko/pkg/types"
+	"github.com/demodesk/neko/pkg/utils"
+)
+
+// turnCredentials generates ephemeral credentials compatible with coturn use-auth-secret
+// (TURN REST API), where username is expiry timestamp followed by user identifier.
+func turnCredentials(secret, sessionId string, ttl time.Duration) (username, credential string) {
+	username = fmt.Sprintf("%d:%s", time.Now().Add(ttl).Unix(), sessionId)
+	credential = turnCredential(secret, username)
+	return
+}
+
+func turnCredential(secret, username string) string {
+	mac := hmac.New(sha1.New, []byte(secret))
+	mac.Write([]byte(username))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func isTURNServer(server types.ICEServer) bool {
+	for _, url := range server.URLs {
+		if strings.HasPrefix(url, "turn:") || strings.HasPrefix(url, "turns:") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// turnRelayAddressGenerator listens on all interfaces and advertises current relay IP.
+type turnRelayAddressGenerator struct {
+	turn.RelayAddressGeneratorStatic
+	relayIP func() net.IP
+}
+
+func (g *turnRelayAddressGenerator) AllocatePacketConn(network string, requestedPort int) (net.PacketConn, net.Addr, error) {
+	conn, addr, err := g.RelayAddressGeneratorStatic.AllocatePacketConn(network, requestedPort)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if udpAddr, ok := addr.(*net.UDPAddr); ok {
+		udpAddr.IP = g.relayIP()
+	}
+
+	return conn, addr, nil
+}
+
+type turnServer struct {
+	logger   zerolog.Logger
+	config   config.WebRTCTURNServer
+	sessions types.SessionManager
+	secret   string
+	ttl      time.Duration
+	server   *turn.Server
+
+	// relay IP can change, when external IP is refreshed
+	relayIPMu sync.RWMutex
+	relayIP   net.IP
+}
+
+func newTURNServer(logger zerolog.Logger, config config.WebRTCTURNServer, sessions types.SessionManager, secret string, ttl time.Duration) (*turnServer, error) {
+	// generate random secret, if none is shared with external servers
+	if secret == "" {
+		var err error
+		secret, err = utils.NewUID(32)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &turnServer{
+		logger:   logger.With().Str("submodule", "turn").Logger(),
+		config:   config,
+		sessions: sessions,
+		secret:   secret,
+		ttl:      ttl,
+	}, nil
+}
+
+func (t *turnServer) Start(loggerFactory logging.LoggerFactory) error {
+	relayIP := net.ParseIP(t.config.RelayIP)
+	if relayIP == nil {
+		return fmt.Errorf("invalid relay IP address: %s", t.config.RelayIP)
+	}
+	t.relayIP = relayIP
+
+	addr := fmt.Sprintf("0.0.0.0:%d", t.config.Port)
+
+	udpListener, err := net.ListenPacket("udp4", addr)
+	if err != nil {
+		return err
+	}
+
+	tcpListener, err := net.Listen("tcp4", addr)
+	if err != nil {
+		udpListener.Close()
+		return err
+	}
+
+	relayAddressGenerator := func() turn.RelayAddressGenerator {
+		return &turnRelayAddressGenerator{
+			RelayAddressGeneratorStatic: turn.RelayAddressGeneratorStatic{
+				RelayAddress: relayIP,
+				Address:      "0.0.0.0",
+			},
+			relayIP: t.RelayIP,
+		}
+	}
+
+	permissionHandler := func(clientAddr net.Addr, peerIP net.IP) bool {
+		return t.peerAllowed(t.RelayIP(), peerIP)
+	}
+
+	t.server, err = turn.NewServer(turn.ServerConfig{
+		Realm:         t.config.Realm,
+		AuthHandler:   t.authHandler,
+		LoggerFactory: loggerFactory,
+		PacketConnConfigs: []turn.PacketConnConfig{
+			{
+				PacketConn:            udpListener,
+				RelayAddressGenerator: relayAddressGenerator(),
+				PermissionHandler:     permissionHandler,
+			},
+		},
+		ListenerConfigs: []turn.ListenerConfig{
+			{
+				Listener:              tcpListener,
+				RelayAddressGenerator: relayAddressGenerator(),
+				PermissionHandler:     permissionHandler,
+			},
+		},
+	})
+	if err != nil {
+		udpListener.Close()
+		tcpListener.Close()
+		return err
+	}
+
+	t.logger.Info().
+		Int("port", t.config.Port).
+		Str("relay_ip", t.config.RelayIP).
+		Str("host", t.host()).
+		Msg("turn server started")
+
+	return nil
+}
+
+func (t *turnServer) Shutdown() error {
+	if t.server == nil {
+		return nil
+	}
+
+	return t.server.Close()
+}
+
+// authHandler accepts only credentials generated for existing sessions that can connect.
+func (t *turnServer) authHandler(username, realm string, srcAddr net.Addr) ([]byte, bool) {
+	logger := t.logger.With().Str("username", username).Str("addr", srcAddr.String()).Logger()
+
+	expiry, sessionId, ok := strings.Cut(username, ":")
+	if !ok {
+		logger.Debug().Msg("invalid username format")
+		return nil, false
+	}
+
+	expiresAt, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		logger.Debug().Msg("credentials expired")
+		return nil, false
+	}
+
+	session, ok := t.sessions.Get(sessionId)
+	if !ok || !session.Profile().CanConnect {
+		logger.Debug().Msg("session not found or not allowed to connect")
+		return nil, false
+	}
+
+	return turn.GenerateAuthKey(username, realm, turnCredential(t.secret, username)), true
+}
+
+func (t *turnServer) RelayIP() net.IP {
+	t.relayIPMu.RLock()
+	defer t.relayIPMu.RUnlock()
+
+	return t.relayIP
+}
+
+// SetRelayIP changes relay IP for new allocations, existing allocations keep the old one.
+func (t *turnServer) SetRelayIP(ip net.IP) {
+	t.relayIPMu.Lock()
+	defer t.relayIPMu.Unlock()
+
+	t.relayIP = ip
+}
+
+// host returns host advertised to clients, current relay IP if not configured.
+func (t *turnServer) host() string {
+	if t.config.Host != "" {
+		return t.config.Host
+	}
+
+	return t.RelayIP().String()
+}
+
+// peerAllowed prevents relaying into internal network of the host, that would make it an open relay.
+func (t *turnServer) peerAllowed(relayIP, peerIP net.IP) bool {
+	// our own candidates are always reachable
+	if peerIP.Equal(relayIP) {
+		return true
+	}
+
+	for _, network := range t.config.AllowedPeers {
+		if network.Contains(peerIP) {
+			return true
+		}
+	}
+
+	if peerIP.IsLoopback() || peerIP.IsPrivate() || peerIP.IsLinkLocalUnicast() ||
+		peerIP.IsUnspecified() || peerIP.IsMulticast() {
+		t.logger.Debug().Str("peer_ip", peerIP.String()).Msg("relay to internal address rejected")
+		return false
+	}
+
+	return true
+}
+
+func (t *turnServer) ICEServer(session types.Session) types.ICEServer {
+	host := net.JoinHostPort(t.host(), strconv.Itoa(t.config.Port))
+	username, credential := turnCredentials(t.secret, session.ID(), t.ttl)
+
+	return types.ICEServer{
+		URLs: []string{
+			"turn:" + host + "?transport=udp",
+			"turn:" + host + "?transport=tcp",
+		},
+		Username:   username,
+		Credential: credential,
+	}
+}
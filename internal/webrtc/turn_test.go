@@ -0,0 +1,48 @@
+package webrtc
+
+import (
+	"net"
+	"testing"
+
+	"github.com/rs/zerolog"
+
+	"github.com/demodesk/neko/internal/config"
+)
+
+func TestTurnServer_peerAllowed(t *testing.T) {
+	_, allowed, _ := net.ParseCIDR("10.1.0.0/16")
+	server := &turnServer{
+		logger: zerolog.Nop(),
+		config: config.WebRTCTURNServer{
+			AllowedPeers: []*net.IPNet{allowed},
+		},
+	}
+
+	relayIP := net.ParseIP("192.168.1.10")
+
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"203.0.113.5", true},
+		{"2001:db8::1", true},
+		{"192.168.1.10", true},
+		{"10.1.2.3", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.0.0.1", false},
+		{"172.17.0.2", false},
+		{"192.168.1.11", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"0.0.0.0", false},
+		{"224.0.0.1", false},
+	}
+
+	for _, tt := range tests {
+		if got := server.peerAllowed(relayIP, net.ParseIP(tt.ip)); got != tt.want {
+			t.Errorf("peerAllowed(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
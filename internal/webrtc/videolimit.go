@@ -0,0 +1,91 @@
+package webrtc
+
+import (
+	"time"
+
+	"github.com/pion/webrtc/v3"
+	"github.com/rs/zerolog"
+
+	"github.com/demodesk/neko/pkg/types"
+)
+
+// how often is measured bitrate checked against member video limits
+const videoLimitInterval = time.Second
+
+// limitedStreamSelector exposes only video streams allowed by member profile of
+// the session, so that neither clients nor bitrate controllers can select others.
+type limitedStreamSelector struct {
+	types.StreamSelectorManager
+	session types.Session
+}
+
+func newLimitedStreamSelector(session types.Session, video types.StreamSelectorManager) *limitedStreamSelector {
+	return &limitedStreamSelector{
+		StreamSelectorManager: video,
+		session:               session,
+	}
+}
+
+func (s *limitedStreamSelector) allowed(profile types.MemberProfile, stream types.StreamSinkManager) bool {
+	return profile.VideoAllowed(s.StreamSelectorManager.IDs(), stream.ID(), stream.Bitrate())
+}
+
+func (s *limitedStreamSelector) IDs() []string {
+	profile := s.session.Profile()
+	return profile.VideoIDsAllowed(s.StreamSelectorManager)
+}
+
+// GetStream returns requested stream, or if it is not allowed, the nearest allowed
+// stream in direction of the selector. Exact and nearest selectors fall back to lower streams.
+func (s *limitedStreamSelector) GetStream(selector types.StreamSelector) (types.StreamSinkManager, bool) {
+	profile := s.session.Profile()
+
+	next := types.StreamSelectorTypeLower
+	if selector.Type == types.StreamSelectorTypeHigher {
+		next = types.StreamSelectorTypeHigher
+	}
+
+	stream, ok := s.StreamSelectorManager.GetStream(selector)
+	for ok && !s.allowed(profile, stream) {
+		stream, ok = s.StreamSelectorManager.GetStream(types.StreamSelector{
+			ID:   stream.ID(),
+			Type: next,
+		})
+	}
+
+	return stream, ok
+}
+
+// videoLimitReader switches peer to a lower stream, when measured bitrate of the current
+// stream exceeds member limit, e.g. after it was selected while its bitrate was unknown.
+func videoLimitReader(logger zerolog.Logger, connection *webrtc.PeerConnection, session types.Session, peer types.WebRTCPeer, current func() (types.StreamSinkManager, bool)) {
+	ticker := time.NewTicker(videoLimitInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		// if peer connection is closed, stop reading
+		if connection.ConnectionState() == webrtc.PeerConnectionStateClosed {
+			break
+		}
+
+		if session.Profile().MaxBitrate == 0 {
+			continue
+		}
+
+		stream, ok := current()
+		if !ok {
+			continue
+		}
+
+		// limited stream selector falls back to the nearest lower allowed stream
+		err := peer.SetVideo(types.PeerVideoRequest{
+			Selector: &types.StreamSelector{
+				ID:   stream.ID(),
+				Type: types.StreamSelectorTypeExact,
+			},
+		})
+		if err != nil && err != types.ErrWebRTCStreamNotFound {
+			logger.Warn().Err(err).Msg("failed to apply video limits")
+		}
+	}
+}
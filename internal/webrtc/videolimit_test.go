@@ -0,0 +1,74 @@
+package webrtc
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/demodesk/neko/pkg/types"
+	"github.com/demodesk/neko/pkg/types/codec"
+)
+
+type testProfileSession struct {
+	testSession
+	profile types.MemberProfile
+}
+
+func (s *testProfileSession) Profile() types.MemberProfile { return s.profile }
+
+func TestLimitedStreamSelector_bitrate(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile types.MemberProfile
+		streams []testStreamConfig
+		ids     []string
+		exact   string
+	}{
+		{
+			name:    "unlimited allows unknown bitrate",
+			profile: types.MemberProfile{},
+			streams: []testStreamConfig{{"high", 0}, {"medium", 0}, {"low", 0}},
+			ids:     []string{"high", "medium", "low"},
+			exact:   "high",
+		},
+		{
+			name:    "unknown bitrate allowed only for the lowest stream",
+			profile: types.MemberProfile{MaxBitrate: 700_000},
+			streams: []testStreamConfig{{"high", 0}, {"medium", 0}, {"low", 0}},
+			ids:     []string{"low"},
+			exact:   "low",
+		},
+		{
+			name:    "measured bitrate within limit",
+			profile: types.MemberProfile{MaxBitrate: 700_000},
+			streams: []testStreamConfig{{"high", 0}, {"medium", 600_000}, {"low", 0}},
+			ids:     []string{"medium", "low"},
+			exact:   "medium",
+		},
+		{
+			name:    "measured bitrate over limit",
+			profile: types.MemberProfile{MaxBitrate: 700_000},
+			streams: []testStreamConfig{{"high", 1_500_000}, {"medium", 600_000}, {"low", 250_000}},
+			ids:     []string{"medium", "low"},
+			exact:   "medium",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			streams := newTestStreamSelector(codec.VP8(), tt.streams)
+			defer streams.Close()
+
+			session := &testProfileSession{profile: tt.profile}
+			selector := newLimitedStreamSelector(session, streams)
+
+			if ids := selector.IDs(); !reflect.DeepEqual(ids, tt.ids) {
+				t.Errorf("selector.IDs() = %v, want %v", ids, tt.ids)
+			}
+
+			stream, ok := selector.GetStream(types.StreamSelector{ID: "high", Type: types.StreamSelectorTypeExact})
+			if !ok || stream.ID() != tt.exact {
+				t.Errorf("selector.GetStream(high) = %v, want %s", stream, tt.exact)
+			}
+		})
+	}
+}
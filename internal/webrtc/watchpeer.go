@@ -0,0 +1,463 @@
+package webrtc
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+	"github.com/rs/zerolog"
+
+	"github.com/demodesk/neko/pkg/types"
+	"github.com/demodesk/neko/pkg/types/codec"
+	"github.com/demodesk/neko/pkg/types/event"
+	"github.com/demodesk/neko/pkg/types/message"
+)
+
+// WebRTCWatchPeerCtx is a lightweight peer for sessions that can only watch. It has
+// no data channel and no bandwidth estimator, and its tracks forward RTP packets
+// that are packetized once per stream and shared between all watch-only peers.
+type WebRTCWatchPeerCtx struct {
+	mu         sync.Mutex
+	logger     zerolog.Logger
+	session    types.Session
+	connection *webrtc.PeerConnection
+	// stream selectors
+	video types.StreamSelectorManager
+	// tracks
+	audioTrack *watchTrack
+	videoTrack *watchTrack
+	// config
+	iceTrickle    bool
+	paused        bool
+	videoDisabled bool
+	audioDisabled bool
+}
+
+//
+// connection
+//
+
+func (peer *WebRTCWatchPeerCtx) CreateOffer(ICERestart bool) (*webrtc.SessionDescription, error) {
+	peer.mu.Lock()
+	defer peer.mu.Unlock()
+
+	offer, err := peer.connection.CreateOffer(&webrtc.OfferOptions{
+		ICERestart: ICERestart,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return setLocalDescription(peer.connection, peer.iceTrickle, offer)
+}
+
+func (peer *WebRTCWatchPeerCtx) CreateAnswer() (*webrtc.SessionDescription, error) {
+	peer.mu.Lock()
+	defer peer.mu.Unlock()
+
+	answer, err := peer.connection.CreateAnswer(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return setLocalDescription(peer.connection, peer.iceTrickle, answer)
+}
+
+func (peer *WebRTCWatchPeerCtx) SetRemoteDescription(desc webrtc.SessionDescription) error {
+	peer.mu.Lock()
+	defer peer.mu.Unlock()
+
+	return peer.connection.SetRemoteDescription(desc)
+}
+
+func (peer *WebRTCWatchPeerCtx) SetCandidate(candidate webrtc.ICECandidateInit) error {
+	peer.mu.Lock()
+	defer peer.mu.Unlock()
+
+	return peer.connection.AddICECandidate(candidate)
+}
+
+func (peer *WebRTCWatchPeerCtx) WatchOnly() bool {
+	return true
+}
+
+func (peer *WebRTCWatchPeerCtx) Destroy() {
+	peer.mu.Lock()
+	defer peer.mu.Unlock()
+
+	var err error
+
+	// if peer connection is not closed, close it
+	if peer.connection.ConnectionState() != webrtc.PeerConnectionStateClosed {
+		err = peer.connection.Close()
+	}
+
+	peer.logger.Err(err).Msg("peer connection destroyed")
+}
+
+func (peer *WebRTCWatchPeerCtx) SetPaused(isPaused bool) error {
+	peer.mu.Lock()
+	defer peer.mu.Unlock()
+
+	peer.videoTrack.SetPaused(isPaused || peer.videoDisabled)
+	peer.audioTrack.SetPaused(isPaused || peer.audioDisabled)
+
+	peer.logger.Info().Bool("is_paused", isPaused).Msg("set paused")
+	peer.paused = isPaused
+
+	return nil
+}
+
+func (peer *WebRTCWatchPeerCtx) Paused() bool {
+	peer.mu.Lock()
+	defer peer.mu.Unlock()
+
+	return peer.paused
+}
+
+//
+// video
+//
+
+func (peer *WebRTCWatchPeerCtx) SetVideo(r types.PeerVideoRequest) error {
+	peer.mu.Lock()
+	defer peer.mu.Unlock()
+
+	modified := false
+
+	// video disabled
+	if r.Disabled != nil {
+		disabled := *r.Disabled
+
+		// update only if changed
+		if peer.videoDisabled != disabled {
+			peer.videoDisabled = disabled
+			peer.videoTrack.SetPaused(disabled || peer.paused)
+
+			peer.logger.Info().Bool("disabled", disabled).Msg("set video disabled")
+			modified = true
+		}
+	}
+
+	// video selector
+	if r.Selector != nil {
+		selector := *r.Selector
+
+		// get requested video stream from selector
+		stream, ok := peer.video.GetStream(selector)
+		if !ok {
+			return types.ErrWebRTCStreamNotFound
+		}
+
+		// set video stream to track
+		changed, err := peer.videoTrack.SetStream(stream)
+		if err != nil {
+			return err
+		}
+
+		// update only if stream changed
+		if changed {
+			peer.logger.Info().Str("video_id", stream.ID()).Msg("set video")
+			modified = true
+		}
+	}
+
+	// watch-only peers have no bandwidth estimator
+	if r.Auto != nil && *r.Auto {
+		peer.logger.Warn().Msg("watch-only peer cannot use video auto")
+	}
+
+	// send video signal if modified
+	if modified {
+		go func() {
+			// in goroutine because of mutex and we don't want to block
+			peer.session.Send(event.SIGNAL_VIDEO, peer.Video())
+		}()
+	}
+
+	return nil
+}
+
+func (peer *WebRTCWatchPeerCtx) Video() types.PeerVideo {
+	peer.mu.Lock()
+	defer peer.mu.Unlock()
+
+	// get current video stream ID
+	ID := ""
+	stream, ok := peer.videoTrack.Stream()
+	if ok {
+		ID = stream.ID()
+	}
+
+	return types.PeerVideo{
+		Disabled: peer.videoDisabled,
+		ID:       ID,
+		Video:    ID, // TODO: Remove, used for backward compatibility
+		Auto:     false,
+	}
+}
+
+//
+// audio
+//
+
+func (peer *WebRTCWatchPeerCtx) SetAudio(r types.PeerAudioRequest) error {
+	peer.mu.Lock()
+	defer peer.mu.Unlock()
+
+	modified := false
+
+	// audio disabled
+	if r.Disabled != nil {
+		disabled := *r.Disabled
+
+		// update only if changed
+		if peer.audioDisabled != disabled {
+			peer.audioDisabled = disabled
+			peer.audioTrack.SetPaused(disabled || peer.paused)
+
+			peer.logger.Info().Bool("disabled", disabled).Msg("set audio disabled")
+			modified = true
+		}
+	}
+
+	// send audio signal if modified
+	if modified {
+		go func() {
+			// in goroutine because of mutex and we don't want to block
+			peer.session.Send(event.SIGNAL_AUDIO, peer.Audio())
+		}()
+	}
+
+	return nil
+}
+
+func (peer *WebRTCWatchPeerCtx) Audio() types.PeerAudio {
+	peer.mu.Lock()
+	defer peer.mu.Unlock()
+
+	return types.PeerAudio{
+		Disabled: peer.audioDisabled,
+	}
+}
+
+//
+// data channel
+//
+
+func (peer *WebRTCWatchPeerCtx) SendCursorPosition(x, y int) error {
+	return types.ErrWebRTCDataChannelNotFound
+}
+
+func (peer *WebRTCWatchPeerCtx) SendCursorImage(cur *types.CursorImage, img []byte) error {
+	return types.ErrWebRTCDataChannelNotFound
+}
+
+//
+// stats
+//
+
+// Stats of watch-only peer contain only current video, other values are not collected.
+func (peer *WebRTCWatchPeerCtx) Stats() types.WebRTCPeerStats {
+	return types.WebRTCPeerStats{
+		VideoID: peer.Video().ID,
+	}
+}
+
+func (peer *WebRTCWatchPeerCtx) SetStatsInterval(interval time.Duration) {
+	if interval > 0 {
+		peer.logger.Debug().Msg("stats push is not supported by watch-only peer")
+	}
+}
+
+//
+// manager
+//
+
+// broadcaster returns shared packetizer for given stream, it is created on first use.
+func (manager *WebRTCManagerCtx) broadcaster(stream types.StreamSinkManager) (*rtpBroadcaster, error) {
+	manager.broadcastersMu.Lock()
+	defer manager.broadcastersMu.Unlock()
+
+	if b, ok := manager.broadcasters[stream]; ok {
+		return b, nil
+	}
+
+	b, err := newRTPBroadcaster(manager.logger, stream)
+	if err != nil {
+		return nil, err
+	}
+
+	manager.broadcasters[stream] = b
+	return b, nil
+}
+
+func (manager *WebRTCManagerCtx) createWatchPeer(session types.Session) (*webrtc.SessionDescription, types.WebRTCPeer, error) {
+	id := atomic.AddInt32(&manager.peerId, 1)
+
+	// add session id to logger context
+	logger := manager.logger.With().Str("session_id", session.ID()).Int32("peer_id", id).Logger()
+	logger.Info().Msg("creating webrtc watch-only peer")
+
+	// all audios must have the same codec
+	audio := manager.capture.Audio()
+	audioCodec := audio.Codec()
+
+	// all videos must have the same codec
+	video := manager.capture.Video()
+	videoCodec := video.Codec()
+
+	connection, _, err := manager.newPeerConnection(
+		logger, []codec.RTPCodec{audioCodec, videoCodec}, false)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// asynchronously send local ICE Candidates
+	if manager.config.ICETrickle {
+		connection.OnICECandidate(func(candidate *webrtc.ICECandidate) {
+			if candidate == nil {
+				logger.Debug().Msg("all local ice candidates sent")
+				return
+			}
+
+			session.Send(
+				event.SIGNAL_CANDIDATE,
+				message.SignalCandidate{
+					ICECandidateInit: candidate.ToJSON(),
+				})
+		})
+	}
+
+	// audio track
+	audioTrack := newWatchTrack(logger, audioCodec, manager.broadcaster)
+	if err := addWatchTrack(connection, audioTrack); err != nil {
+		return nil, nil, err
+	}
+
+	// we disable audio by default manually
+	audioTrack.SetPaused(true)
+
+	// set stream for audio track
+	_, err = audioTrack.SetStream(audio)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// video track
+	videoTrack := newWatchTrack(logger, videoCodec, manager.broadcaster)
+	if err := addWatchTrack(connection, videoTrack); err != nil {
+		return nil, nil, err
+	}
+
+	//
+	// stream for video track will be set later
+	//
+
+	peer := &WebRTCWatchPeerCtx{
+		logger:     logger,
+		session:    session,
+		connection: connection,
+		// stream selectors
+		video: newLimitedStreamSelector(session, video),
+		// tracks
+		audioTrack: audioTrack,
+		videoTrack: videoTrack,
+		// config
+		iceTrickle:    manager.config.ICETrickle,
+		audioDisabled: true, // we disable audio by default manually
+	}
+
+	// remote tracks are not accepted from watch-only peers
+	connection.OnTrack(func(track *webrtc.TrackRemote, receiver *webrtc.RTPReceiver) {
+		err := receiver.Stop()
+		logger.Warn().Err(err).Msg("watch-only peer cannot share media")
+	})
+
+	recovery := manager.newConnectionRecovery(logger, session, peer, nil)
+
+	metrics := manager.metrics.getWatch()
+	metrics.connections.Inc()
+	metrics.peers.Inc()
+
+	var once sync.Once
+	connection.OnConnectionStateChange(func(state webrtc.PeerConnectionState) {
+		switch state {
+		case webrtc.PeerConnectionStateConnected:
+			session.SetWebRTCConnected(peer, true)
+			recovery.Connected()
+		case webrtc.PeerConnectionStateDisconnected,
+			webrtc.PeerConnectionStateFailed:
+			if recovery != nil {
+				recovery.Start()
+			} else {
+				peer.Destroy()
+			}
+		case webrtc.PeerConnectionStateClosed:
+			// ensure we only run this once
+			once.Do(func() {
+				session.SetWebRTCConnected(peer, false)
+				audioTrack.RemoveStream()
+				videoTrack.RemoveStream()
+				recovery.Close()
+				metrics.peers.Dec()
+			})
+		}
+	})
+
+	session.SetWebRTCPeer(peer)
+
+	offer, err := peer.CreateOffer(false)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// on negotiation needed handler must be registered after creating initial
+	// offer, otherwise it can fire and intercept sucessful negotiation
+
+	connection.OnNegotiationNeeded(func() {
+		logger.Warn().Msg("negotiation is needed")
+
+		if connection.SignalingState() != webrtc.SignalingStateStable {
+			logger.Warn().Msg("connection isn't stable yet; postponing...")
+			return
+		}
+
+		offer, err := peer.CreateOffer(false)
+		if err != nil {
+			logger.Err(err).Msg("sdp offer failed")
+			return
+		}
+
+		session.Send(
+			event.SIGNAL_OFFER,
+			message.SignalDescription{
+				SDP: offer.SDP,
+			})
+	})
+
+	go videoLimitReader(logger, connection, session, peer, videoTrack.Stream)
+
+	return offer, peer, nil
+}
+
+// addWatchTrack adds track to the connection, incoming RTCP is read only
+// so that interceptors can process it, e.g. respond to NACKs.
+func addWatchTrack(connection *webrtc.PeerConnection, track *watchTrack) error {
+	sender, err := connection.AddTrack(track)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			if _, _, err := sender.Read(buf); err != nil {
+				return
+			}
+		}
+	}()
+
+	return nil
+}
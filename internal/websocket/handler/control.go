@@ -69,7 +69,9 @@ func (h *MessageHandlerCtx) controlMove(session types.Session, payload *message.
 	}
 
 	// handle active cursor movement
-	h.desktop.Move(payload.X, payload.Y)
+	if err := h.desktop.ApplyInput(types.InputEvent{Type: "move", X: payload.X, Y: payload.Y}); err != nil {
+		return err
+	}
 	h.webrtc.SetCursorPosition(payload.X, payload.Y)
 	return nil
 }
@@ -85,8 +87,7 @@ func (h *MessageHandlerCtx) controlScroll(session types.Session, payload *messag
 		payload.DeltaY = payload.Y
 	}
 
-	h.desktop.Scroll(payload.DeltaX, payload.DeltaY, payload.ControlKey)
-	return nil
+	return h.desktop.ApplyInput(types.InputEvent{Type: "scroll", X: payload.DeltaX, Y: payload.DeltaY, ControlKey: payload.ControlKey})
 }
 
 func (h *MessageHandlerCtx) controlButtonPress(session types.Session, payload *message.ControlButton) error {
@@ -98,7 +99,7 @@ func (h *MessageHandlerCtx) controlButtonPress(session types.Session, payload *m
 		return err
 	}
 
-	return h.desktop.ButtonPress(payload.Code)
+	return h.desktop.ApplyInput(types.InputEvent{Type: "button_press", Code: payload.Code})
 }
 
 func (h *MessageHandlerCtx) controlButtonDown(session types.Session, payload *message.ControlButton) error {
@@ -110,7 +111,7 @@ func (h *MessageHandlerCtx) controlButtonDown(session types.Session, payload *me
 		return err
 	}
 
-	return h.desktop.ButtonDown(payload.Code)
+	return h.desktop.ApplyInput(types.InputEvent{Type: "button_down", Code: payload.Code})
 }
 
 func (h *MessageHandlerCtx) controlButtonUp(session types.Session, payload *message.ControlButton) error {
@@ -122,7 +123,7 @@ func (h *MessageHandlerCtx) controlButtonUp(session types.Session, payload *mess
 		return err
 	}
 
-	return h.desktop.ButtonUp(payload.Code)
+	return h.desktop.ApplyInput(types.InputEvent{Type: "button_up", Code: payload.Code})
 }
 
 func (h *MessageHandlerCtx) controlKeyPress(session types.Session, payload *message.ControlKey) error {
@@ -134,7 +135,7 @@ func (h *MessageHandlerCtx) controlKeyPress(session types.Session, payload *mess
 		return err
 	}
 
-	return h.desktop.KeyPress(payload.Keysym)
+	return h.desktop.ApplyInput(types.InputEvent{Type: "key_press", Codes: []uint32{payload.Keysym}})
 }
 
 func (h *MessageHandlerCtx) controlKeyDown(session types.Session, payload *message.ControlKey) error {
@@ -146,7 +147,7 @@ func (h *MessageHandlerCtx) controlKeyDown(session types.Session, payload *messa
 		return err
 	}
 
-	return h.desktop.KeyDown(payload.Keysym)
+	return h.desktop.ApplyInput(types.InputEvent{Type: "key_down", Code: payload.Keysym})
 }
 
 func (h *MessageHandlerCtx) controlKeyUp(session types.Session, payload *message.ControlKey) error {
@@ -158,28 +159,36 @@ func (h *MessageHandlerCtx) controlKeyUp(session types.Session, payload *message
 		return err
 	}
 
-	return h.desktop.KeyUp(payload.Keysym)
+	return h.desktop.ApplyInput(types.InputEvent{Type: "key_up", Code: payload.Keysym})
+}
+
+func (h *MessageHandlerCtx) controlText(session types.Session, payload *message.ControlText) error {
+	if err := h.controlRequest(session); err != nil && !errors.Is(err, ErrIsAlreadyTheHost) {
+		return err
+	}
+
+	return h.desktop.ApplyInput(types.InputEvent{Type: "text", Text: payload.Text})
 }
 
 func (h *MessageHandlerCtx) controlTouchBegin(session types.Session, payload *message.ControlTouch) error {
 	if err := h.controlRequest(session); err != nil && !errors.Is(err, ErrIsAlreadyTheHost) {
 		return err
 	}
-	return h.desktop.TouchBegin(payload.TouchId, payload.X, payload.Y, payload.Pressure)
+	return h.desktop.ApplyInput(types.InputEvent{Type: "touch_begin", TouchId: payload.TouchId, X: payload.X, Y: payload.Y, Pressure: payload.Pressure})
 }
 
 func (h *MessageHandlerCtx) controlTouchUpdate(session types.Session, payload *message.ControlTouch) error {
 	if err := h.controlRequest(session); err != nil && !errors.Is(err, ErrIsAlreadyTheHost) {
 		return err
 	}
-	return h.desktop.TouchUpdate(payload.TouchId, payload.X, payload.Y, payload.Pressure)
+	return h.desktop.ApplyInput(types.InputEvent{Type: "touch_update", TouchId: payload.TouchId, X: payload.X, Y: payload.Y, Pressure: payload.Pressure})
 }
 
 func (h *MessageHandlerCtx) controlTouchEnd(session types.Session, payload *message.ControlTouch) error {
 	if err := h.controlRequest(session); err != nil && !errors.Is(err, ErrIsAlreadyTheHost) {
 		return err
 	}
-	return h.desktop.TouchEnd(payload.TouchId, payload.X, payload.Y, payload.Pressure)
+	return h.desktop.ApplyInput(types.InputEvent{Type: "touch_end", TouchId: payload.TouchId, X: payload.X, Y: payload.Y, Pressure: payload.Pressure})
 }
 
 func (h *MessageHandlerCtx) controlCut(session types.Session) error {
@@ -187,7 +196,7 @@ func (h *MessageHandlerCtx) controlCut(session types.Session) error {
 		return err
 	}
 
-	return h.desktop.KeyPress(xorg.XK_Control_L, xorg.XK_x)
+	return h.desktop.ApplyInput(types.InputEvent{Type: "key_press", Codes: []uint32{xorg.XK_Control_L, xorg.XK_x}})
 }
 
 func (h *MessageHandlerCtx) controlCopy(session types.Session) error {
@@ -195,7 +204,7 @@ func (h *MessageHandlerCtx) controlCopy(session types.Session) error {
 		return err
 	}
 
-	return h.desktop.KeyPress(xorg.XK_Control_L, xorg.XK_c)
+	return h.desktop.ApplyInput(types.InputEvent{Type: "key_press", Codes: []uint32{xorg.XK_Control_L, xorg.XK_c}})
 }
 
 func (h *MessageHandlerCtx) controlPaste(session types.Session, payload *message.ClipboardData) error {
@@ -210,7 +219,7 @@ func (h *MessageHandlerCtx) controlPaste(session types.Session, payload *message
 		}
 	}
 
-	return h.desktop.KeyPress(xorg.XK_Control_L, xorg.XK_v)
+	return h.desktop.ApplyInput(types.InputEvent{Type: "key_press", Codes: []uint32{xorg.XK_Control_L, xorg.XK_v}})
 }
 
 func (h *MessageHandlerCtx) controlSelectAll(session types.Session) error {
@@ -218,5 +227,5 @@ func (h *MessageHandlerCtx) controlSelectAll(session types.Session) error {
 		return err
 	}
 
-	return h.desktop.KeyPress(xorg.XK_Control_L, xorg.XK_a)
+	return h.desktop.ApplyInput(types.InputEvent{Type: "key_press", Codes: []uint32{xorg.XK_Control_L, xorg.XK_a}})
 }
@@ -76,6 +76,11 @@ func (h *MessageHandlerCtx) Message(session types.Session, data types.WebSocketM
 		err = utils.Unmarshal(payload, data.Payload, func() error {
 			return h.signalAudio(session, payload)
 		})
+	case event.SIGNAL_STATS:
+		payload := &message.SignalStatsRequest{}
+		err = utils.Unmarshal(payload, data.Payload, func() error {
+			return h.signalStats(session, payload)
+		})
 
 	// Control Events
 	case event.CONTROL_RELEASE:
@@ -122,6 +127,11 @@ func (h *MessageHandlerCtx) Message(session types.Session, data types.WebSocketM
 		err = utils.Unmarshal(payload, data.Payload, func() error {
 			return h.controlKeyUp(session, payload)
 		})
+	case event.CONTROL_TEXT:
+		payload := &message.ControlText{}
+		err = utils.Unmarshal(payload, data.Payload, func() error {
+			return h.controlText(session, payload)
+		})
 	// touch
 	case event.CONTROL_TOUCHBEGIN:
 		payload := &message.ControlTouch{}
@@ -60,6 +60,25 @@ func (h *MessageHandlerCtx) SessionProfileChanged(session types.Session) error {
 			MemberProfile: session.Profile(),
 		})
 
+	// watch-only peer has no data channel, client needs to reconnect to be able to host
+	if peer := session.GetWebRTCPeer(); peer != nil && peer.WatchOnly() && session.Profile().CanHost {
+		h.logger.Info().Str("session_id", session.ID()).Msg("session can host now, destroying watch-only peer")
+		peer.Destroy()
+		return nil
+	}
+
+	// reselect current video, so that new video limits are applied
+	if peer := session.GetWebRTCPeer(); peer != nil {
+		if videoID := peer.Video().ID; videoID != "" {
+			return peer.SetVideo(types.PeerVideoRequest{
+				Selector: &types.StreamSelector{
+					ID:   videoID,
+					Type: types.StreamSelectorTypeExact,
+				},
+			})
+		}
+	}
+
 	return nil
 }
 
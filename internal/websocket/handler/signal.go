@@ -2,6 +2,7 @@ package handler
 
 import (
 	"errors"
+	"time"
 
 	"github.com/demodesk/neko/pkg/types"
 	"github.com/demodesk/neko/pkg/types/event"
@@ -64,7 +65,7 @@ func (h *MessageHandlerCtx) signalRequest(session types.Session, payload *messag
 		event.SIGNAL_PROVIDE,
 		message.SignalProvide{
 			SDP:        offer.SDP,
-			ICEServers: h.webrtc.ICEServers(),
+			ICEServers: h.webrtc.SessionICEServers(session),
 
 			Video: peer.Video(),
 			Audio: peer.Audio(),
@@ -160,3 +161,13 @@ func (h *MessageHandlerCtx) signalAudio(session types.Session, payload *message.
 
 	return peer.SetAudio(payload.PeerAudioRequest)
 }
+
+func (h *MessageHandlerCtx) signalStats(session types.Session, payload *message.SignalStatsRequest) error {
+	peer := session.GetWebRTCPeer()
+	if peer == nil {
+		return errors.New("webRTC peer does not exist")
+	}
+
+	peer.SetStatsInterval(time.Duration(payload.Interval) * time.Millisecond)
+	return nil
+}
@@ -29,6 +29,9 @@ func (h *MessageHandlerCtx) systemInit(session types.Session) error {
 		Rate:   size.Rate,
 	}
 
+	// only video streams within member limits are offered
+	profile := session.Profile()
+
 	sessions := map[string]message.SessionData{}
 	for _, session := range h.sessions.List() {
 		sessionId := session.ID()
@@ -50,7 +53,7 @@ func (h *MessageHandlerCtx) systemInit(session types.Session) error {
 			TouchEvents:       h.desktop.HasTouchSupport(),
 			ScreencastEnabled: h.capture.Screencast().Enabled(),
 			WebRTC: message.SystemWebRTC{
-				Videos: h.capture.Video().IDs(),
+				Videos: profile.VideoIDsAllowed(h.capture.Video()),
 			},
 		})
 
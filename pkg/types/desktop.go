@@ -1,10 +1,24 @@
 package types
 
 import (
+	"errors"
 	"fmt"
 	"image"
 )
 
+// maximum number of characters in a single text input
+const TextInputMaxLength = 1024
+
+var (
+	ErrTextInputTooLong       = fmt.Errorf("text input is longer than %d characters", TextInputMaxLength)
+	ErrInputRecordingDisabled = errors.New("input recording is disabled")
+	ErrInputRecordingName     = errors.New("invalid input recording name")
+	ErrInputRecordingActive   = errors.New("input recording is already active")
+	ErrInputRecordingInactive = errors.New("input recording is not active")
+	ErrInputReplayActive      = errors.New("input replay is already active")
+	ErrInputReplayInactive    = errors.New("input replay is not active")
+)
+
 type CursorImage struct {
 	Width  uint16
 	Height uint16
@@ -45,6 +59,28 @@ type ClipboardText struct {
 	HTML string
 }
 
+// InputEvent is single user input stored in input recording.
+type InputEvent struct {
+	// milliseconds since the recording started
+	Time int64  `json:"t"`
+	Type string `json:"type"`
+
+	X          int      `json:"x,omitempty"`
+	Y          int      `json:"y,omitempty"`
+	ControlKey bool     `json:"control_key,omitempty"`
+	Code       uint32   `json:"code,omitempty"`
+	Codes      []uint32 `json:"codes,omitempty"`
+	Text       string   `json:"text,omitempty"`
+	TouchId    uint32   `json:"touch_id,omitempty"`
+	Pressure   uint8    `json:"pressure,omitempty"`
+}
+
+type InputRecordStatus struct {
+	Recording bool   `json:"recording"`
+	Replaying bool   `json:"replaying"`
+	Name      string `json:"name,omitempty"`
+}
+
 type DesktopManager interface {
 	Start()
 	Shutdown() error
@@ -61,6 +97,7 @@ type DesktopManager interface {
 	KeyUp(code uint32) error
 	ButtonPress(code uint32) error
 	KeyPress(codes ...uint32) error
+	TextInput(text string) error
 	ResetKeys()
 	ScreenConfigurations() []ScreenSize
 	SetScreenSize(ScreenSize) (ScreenSize, error)
@@ -85,6 +122,14 @@ type DesktopManager interface {
 	TouchUpdate(touchId uint32, x, y int, pressure uint8) error
 	TouchEnd(touchId uint32, x, y int, pressure uint8) error
 
+	// input recording
+	ApplyInput(event InputEvent) error
+	InputRecordStart(name string) error
+	InputRecordStop() error
+	InputReplayStart(name string, speed float64) error
+	InputReplayStop() error
+	InputRecordStatus() InputRecordStatus
+
 	// clipboard
 	ClipboardGetText() (*ClipboardText, error)
 	ClipboardSetText(data ClipboardText) error
@@ -19,6 +19,7 @@ const (
 	SIGNAL_VIDEO     = "signal/video"
 	SIGNAL_AUDIO     = "signal/audio"
 	SIGNAL_CLOSE     = "signal/close"
+	SIGNAL_STATS     = "signal/stats"
 )
 
 const (
@@ -43,6 +44,7 @@ const (
 	CONTROL_KEYPRESS = "control/keypress"
 	CONTROL_KEYDOWN  = "control/keydown"
 	CONTROL_KEYUP    = "control/keyup"
+	CONTROL_TEXT     = "control/text"
 	// touch
 	CONTROL_TOUCHBEGIN  = "control/touchbegin"
 	CONTROL_TOUCHUPDATE = "control/touchupdate"
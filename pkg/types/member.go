@@ -1,16 +1,41 @@
 package types
 
-import "errors"
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/demodesk/neko/pkg/utils"
+)
 
 var (
 	ErrMemberAlreadyExists   = errors.New("member already exists")
 	ErrMemberDoesNotExist    = errors.New("member does not exist")
 	ErrMemberInvalidPassword = errors.New("invalid password")
+	ErrMemberOIDCDisabled    = errors.New("oidc login is not enabled")
+	ErrMemberInviteNotFound  = errors.New("invite not found")
+	ErrMemberInviteUsedUp    = errors.New("invite has no uses left")
+
+	ErrMemberLockedOut       = errors.New("too many failed login attempts")
+	ErrMemberLockoutNotFound = errors.New("lockout not found")
+	ErrMemberRoleNotFound    = errors.New("role not found")
+
+	ErrMemberTOTPUnsupported      = errors.New("totp is not supported by member provider")
+	ErrMemberTOTPNotEnrolled      = errors.New("totp is not enrolled")
+	ErrMemberTOTPEnabled          = errors.New("totp is already enabled")
+	ErrMemberTOTPRequired         = errors.New("totp is required")
+	ErrMemberTOTPInvalidCode      = errors.New("invalid totp code")
+	ErrMemberTOTPChallengeInvalid = errors.New("invalid or expired totp challenge")
 )
 
 type MemberProfile struct {
 	Name string `json:"name"`
 
+	// named role provides permissions and limits, so that profile fields are ignored,
+	// overrides use the same keys as profile and are applied on top of the role
+	Role      string         `json:"role,omitempty"      mapstructure:"role"`
+	Overrides map[string]any `json:"overrides,omitempty" mapstructure:"overrides"`
+
 	// permissions
 	IsAdmin               bool `json:"is_admin"                 mapstructure:"is_admin"`
 	CanLogin              bool `json:"can_login"                mapstructure:"can_login"`
@@ -22,10 +47,66 @@ type MemberProfile struct {
 	SendsInactiveCursor   bool `json:"sends_inactive_cursor"    mapstructure:"sends_inactive_cursor"`
 	CanSeeInactiveCursors bool `json:"can_see_inactive_cursors" mapstructure:"can_see_inactive_cursors"`
 
+	// video limits, empty means unlimited
+	MaxVideoID      string   `json:"max_video_id"      mapstructure:"max_video_id"`
+	MaxBitrate      uint64   `json:"max_bitrate"       mapstructure:"max_bitrate"`
+	AllowedVideoIDs []string `json:"allowed_video_ids" mapstructure:"allowed_video_ids"`
+
 	// plugin scope
 	Plugins map[string]any `json:"plugins"`
 }
 
+// VideoAllowed checks if video stream is within member video limits,
+// video IDs must be ordered from the highest to the lowest quality.
+func (profile *MemberProfile) VideoAllowed(videoIDs []string, videoID string, bitrate uint64) bool {
+	if len(profile.AllowedVideoIDs) > 0 {
+		if ok, _ := utils.ArrayIn(videoID, profile.AllowedVideoIDs); !ok {
+			return false
+		}
+	}
+
+	if profile.MaxBitrate > 0 {
+		// unknown bitrate (stream is not running) is allowed only for the lowest stream,
+		// so that member can start watching, higher streams are allowed once measured
+		if bitrate == 0 && (len(videoIDs) == 0 || videoIDs[len(videoIDs)-1] != videoID) {
+			return false
+		}
+
+		if bitrate > profile.MaxBitrate {
+			return false
+		}
+	}
+
+	if profile.MaxVideoID != "" {
+		_, max := utils.ArrayIn(profile.MaxVideoID, videoIDs)
+		_, index := utils.ArrayIn(videoID, videoIDs)
+		// limit that cannot be resolved denies everything
+		if max == -1 || index < max {
+			return false
+		}
+	}
+
+	return true
+}
+
+// VideoIDsAllowed returns IDs of video streams within member video limits.
+func (profile *MemberProfile) VideoIDsAllowed(video StreamSelectorManager) []string {
+	videoIDs := video.IDs()
+
+	allowed := []string{}
+	for _, videoID := range videoIDs {
+		stream, ok := video.GetStream(StreamSelector{
+			ID:   videoID,
+			Type: StreamSelectorTypeExact,
+		})
+		if ok && profile.VideoAllowed(videoIDs, videoID, stream.Bitrate()) {
+			allowed = append(allowed, videoID)
+		}
+	}
+
+	return allowed
+}
+
 type MemberProvider interface {
 	Connect() error
 	Disconnect() error
@@ -40,9 +121,84 @@ type MemberProvider interface {
 	Delete(id string) error
 }
 
+// MemberOIDCProvider is implemented by providers, that support OpenID Connect authorization code flow.
+type MemberOIDCProvider interface {
+	AuthCodeURL(state string, nonce string) string
+	Exchange(ctx context.Context, code string, nonce string) (id string, profile MemberProfile, err error)
+}
+
+// MemberTOTP is second factor state, that is stored by provider next to member profile.
+type MemberTOTP struct {
+	Secret  string `json:"secret"`
+	Enabled bool   `json:"enabled"`
+	// sha256 hashes of unused recovery codes
+	RecoveryCodes []string `json:"recovery_codes"`
+	// counter of last accepted code, to prevent its reuse
+	LastCounter int64 `json:"last_counter"`
+}
+
+// MemberTOTPProvider is implemented by providers, that are able to store TOTP state.
+type MemberTOTPProvider interface {
+	SelectTOTP(id string) (MemberTOTP, error)
+	UpdateTOTP(id string, totp MemberTOTP) error
+}
+
+// MemberTOTPChallenge is returned as error by Login, when second factor is needed to finish login.
+// If Enroll is set, member must enroll TOTP before login is allowed.
+type MemberTOTPChallenge struct {
+	Token  string
+	Enroll bool
+}
+
+func (c *MemberTOTPChallenge) Error() string {
+	return "totp challenge required"
+}
+
+// MemberLockout is failed login attempts counter for either username or IP address.
+type MemberLockout struct {
+	Username    string     `json:"username,omitempty"`
+	IP          string     `json:"ip,omitempty"`
+	Failures    int        `json:"failures"`
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
+}
+
+// MemberInvite grants sessions with profile template until it expires,
+// sessions created by invite are revoked when it expires or is deleted.
+type MemberInvite struct {
+	ID        string        `json:"id"`
+	Token     string        `json:"token"`
+	Profile   MemberProfile `json:"profile"`
+	ExpiresAt time.Time     `json:"expires_at"`
+	MaxUses   int           `json:"max_uses"`
+	Uses      int           `json:"uses"`
+	Sessions  []string      `json:"sessions"`
+}
+
 type MemberManager interface {
 	MemberProvider
 
-	Login(username string, password string) (Session, string, error)
+	Login(username string, password string, ip string) (Session, string, error)
+	OIDCAuthURL(state string, nonce string) (string, error)
+	LoginOIDC(ctx context.Context, code string, nonce string) (Session, string, error)
+	LoginInvite(token string, name string) (Session, string, error)
+	LoginTOTP(challenge string, code string, ip string) (session Session, token string, recoveryCodes []string, err error)
+	LoginTOTPEnroll(challenge string) (secret string, url string, err error)
 	Logout(id string) error
+
+	CreateInvite(profile MemberProfile, expiresAt time.Time, maxUses int) (MemberInvite, error)
+	ListInvites() []MemberInvite
+	DeleteInvite(id string) error
+
+	TOTPEnroll(id string) (secret string, url string, err error)
+	TOTPConfirm(id string, code string) (recoveryCodes []string, err error)
+	TOTPDisable(id string, code string) error
+	TOTPReset(id string) error
+
+	ListLockouts() []MemberLockout
+	ClearLockout(username string, ip string) error
+	ClearLockouts()
+
+	ListRoles() map[string]MemberProfile
+	UpdateRole(name string, profile MemberProfile) error
+	DeleteRole(name string) error
 }
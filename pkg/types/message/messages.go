@@ -77,6 +77,15 @@ type SignalAudio struct {
 	types.PeerAudioRequest
 }
 
+type SignalStatsRequest struct {
+	// push interval in milliseconds, 0 to disable
+	Interval int `json:"interval"`
+}
+
+type SignalStats struct {
+	types.WebRTCPeerStats
+}
+
 /////////////////////////////
 // Session
 /////////////////////////////
@@ -140,6 +149,10 @@ type ControlKey struct {
 	Keysym uint32 `json:"keysym"`
 }
 
+type ControlText struct {
+	Text string `json:"text"`
+}
+
 type ControlTouch struct {
 	TouchId uint32 `json:"touch_id"`
 	*ControlPos
@@ -39,6 +39,7 @@ type PluginManagers struct {
 	SessionManager        SessionManager
 	WebSocketManager      WebSocketManager
 	ApiManager            ApiManager
+	WebRTCManager         WebRTCManager
 	LoadServiceFromPlugin func(string) (any, error)
 }
 
@@ -55,6 +56,10 @@ func (p *PluginManagers) Validate() error {
 		return errors.New("ApiManager is nil")
 	}
 
+	if p.WebRTCManager == nil {
+		return errors.New("WebRTCManager is nil")
+	}
+
 	if p.LoadServiceFromPlugin == nil {
 		return errors.New("LoadServiceFromPlugin is nil")
 	}
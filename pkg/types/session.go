@@ -10,6 +10,7 @@ var (
 	ErrSessionNotFound         = errors.New("session not found")
 	ErrSessionAlreadyExists    = errors.New("session already exists")
 	ErrSessionAlreadyConnected = errors.New("session is already connected")
+	ErrSessionNotConnected     = errors.New("session is not connected")
 	ErrSessionLoginDisabled    = errors.New("session login disabled")
 )
 
@@ -19,9 +20,12 @@ type Cursor struct {
 }
 
 type SessionProfile struct {
-	Id      string
-	Token   string
-	Profile MemberProfile
+	Id        string
+	Token     string
+	Profile   MemberProfile
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	JWT       bool
 }
 
 type SessionState struct {
@@ -73,6 +77,7 @@ type Session interface {
 
 type SessionManager interface {
 	Create(id string, profile MemberProfile) (Session, string, error)
+	CreateExpiring(id string, profile MemberProfile, expiresAt time.Time) (Session, string, error)
 	Update(id string, profile MemberProfile) error
 	Delete(id string) error
 	Get(id string) (Session, bool)
@@ -2,6 +2,7 @@ package types
 
 import (
 	"errors"
+	"time"
 
 	"github.com/pion/webrtc/v3"
 )
@@ -10,6 +11,8 @@ var (
 	ErrWebRTCDataChannelNotFound = errors.New("webrtc data channel not found")
 	ErrWebRTCConnectionNotFound  = errors.New("webrtc connection not found")
 	ErrWebRTCStreamNotFound      = errors.New("webrtc stream not found")
+
+	ErrWebRTCBitrateControllerExists = errors.New("webrtc bitrate controller already exists")
 )
 
 type ICEServer struct {
@@ -39,6 +42,64 @@ type PeerAudioRequest struct {
 	Disabled *bool `json:"disabled,omitempty"`
 }
 
+type WebRTCICECandidate struct {
+	Address  string `json:"address"`
+	Port     int    `json:"port"`
+	Protocol string `json:"protocol"`
+	Type     string `json:"type"`
+}
+
+type WebRTCPeerStats struct {
+	// data channel round trip time in milliseconds
+	RTT float64 `json:"rtt"`
+	// difference between client and server clock in milliseconds
+	ClockOffset float64 `json:"clock_offset"`
+	// time between last host input and the next video sample written to the track in milliseconds,
+	// frame captured before the input can be that sample, so it is not input-to-encode latency
+	InputToSample float64 `json:"input_to_sample"`
+
+	// selected ICE candidate pair
+	LocalCandidate  *WebRTCICECandidate `json:"local_candidate,omitempty"`
+	RemoteCandidate *WebRTCICECandidate `json:"remote_candidate,omitempty"`
+	// ICE round trip time in milliseconds
+	ICERTT float64 `json:"ice_rtt"`
+
+	// from RTCP receiver reports, jitter in timestamp units
+	Jitter       uint32  `json:"jitter"`
+	PacketsLost  uint32  `json:"packets_lost"`
+	FractionLost float64 `json:"fraction_lost"`
+	NACKs        uint64  `json:"nacks"`
+
+	// estimated bitrates in bits per second
+	REMB          float64 `json:"remb"`
+	TargetBitrate float64 `json:"target_bitrate"`
+
+	VideoID   string `json:"video_id"`
+	BytesSent uint64 `json:"bytes_sent"`
+}
+
+type BitrateSample struct {
+	// target bitrate from bandwidth estimator in bits per second
+	TargetBitrate int
+	// fraction of packets lost from last RTCP receiver report, from 0 to 1
+	FractionLost float64
+	// data channel round trip time, zero if not measured yet
+	RTT time.Duration
+	// when the sample was taken
+	Time time.Time
+}
+
+// BitrateController decides which video stream should be sent to a peer
+// based on periodic bandwidth estimator samples.
+type BitrateController interface {
+	// Next returns selector of stream that should be used instead of current
+	// stream, or nil if current stream should be kept.
+	Next(sample BitrateSample, current StreamSinkManager, available StreamSelectorManager) *StreamSelector
+}
+
+// BitrateControllerFactory creates new bitrate controller for every peer.
+type BitrateControllerFactory func(session Session) BitrateController
+
 type WebRTCPeer interface {
 	CreateOffer(ICERestart bool) (*webrtc.SessionDescription, error)
 	CreateAnswer() (*webrtc.SessionDescription, error)
@@ -56,6 +117,11 @@ type WebRTCPeer interface {
 	SendCursorPosition(x, y int) error
 	SendCursorImage(cur *CursorImage, img []byte) error
 
+	Stats() WebRTCPeerStats
+	SetStatsInterval(interval time.Duration)
+
+	// watch-only peers have no data channel and cannot be used for hosting
+	WatchOnly() bool
 	Destroy()
 }
 
@@ -64,6 +130,8 @@ type WebRTCManager interface {
 	Shutdown() error
 
 	ICEServers() []ICEServer
+	SessionICEServers(session Session) []ICEServer
+	RegisterBitrateController(name string, factory BitrateControllerFactory) error
 
 	CreatePeer(session Session) (*webrtc.SessionDescription, WebRTCPeer, error)
 	SetCursorPosition(x, y int)
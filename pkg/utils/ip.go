@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os/exec"
+	"time"
+
+	"github.com/pion/stun"
+)
+
+// how long to wait for IP retrieval command
+const ipRetrievalTimeout = 10 * time.Second
+
+func parseIP(value string) (string, error) {
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return "", fmt.Errorf("invalid IP address: %q", value)
+	}
+
+	return ip.String(), nil
+}
+
+// IpRetrievalHTTP returns IP address from the body of a HTTP response.
+func IpRetrievalHTTP(url string) (string, error) {
+	body, err := HttpRequestGET(url)
+	if err != nil {
+		return "", err
+	}
+
+	return parseIP(body)
+}
+
+// IpRetrievalSTUN returns IP address mapped by a STUN server using binding request.
+func IpRetrievalSTUN(address string) (string, error) {
+	client, err := stun.Dial("udp4", address)
+	if err != nil {
+		return "", err
+	}
+	defer client.Close()
+
+	var ip string
+	var resErr error
+
+	message := stun.MustBuild(stun.TransactionID, stun.BindingRequest)
+	err = client.Do(message, func(res stun.Event) {
+		if res.Error != nil {
+			resErr = res.Error
+			return
+		}
+
+		var addr stun.XORMappedAddress
+		if err := addr.GetFrom(res.Message); err != nil {
+			resErr = err
+			return
+		}
+
+		ip = addr.IP.String()
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	if resErr != nil {
+		return "", resErr
+	}
+
+	return parseIP(ip)
+}
+
+// IpRetrievalCommand returns IP address printed by a local command.
+func IpRetrievalCommand(command string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), ipRetrievalTimeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, "sh", "-c", command).Output()
+	if err != nil {
+		return "", err
+	}
+
+	return parseIP(string(bytes.TrimSpace(out)))
+}
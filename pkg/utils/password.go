@@ -0,0 +1,147 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	PasswordHashArgon2id = "argon2id"
+	PasswordHashBcrypt   = "bcrypt"
+)
+
+// argon2id parameters, as recommended by OWASP
+const (
+	argon2idTime    = 2
+	argon2idMemory  = 19 * 1024
+	argon2idThreads = 1
+	argon2idKeyLen  = 32
+	argon2idSaltLen = 16
+
+	// upper bound of memory accepted in stored hashes, in KiB
+	argon2idMaxMemory = 1024 * 1024
+)
+
+var ErrPasswordHashInvalid = errors.New("invalid password hash")
+
+// HashPassword returns salted password hash in self-describing format,
+// argon2id hashes use PHC string format and bcrypt hashes modular crypt format.
+func HashPassword(algorithm string, password string) (string, error) {
+	switch algorithm {
+	case PasswordHashArgon2id:
+		salt := make([]byte, argon2idSaltLen)
+		if _, err := rand.Read(salt); err != nil {
+			return "", err
+		}
+
+		key := argon2.IDKey([]byte(password), salt, argon2idTime, argon2idMemory, argon2idThreads, argon2idKeyLen)
+		return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+			argon2.Version, argon2idMemory, argon2idTime, argon2idThreads,
+			base64.RawStdEncoding.EncodeToString(salt),
+			base64.RawStdEncoding.EncodeToString(key),
+		), nil
+	case PasswordHashBcrypt:
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		return string(hash), err
+	default:
+		return "", fmt.Errorf("unknown password hash algorithm %q", algorithm)
+	}
+}
+
+// IsPasswordHash checks if value looks like hash created by HashPassword.
+func IsPasswordHash(value string) bool {
+	return strings.HasPrefix(value, "$argon2id$") ||
+		strings.HasPrefix(value, "$2a$") ||
+		strings.HasPrefix(value, "$2b$") ||
+		strings.HasPrefix(value, "$2y$")
+}
+
+// VerifyPassword checks password against hash created by HashPassword.
+func VerifyPassword(hash string, password string) (bool, error) {
+	if strings.HasPrefix(hash, "$argon2id$") {
+		return verifyArgon2id(hash, password)
+	}
+
+	if IsPasswordHash(hash) {
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	return false, ErrPasswordHashInvalid
+}
+
+// CheckPassword checks password against value, that is either a hash or plain text.
+func CheckPassword(value string, password string) bool {
+	if IsPasswordHash(value) {
+		ok, _ := VerifyPassword(value, password)
+		return ok
+	}
+
+	return subtle.ConstantTimeCompare([]byte(value), []byte(password)) == 1
+}
+
+// LegacyPasswordHash returns unsalted sha256, that was used before salted hashes.
+// It is only used to verify stored passwords, that were not rehashed yet.
+func LegacyPasswordHash(password string) string {
+	sum := sha256.Sum256([]byte(password))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// VerifyStoredPassword checks password against value stored by member provider and reports,
+// whether it should be rehashed. If hashing is disabled, stored value is plain text.
+func VerifyStoredPassword(stored string, password string, hashed bool) (ok bool, rehash bool) {
+	if !hashed || IsPasswordHash(stored) {
+		return CheckPassword(stored, password), false
+	}
+
+	legacyHash := LegacyPasswordHash(password)
+	return subtle.ConstantTimeCompare([]byte(stored), []byte(legacyHash)) == 1, true
+}
+
+func verifyArgon2id(hash string, password string) (bool, error) {
+	// $argon2id$v=19$m=19456,t=2,p=1$<salt>$<key>
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return false, ErrPasswordHashInvalid
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false, ErrPasswordHashInvalid
+	}
+
+	var memory, time uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &time, &threads); err != nil {
+		return false, ErrPasswordHashInvalid
+	}
+
+	// argon2 panics on zero parameters, and huge memory would exhaust the server
+	if threads < 1 || time < 1 || memory < 8*uint32(threads) || memory > argon2idMaxMemory {
+		return false, ErrPasswordHashInvalid
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, ErrPasswordHashInvalid
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return false, ErrPasswordHashInvalid
+	}
+
+	other := argon2.IDKey([]byte(password), salt, time, memory, threads, uint32(len(key)))
+	return subtle.ConstantTimeCompare(key, other) == 1, nil
+}
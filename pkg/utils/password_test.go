@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestHashPassword(t *testing.T) {
+	for _, algorithm := range []string{PasswordHashArgon2id, PasswordHashBcrypt} {
+		t.Run(algorithm, func(t *testing.T) {
+			hash, err := HashPassword(algorithm, "secret")
+			if err != nil {
+				t.Fatalf("HashPassword() returned error: %s", err)
+			}
+
+			if !IsPasswordHash(hash) {
+				t.Errorf("IsPasswordHash(%q) = false", hash)
+			}
+
+			// salted hashes differ for the same password
+			other, err := HashPassword(algorithm, "secret")
+			if err != nil {
+				t.Fatalf("HashPassword() returned error: %s", err)
+			}
+			if hash == other {
+				t.Errorf("HashPassword() returned the same hash twice")
+			}
+
+			if ok, err := VerifyPassword(hash, "secret"); !ok || err != nil {
+				t.Errorf("VerifyPassword() = %v, %v, want true", ok, err)
+			}
+
+			if ok, err := VerifyPassword(hash, "wrong"); ok || err != nil {
+				t.Errorf("VerifyPassword() with wrong password = %v, %v, want false", ok, err)
+			}
+		})
+	}
+
+	if _, err := HashPassword("md5", "secret"); err == nil {
+		t.Errorf("HashPassword() expected error for unknown algorithm")
+	}
+}
+
+func TestVerifyPassword_malformed(t *testing.T) {
+	valid, err := HashPassword(PasswordHashArgon2id, "secret")
+	if err != nil {
+		t.Fatalf("HashPassword() returned error: %s", err)
+	}
+	parts := strings.Split(valid, "$")
+
+	tests := map[string]string{
+		"plain text":       "secret",
+		"missing key":      strings.Join(parts[:5], "$"),
+		"unknown version":  strings.Replace(valid, "$v=19$", "$v=16$", 1),
+		"invalid params":   strings.Replace(valid, parts[3], "m=x,t=2,p=1", 1),
+		"zero threads":     strings.Replace(valid, parts[3], "m=19456,t=2,p=0", 1),
+		"zero time":        strings.Replace(valid, parts[3], "m=19456,t=0,p=1", 1),
+		"zero memory":      strings.Replace(valid, parts[3], "m=0,t=2,p=1", 1),
+		"huge memory":      strings.Replace(valid, parts[3], "m=4294967295,t=2,p=1", 1),
+		"invalid salt":     strings.Replace(valid, parts[4], "!!!", 1),
+		"invalid key":      strings.Replace(valid, parts[5], "!!!", 1),
+		"empty key":        strings.Join(append(parts[:5:5], ""), "$"),
+		"truncated bcrypt": "$2a$10$tooshort",
+	}
+
+	for name, hash := range tests {
+		t.Run(name, func(t *testing.T) {
+			ok, err := VerifyPassword(hash, "secret")
+			if ok || err == nil {
+				t.Errorf("VerifyPassword() = %v, %v, want error", ok, err)
+			}
+		})
+	}
+
+	if _, err := VerifyPassword("secret", "secret"); !errors.Is(err, ErrPasswordHashInvalid) {
+		t.Errorf("VerifyPassword() error = %v, want %v", err, ErrPasswordHashInvalid)
+	}
+}
+
+func TestCheckPassword(t *testing.T) {
+	hash, err := HashPassword(PasswordHashBcrypt, "secret")
+	if err != nil {
+		t.Fatalf("HashPassword() returned error: %s", err)
+	}
+
+	tests := []struct {
+		value    string
+		password string
+		want     bool
+	}{
+		{hash, "secret", true},
+		{hash, "wrong", false},
+		{"secret", "secret", true},
+		{"secret", "wrong", false},
+		{"$2a$10$tooshort", "$2a$10$tooshort", false},
+	}
+
+	for _, tt := range tests {
+		if got := CheckPassword(tt.value, tt.password); got != tt.want {
+			t.Errorf("CheckPassword(%q, %q) = %v, want %v", tt.value, tt.password, got, tt.want)
+		}
+	}
+}
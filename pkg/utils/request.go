@@ -4,10 +4,13 @@ import (
 	"bytes"
 	"io"
 	"net/http"
+	"time"
 )
 
+var httpClient = http.Client{Timeout: 10 * time.Second}
+
 func HttpRequestGET(url string) (string, error) {
-	rsp, err := http.Get(url)
+	rsp, err := httpClient.Get(url)
 	if err != nil {
 		return "", err
 	}
@@ -229,6 +229,65 @@ void XKey(KeySym keysym, int down) {
   XSync(display, 0);
 }
 
+KeyCode XKeyFind(KeySym keysym) {
+  return XkbKeysymToKeycode(getXDisplay(), keysym);
+}
+
+// Inspired by https://github.com/jordansissel/xdotool/blob/master/xdo.c (_xdo_send_keysequence_window_to_keycode_list)
+KeyCode XKeyScratchMap(KeySym keysym) {
+  Display *display = getXDisplay();
+  int min_keycode, max_keycode, keysyms_per_keycode;
+  KeyCode keycode = 0;
+
+  XDisplayKeycodes(display, &min_keycode, &max_keycode);
+  KeySym *keysyms = XGetKeyboardMapping(display, min_keycode, max_keycode - min_keycode + 1, &keysyms_per_keycode);
+  if (keysyms == NULL)
+    return 0;
+
+  // find keycode without any keysym assigned
+  for (int key = max_keycode; key >= min_keycode && keycode == 0; key--) {
+    int empty = 1;
+    for (int i = 0; i < keysyms_per_keycode; i++) {
+      if (keysyms[(key - min_keycode) * keysyms_per_keycode + i] != NoSymbol) {
+        empty = 0;
+        break;
+      }
+    }
+
+    if (empty)
+      keycode = key;
+  }
+
+  XFree(keysyms);
+
+  // no free keycodes
+  if (keycode == 0)
+    return 0;
+
+  // same keysym for both levels, so that modifiers do not matter
+  KeySym syms[2] = { keysym, keysym };
+  XChangeKeyboardMapping(display, keycode, 2, syms, 1);
+  XSync(display, 0);
+
+  return keycode;
+}
+
+void XKeyScratchUnmap(KeyCode keycode) {
+  Display *display = getXDisplay();
+
+  KeySym syms[2] = { NoSymbol, NoSymbol };
+  XChangeKeyboardMapping(display, keycode, 2, syms, 1);
+  XSync(display, 0);
+}
+
+void XKeyTap(KeyCode keycode) {
+  Display *display = getXDisplay();
+
+  XTestFakeKeyEvent(display, keycode, 1, CurrentTime);
+  XTestFakeKeyEvent(display, keycode, 0, CurrentTime);
+  XSync(display, 0);
+}
+
 Status XSetScreenConfiguration(int width, int height, short rate) {
   Display *display = getXDisplay();
   Window root = DefaultRootWindow(display);
@@ -156,6 +156,69 @@ func KeyUp(code uint32) error {
 	return nil
 }
 
+// time given to X clients to process keyboard mapping changes
+const keyRemapDelay = 5 * time.Millisecond
+
+// serializes typed characters, so that they are not interleaved
+var keyTypeMu = sync.Mutex{}
+
+func KeyType(keysym uint32) error {
+	keyTypeMu.Lock()
+	defer keyTypeMu.Unlock()
+
+	mu.Lock()
+
+	// try to use existing keycode from current layout
+	if keycode := C.XKeyFind(C.KeySym(keysym)); keycode != 0 {
+		C.XKeyTap(keycode)
+		mu.Unlock()
+		return nil
+	}
+
+	// temporarily remap spare keycode to requested keysym
+	keycode := C.XKeyScratchMap(C.KeySym(keysym))
+	mu.Unlock()
+
+	if keycode == 0 {
+		return fmt.Errorf("no spare keycode available for keysym %v", keysym)
+	}
+
+	// other input is not blocked while X clients process mapping change,
+	// mapped keycode is no longer spare, so it cannot be taken by others
+	time.Sleep(keyRemapDelay)
+
+	mu.Lock()
+	C.XKeyTap(keycode)
+	mu.Unlock()
+
+	time.Sleep(keyRemapDelay)
+
+	mu.Lock()
+	C.XKeyScratchUnmap(keycode)
+	mu.Unlock()
+
+	return nil
+}
+
+func KeysymFromRune(r rune) uint32 {
+	switch r {
+	case '\n', '\r':
+		return XK_Return
+	case '\t':
+		return XK_Tab
+	case '\b':
+		return XK_BackSpace
+	}
+
+	// latin-1 characters map directly to keysyms
+	if (r >= 0x20 && r <= 0x7e) || (r >= 0xa0 && r <= 0xff) {
+		return uint32(r)
+	}
+
+	// other characters use unicode keysym range
+	return 0x01000000 | uint32(r)
+}
+
 func ResetKeys() {
 	mu.Lock()
 	defer mu.Unlock()
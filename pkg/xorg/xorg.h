@@ -36,6 +36,11 @@ static KeyCode XKeyEntryGet(KeySym keysym);
 static KeyCode XkbKeysymToKeycode(Display *dpy, KeySym keysym);
 void XKey(KeySym keysym, int down);
 
+KeyCode XKeyFind(KeySym keysym);
+KeyCode XKeyScratchMap(KeySym keysym);
+void XKeyScratchUnmap(KeyCode keycode);
+void XKeyTap(KeyCode keycode);
+
 Status XSetScreenConfiguration(int width, int height, short rate);
 void XGetScreenConfiguration(int *width, int *height, short *rate);
 void XGetScreenConfigurations();